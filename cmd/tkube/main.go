@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"tkube/internal/commands"
 	"tkube/internal/config"
 	"tkube/internal/kubectl"
+	"tkube/internal/paths"
 	"tkube/internal/shell"
 	"tkube/internal/teleport"
 
@@ -15,9 +18,32 @@ import (
 var version = "1.2.0" // Set by build process
 
 func main() {
+	// The profile decides which files are used, so it is needed before anything is loaded
+	if profile := profileFromArgs(os.Args[1:]); profile != "" {
+		os.Setenv(paths.EnvProfile, profile)
+	}
+
+	// Move files of an existing ~/.tkube install to XDG directories the user opted into
+	if tkubePaths, err := paths.Resolve(); err == nil {
+		moves, err := tkubePaths.MigrateLegacy()
+		for _, move := range moves {
+			fmt.Fprintf(os.Stderr, "📦 Moved %s to %s\n", move.From, move.To)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not move existing tkube files: %v\n", err)
+		}
+	}
+
 	// Initialize dependencies
 	configManager, err := config.NewManager()
+	var notFound *config.ProfileNotFoundError
+	if errors.As(err, &notFound) && isProfileCommand(os.Args[1:]) {
+		// Managing profiles must keep working, e.g. to select another one
+		fmt.Fprintf(os.Stderr, "⚠️  Profile '%s' not found - using the default profile\n", notFound.Profile)
+		configManager, err = config.NewManagerForProfile(paths.DefaultProfile)
+	}
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
 		os.Exit(1)
 	}
 
@@ -49,7 +75,20 @@ tkube simplifies your workflow by:
   • Working seamlessly across bash, zsh, and fish shells
 
 Configuration is stored in ~/.tkube/config.json and created automatically
-on first run with example environments.`,
+on first run with example environments. It is merged on top of an optional
+system baseline (/etc/tkube/config.json) and can be overridden per project
+with a .tkube.json file.
+
+Each cluster's context is written to its own kubeconfig rather than
+~/.kube/config; see 'tkube kubeconfig'.
+
+File locations can be changed with environment variables:
+  TKUBE_HOME        keep all tkube files below one directory
+  TKUBE_CONFIG      use a specific configuration file
+  XDG_CONFIG_HOME   configuration in $XDG_CONFIG_HOME/tkube
+  XDG_CACHE_HOME    installed tsh versions in $XDG_CACHE_HOME/tkube
+  XDG_STATE_HOME    sessions, kubeconfigs and backups in $XDG_STATE_HOME/tkube
+  TKUBE_PROFILE     use a named profile (see 'tkube profile')`,
 		Example: `  # Connect to a production cluster
   tkube prod my-app-cluster
 
@@ -98,7 +137,7 @@ on first run with example environments.`,
 				// Create a map for quick lookup of descriptions
 				descMap := make(map[string]string)
 				for _, item := range clusterItems {
-					if item.Category == "cluster" {
+					if item.Category == "cluster" || item.Category == "alias" {
 						descMap[item.Value] = item.Description
 					}
 				}
@@ -155,14 +194,36 @@ This command helps you:
 		Example: `  # Check status of all environments
   tkube status
 
+  # Check only the EU production environments
+  tkube status --selector tier=prod,region=eu
+
   # Typical output shows:
   # ✅ prod → teleport.prod.company.com:443 (authenticated)
   # ❌ test → teleport.test.company.com:443 (not authenticated)`,
-		Run: func(cmd *cobra.Command, args []string) {
-			commandHandler.ShowStatus()
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selector, _ := cmd.Flags().GetString("selector")
+			return commandHandler.ShowStatus(selector)
 		},
 	}
 
+	// completeSelector completes the last term of a comma-separated tag selector
+	completeSelector := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		prefix := ""
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			prefix = toComplete[:i+1]
+		}
+
+		var completions []string
+		for _, item := range shellProvider.GetTagSelectorsWithContext() {
+			completions = append(completions, prefix+item.Value+"\t"+item.Description)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+
+	statusCmd.Flags().StringP("selector", "l", "", "Only show environments whose tags match (e.g. tier=prod,region=eu)")
+	statusCmd.RegisterFlagCompletionFunc("selector", completeSelector)
+
 	// Create config command with enhanced completion
 	configCmd := &cobra.Command{
 		Use:   "config",
@@ -170,7 +231,20 @@ This command helps you:
 		Long: `Manage your tkube configuration file (~/.tkube/config.json).
 
 The configuration file stores your Teleport environments and settings.
-It's automatically created with example values on first run.`,
+It's automatically created with example values on first run. Instead of
+config.json you may use config.yaml or config.jsonc (JSON with comments);
+tkube keeps comments and key order intact when it updates the file.
+
+The effective configuration is merged from these layers, each overriding
+the previous one key by key:
+  • default  built-in defaults
+  • system   /etc/tkube/config.json (shared baseline)
+  • team     cached copy of the team config set with "team" (see 'tkube config sync')
+  • user     ~/.tkube/config.json
+  • project  .tkube.json in the current directory or any parent
+
+Changes made by tkube are always written to the user layer, and the
+previous version is kept in ~/.tkube/backups/ (see 'tkube config history').`,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
 				// Complete config subcommands with contextual information
@@ -190,7 +264,10 @@ It's automatically created with example values on first run.`,
 		Use:   "show",
 		Short: "Show current configuration",
 		Long: `Display the current tkube configuration including all environments,
-their Teleport proxy addresses, and settings like auto-login.`,
+their Teleport proxy addresses, and settings like auto-login.
+
+Each value is annotated with the layer (system, user, project or default)
+it was taken from.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			commandHandler.ShowConfig()
 		},
@@ -267,20 +344,51 @@ This command is useful for:
 
 	// Create interactive config commands
 	configAddCmd := &cobra.Command{
-		Use:   "add",
+		Use:   "add [name]",
 		Short: "Interactively add a new environment",
 		Long: `Interactively add a new environment to your tkube configuration.
 
 This command will prompt you for:
   • Environment name
   • Teleport proxy address
-  • TSH version (optional)
+  • Teleport user (optional)
+  • TSH version (optional, pre-filled from the proxy)
+
+The proxy is probed to check that it is reachable and to detect the
+Teleport version it runs; tkube then offers to install the matching tsh.
+Every answer can also be given as a flag, and --non-interactive never
+prompts, which is useful in scripts.
 
 A backup of your current configuration will be created automatically.`,
+		Example: `  # Guided setup
+  tkube config add
+
+  # Scripted setup
+  tkube config add prod --proxy teleport.prod.company.com:443 --user alice --install-tsh --non-interactive`,
+		Args: cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return commandHandler.AddEnvironmentInteractive()
+			opts := commands.AddEnvironmentOptions{}
+			if len(args) > 0 {
+				opts.Name = args[0]
+			}
+			opts.Proxy, _ = cmd.Flags().GetString("proxy")
+			opts.User, _ = cmd.Flags().GetString("user")
+			opts.TSHVersion, _ = cmd.Flags().GetString("tsh-version")
+			opts.SkipProbe, _ = cmd.Flags().GetBool("no-probe")
+			opts.InstallTSH, _ = cmd.Flags().GetBool("install-tsh")
+			opts.NonInteractive, _ = cmd.Flags().GetBool("non-interactive")
+			return commandHandler.AddEnvironmentInteractive(opts)
 		},
 	}
+	configAddCmd.Flags().String("proxy", "", "Teleport proxy address (host:port)")
+	configAddCmd.Flags().String("user", "", "Teleport user for this environment")
+	configAddCmd.Flags().String("tsh-version", "", "tsh version to pin (default: detected from the proxy)")
+	configAddCmd.Flags().Bool("no-probe", false, "Do not contact the proxy")
+	configAddCmd.Flags().Bool("install-tsh", false, "Install the tsh version without asking")
+	configAddCmd.Flags().Bool("non-interactive", false, "Never prompt; fail if a required value is missing")
 
 	configEditCmd := &cobra.Command{
 		Use:   "edit <environment>",
@@ -289,8 +397,13 @@ A backup of your current configuration will be created automatically.`,
 
 This command allows you to modify:
   • Teleport proxy address
+  • Teleport user
   • TSH version
 
+Current values are offered as defaults; enter '-' to clear an optional
+value. Every value can also be given as a flag, and --non-interactive
+never prompts.
+
 A backup of your current configuration will be created automatically.`,
 		Args: cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -306,9 +419,20 @@ A backup of your current configuration will be created automatically.`,
 			return completions, cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return commandHandler.EditEnvironmentInteractive(args[0])
+			opts := commands.EditEnvironmentOptions{}
+			opts.Proxy, _ = cmd.Flags().GetString("proxy")
+			opts.User, _ = cmd.Flags().GetString("user")
+			opts.TSHVersion, _ = cmd.Flags().GetString("tsh-version")
+			opts.InstallTSH, _ = cmd.Flags().GetBool("install-tsh")
+			opts.NonInteractive, _ = cmd.Flags().GetBool("non-interactive")
+			return commandHandler.EditEnvironmentInteractive(args[0], opts)
 		},
 	}
+	configEditCmd.Flags().String("proxy", "", "New Teleport proxy address (host:port)")
+	configEditCmd.Flags().String("user", "", "New Teleport user ('-' to clear)")
+	configEditCmd.Flags().String("tsh-version", "", "New tsh version ('-' to clear)")
+	configEditCmd.Flags().Bool("install-tsh", false, "Install the tsh version without asking")
+	configEditCmd.Flags().Bool("non-interactive", false, "Never prompt; keep values not given as flags")
 
 	configRemoveCmd := &cobra.Command{
 		Use:   "remove <environment>",
@@ -316,6 +440,10 @@ A backup of your current configuration will be created automatically.`,
 		Long: `Interactively remove an environment from your tkube configuration.
 
 This command will ask for confirmation before removing the environment.
+It then logs out of the environment, deletes its session directory
+(~/.tkube/sessions/<environment>) and lists installed tsh versions that
+no remaining environment uses.
+
 A backup of your current configuration will be created automatically.`,
 		Args: cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -331,7 +459,166 @@ A backup of your current configuration will be created automatically.`,
 			return completions, cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return commandHandler.RemoveEnvironmentInteractive(args[0])
+			yes, _ := cmd.Flags().GetBool("yes")
+			return commandHandler.RemoveEnvironmentInteractive(args[0], yes)
+		},
+	}
+	configRemoveCmd.Flags().BoolP("yes", "y", false, "Remove without asking for confirmation")
+
+	configImportTSHCmd := &cobra.Command{
+		Use:   "import-tsh",
+		Short: "Create environments from existing tsh profiles",
+		Long: `Create environments from the profiles plain tsh and Teleport Connect keep
+in ~/.tsh/*.yaml (or $TELEPORT_HOME).
+
+Each profile's proxy and user become a new environment, named after the
+Teleport cluster. Profiles whose proxy is already configured are skipped.
+The proxy is probed to pin the matching tsh version.
+
+With --copy-sessions the still-valid keys of a profile are copied into the
+environment's isolated session directory (~/.tkube/sessions/<environment>),
+so you don't have to log in again. Expired sessions are not copied.`,
+		Example: `  # Import profiles, confirming each one
+  tkube config import-tsh
+
+  # Import everything and reuse the existing logins
+  tkube config import-tsh --yes --copy-sessions
+
+  # Import from another tsh directory
+  tkube config import-tsh --tsh-dir /path/to/.tsh`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("tsh-dir")
+			copySessions, _ := cmd.Flags().GetBool("copy-sessions")
+			noProbe, _ := cmd.Flags().GetBool("no-probe")
+			yes, _ := cmd.Flags().GetBool("yes")
+			return commandHandler.ImportTSHProfiles(commands.ImportTSHOptions{
+				Dir:          dir,
+				CopySessions: copySessions,
+				SkipProbe:    noProbe,
+				Yes:          yes,
+			})
+		},
+	}
+	configImportTSHCmd.Flags().String("tsh-dir", "", "tsh profile directory (default $TELEPORT_HOME or ~/.tsh)")
+	configImportTSHCmd.Flags().Bool("copy-sessions", false, "Copy still-valid keys into the environments' session directories")
+	configImportTSHCmd.Flags().Bool("no-probe", false, "Don't probe the proxies for their tsh version")
+	configImportTSHCmd.Flags().BoolP("yes", "y", false, "Import every profile with the suggested names without asking")
+	configImportTSHCmd.MarkFlagDirname("tsh-dir")
+
+	configSyncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Refresh the shared team configuration",
+		Long: `Fetch the shared team configuration and show what changed upstream.
+
+A team configuration is referenced from your config (or the system config)
+and merged between the system and user layers, so your own settings always
+override it:
+  "team": {"url": "https://config.company.com/tkube/team.json"}
+  "team": {"path": "~/src/platform-config/tkube/team.json"}
+
+HTTPS sources are requested with the cached ETag, so unchanged files are not
+downloaded again. A path inside a git checkout is updated with
+'git pull --ff-only' first. Add "sha256" to pin the expected checksum.
+
+The fetched file is verified before it replaces the cached copy: it must
+parse, may only contain known configuration keys and valid environments,
+and must not reference another team configuration.`,
+		Example: `  # Refresh the team configuration and show the changes
+  tkube config sync`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.SyncTeamConfig()
+		},
+	}
+
+	// completeConfigKey completes dotted configuration keys one level at a time
+	completeConfigKey := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var completions []string
+		for _, item := range shellProvider.GetConfigKeysWithContext(toComplete) {
+			completions = append(completions, item.Value+"\t"+item.Description)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+
+	configGetCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Show the value of a configuration key",
+		Long: `Show the effective value of a configuration key, merged from all layers.
+
+Keys are dotted paths into the configuration, e.g. auto_login,
+environments.prod.user or environments.prod.login.ttl. Strings are printed
+as they are and other values as JSON, so the output can be used in scripts.`,
+		Example: `  tkube config get environments.prod.user
+  tkube config get environments.prod --source`,
+		Args:              cobra.ExactArgs(1),
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, _ := cmd.Flags().GetBool("source")
+			return commandHandler.GetConfigValue(args[0], source)
+		},
+	}
+	configGetCmd.Flags().Bool("source", false, "Show the layer the value comes from")
+
+	configSetCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a configuration key in your config",
+		Long: `Set a configuration key in your user config file.
+
+The key is checked against the configuration structure and the value against
+its type: booleans accept true/false, lists are comma-separated (or a JSON
+array) and objects are given as JSON. The change is only saved if the
+resulting configuration is still valid. Comments and key order in the file
+are preserved, and the previous version is kept in the backup history.`,
+		Example: `  tkube config set auto_login false
+  tkube config set environments.prod.user alice
+  tkube config set environments.prod.login.roles prod-readonly,auditor
+  tkube config set environments.dev '{"proxy": "teleport.dev.company.com:443"}'`,
+		Args:              cobra.ExactArgs(2),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.SetConfigValue(args[0], args[1])
+		},
+	}
+
+	configUnsetCmd := &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a configuration key from your config",
+		Long: `Remove a configuration key from your user config file, so the value from
+the system, team or project layer (or the default) applies again.`,
+		Example: `  tkube config unset environments.prod.user
+  tkube config unset environments.prod.login`,
+		Args:              cobra.ExactArgs(1),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.UnsetConfigValue(args[0])
+		},
+	}
+
+	configSchemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the configuration file",
+		Long: `Print the JSON Schema describing the tkube configuration file.
+
+Editors use the schema to validate and complete the configuration. New
+configuration files reference the published schema with a "$schema" key;
+point the key at a local copy to use the schema of this exact release.`,
+		Example: `  # Save the schema next to your configuration
+  tkube config schema > ~/.tkube/config.schema.json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.ShowConfigSchema()
 		},
 	}
 
@@ -342,15 +629,103 @@ A backup of your current configuration will be created automatically.`,
 
 This command checks for:
   • Valid environment names
-  • Proper proxy address formats
-  • Valid TSH version formats
-  • Configuration file syntax
+  • Proper proxy address formats (host:port)
+  • Valid TSH version formats (MAJOR.MINOR.PATCH)
+  • Environments sharing the same proxy
+  • Unknown keys, e.g. misspelled settings (tkube refuses to load them)
+  • Configuration file syntax and permissions
+  • Pinned tsh versions that are not installed
+  • Proxy reachability (with --check-proxies)
 
-Any issues found will be reported with suggestions for fixes.`,
+Every finding is reported with its severity and a suggested fix. The
+command exits with a non-zero status if any error is found, so it can be
+used in CI together with --output json.`,
+		Example: `  # Human readable report
+  tkube config validate
+
+  # Machine readable report including live proxy checks
+  tkube config validate --check-proxies --output json`,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := commands.ValidateOptions{}
+			opts.Output, _ = cmd.Flags().GetString("output")
+			opts.CheckProxies, _ = cmd.Flags().GetBool("check-proxies")
+			return commandHandler.ValidateConfiguration(opts)
+		},
+	}
+	configValidateCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	configValidateCmd.Flags().Bool("check-proxies", false, "Check that every proxy is reachable")
+	configValidateCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	configMigrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the configuration file to the current schema version",
+		Long: `Upgrade your tkube configuration file to the schema version used by
+this release.
+
+Outdated files are also migrated automatically the next time tkube loads
+them. The original file is kept in the backup history as a schema-vN entry;
+see 'tkube config history' and undo the migration with 'tkube config restore'.`,
+		Example: `  # Show what would change without writing anything
+  tkube config migrate --dry-run
+
+  # Apply the migration
+  tkube config migrate`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return commandHandler.MigrateConfig(dryRun)
+		},
+	}
+	configMigrateCmd.Flags().Bool("dry-run", false, "Show the changes without writing them")
+
+	configHistoryCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List previous configuration versions",
+		Long: `List the backups of your tkube configuration.
+
+Every time tkube changes your configuration, the previous version is stored
+in ~/.tkube/backups/. The most recent 20 versions are kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.ShowConfigHistory()
+		},
+	}
+
+	configRestoreCmd := &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Restore a previous configuration version",
+		Long: `Restore your tkube configuration from a backup listed by 'tkube config history'.
+
+The changes are shown as a diff and you are asked for confirmation before
+anything is written. The configuration being replaced is added to the
+history, so a restore can be undone.`,
+		Example: `  # List available versions
+  tkube config history
+
+  # Review and restore one of them
+  tkube config restore 20250815-101500-123`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var completions []string
+			for _, item := range shellProvider.GetConfigBackupsWithContext() {
+				completions = append(completions, item.Value+"\t"+item.Description)
+			}
+			return completions, cobra.ShellCompDirectiveNoFileComp
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return commandHandler.ValidateConfiguration()
+			yes, _ := cmd.Flags().GetBool("yes")
+			return commandHandler.RestoreConfig(args[0], yes)
 		},
 	}
+	configRestoreCmd.Flags().BoolP("yes", "y", false, "Restore without asking for confirmation")
 
 	// Add subcommands to config
 	configCmd.AddCommand(configShowCmd)
@@ -358,7 +733,16 @@ Any issues found will be reported with suggestions for fixes.`,
 	configCmd.AddCommand(configAddCmd)
 	configCmd.AddCommand(configEditCmd)
 	configCmd.AddCommand(configRemoveCmd)
+	configCmd.AddCommand(configImportTSHCmd)
+	configCmd.AddCommand(configSyncCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configSchemaCmd)
 	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configHistoryCmd)
+	configCmd.AddCommand(configRestoreCmd)
 
 	// Create logout command
 	logoutCmd := &cobra.Command{
@@ -368,6 +752,7 @@ Any issues found will be reported with suggestions for fixes.`,
 
 Without arguments, logs out from all configured environments.
 With an environment name, logs out from that specific environment only.
+With --selector, logs out from every environment whose tags match.
 
 This command helps you:
   • Clear authentication sessions when switching contexts
@@ -378,7 +763,10 @@ This command helps you:
 
   # Log out from specific environment
   tkube logout prod
-  tkube logout test`,
+  tkube logout test
+
+  # Log out from all EU production environments
+  tkube logout --selector tier=prod,region=eu`,
 		Args: cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
@@ -401,14 +789,453 @@ This command helps you:
 			if len(args) > 0 {
 				env = args[0]
 			}
-			return commandHandler.Logout(env)
+			selector, _ := cmd.Flags().GetString("selector")
+			return commandHandler.Logout(env, selector)
+		},
+	}
+	logoutCmd.Flags().StringP("selector", "l", "", "Log out from environments whose tags match (e.g. tier=prod,region=eu)")
+	logoutCmd.RegisterFlagCompletionFunc("selector", completeSelector)
+
+	// Create login command
+	loginCmd := &cobra.Command{
+		Use:   "login [environment]",
+		Short: "Log in to Teleport environments",
+		Long: `Log in to Teleport environments without connecting to a cluster.
+
+With an environment name, logs in to that environment.
+With --selector, logs in to every environment whose tags match, one after
+another. Environments that already have a valid session are skipped.
+
+Tags are set per environment in the config file:
+  "environments": {
+    "prod-eu": {
+      "proxy": "teleport.eu.company.com:443",
+      "tags": {"tier": "prod", "region": "eu"}
+    }
+  }
+
+A selector is a comma-separated list of terms that must all match:
+  key=value    the tag is set to value
+  key!=value   the tag is not set to value
+  key          the tag is set
+  !key         the tag is not set`,
+		Example: `  # Log in to a single environment
+  tkube login prod
+
+  # Log in to all EU production environments
+  tkube login --selector tier=prod,region=eu
+
+  # Log in to everything except production
+  tkube login -l tier!=prod`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				envItems := shellProvider.GetEnvironmentsWithContext()
+				var completions []string
+				for _, item := range envItems {
+					if item.Category == "error" || item.Category == "help" {
+						completions = append(completions, item.Description)
+					} else {
+						completions = append(completions, item.Value+"\t"+item.Description)
+					}
+				}
+				return completions, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var env string
+			if len(args) > 0 {
+				env = args[0]
+			}
+			selector, _ := cmd.Flags().GetString("selector")
+			return commandHandler.Login(env, selector)
+		},
+	}
+	loginCmd.Flags().StringP("selector", "l", "", "Log in to environments whose tags match (e.g. tier=prod,region=eu)")
+	loginCmd.RegisterFlagCompletionFunc("selector", completeSelector)
+
+	// completeProfile completes profile names with their environment counts
+	completeProfile := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var completions []string
+		for _, item := range shellProvider.GetProfilesWithContext() {
+			completions = append(completions, item.Value+"\t"+item.Description)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.PersistentFlags().String("profile", "", "Use a named profile for this command (default $TKUBE_PROFILE or 'tkube profile use')")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfile)
+
+	// Create profile command
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage named configuration profiles",
+		Long: `Manage named configuration profiles, e.g. one per organisation you work for.
+
+Each profile has its own environments, default user, Teleport sessions and
+configuration backups; installed tsh versions are shared. The default
+profile uses ~/.tkube/config.json, named profiles live in
+~/.tkube/profiles/<name>/. Tab completion only offers the environments of
+the active profile.
+
+The profile is chosen, in order of precedence, by:
+  --profile <name>      for a single command
+  TKUBE_PROFILE=<name>  for a shell session
+  tkube profile use     until changed again`,
+		Example: `  # Create a profile for a customer and switch to it
+  tkube profile create customer --default-user alice.consultant --use
+
+  # Run a single command in another profile
+  tkube --profile personal status
+
+  # Go back to the default profile
+  tkube profile use default`,
+	}
+
+	profileListCmd := &cobra.Command{
+		Use:          "list",
+		Aliases:      []string{"ls"},
+		Short:        "List profiles",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.ListProfiles()
+		},
+	}
+
+	profileCurrentCmd := &cobra.Command{
+		Use:   "current",
+		Short: "Print the active profile",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			commandHandler.ShowProfile()
+		},
+	}
+
+	profileUseCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Select the profile used by later commands",
+		Long: `Select the profile used by later commands. Use 'default' to go back to
+the default profile. TKUBE_PROFILE and --profile still take precedence.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeProfile(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.UseProfile(args[0])
 		},
 	}
 
+	profileCreateCmd := &cobra.Command{
+		Use:          "create <name>",
+		Short:        "Create a profile without environments",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defaultUser, _ := cmd.Flags().GetString("default-user")
+			use, _ := cmd.Flags().GetBool("use")
+			return commandHandler.CreateProfile(args[0], defaultUser, use)
+		},
+	}
+	profileCreateCmd.Flags().String("default-user", "", "Teleport user for the profile's environments")
+	profileCreateCmd.Flags().Bool("use", false, "Switch to the new profile")
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCurrentCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+
+	// Create agent command
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the background session agent",
+		Long: `Run the session agent in the foreground until it is stopped.
+
+The agent checks the session of every environment of the active profile
+every minute, warns before sessions expire and refreshes the cluster lists
+of logged-in environments. Its state is served on a Unix socket in the
+state directory, so tab completion and prompts read it instead of running
+tsh.
+
+Warnings are logged and, depending on the "agent" configuration block,
+ring the terminal bell, run a desktop notification command and a hook:
+
+  {
+    "agent": {
+      "warn_before": "15m",
+      "bell": true,
+      "notify_command": "notify-send",
+      "hook": "echo \"$TKUBE_EVENT $TKUBE_ENV\" >> ~/tkube-events.log"
+    }
+  }
+
+Hooks get TKUBE_EVENT (expiring or expired), TKUBE_ENV, TKUBE_PROXY,
+TKUBE_VALID_UNTIL and TKUBE_MESSAGE.`,
+		Example: `  # Run the agent in this terminal
+  tkube agent
+
+  # Run it as a systemd user service
+  tkube agent install-service
+  systemctl --user daemon-reload && systemctl --user enable --now tkube-agent.service
+
+  # Show what the agent knows and stop it
+  tkube agent status
+  tkube agent stop`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.RunAgent()
+		},
+	}
+
+	agentStopCmd := &cobra.Command{
+		Use:          "stop",
+		Short:        "Stop the running agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.StopAgent()
+		},
+	}
+
+	agentStatusCmd := &cobra.Command{
+		Use:          "status",
+		Short:        "Show the sessions and cluster lists known to the agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			return commandHandler.ShowAgentStatus(jsonOutput)
+		},
+	}
+	agentStatusCmd.Flags().Bool("json", false, "Print the agent state as JSON, e.g. for shell prompts")
+
+	agentInstallServiceCmd := &cobra.Command{
+		Use:   "install-service",
+		Short: "Install a systemd user service running the agent",
+		Long: `Write a systemd user unit that runs 'tkube agent' for the active profile
+to ~/.config/systemd/user. Variables locating tkube's files, such as
+TKUBE_HOME, are copied into the unit.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printOnly, _ := cmd.Flags().GetBool("print")
+			return commandHandler.InstallAgentService(printOnly)
+		},
+	}
+	agentInstallServiceCmd.Flags().Bool("print", false, "Print the unit instead of writing it")
+
+	agentCmd.AddCommand(agentStopCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentInstallServiceCmd)
+
+	// completeEnvironment completes the first argument with environment names and their session status
+	completeEnvironment := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var completions []string
+		for _, item := range shellProvider.GetEnvironmentsWithContext() {
+			if item.Category == "error" || item.Category == "help" {
+				completions = append(completions, item.Description)
+			} else {
+				completions = append(completions, item.Value+"\t"+item.Description)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// completeEnvironmentCluster completes an environment followed by one of its clusters
+	completeEnvironmentCluster := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return shellProvider.GetClustersWithPrefix(args[0], toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeEnvironment(cmd, args, toComplete)
+	}
+
+	// Create request command
+	requestCmd := &cobra.Command{
+		Use:   "request",
+		Short: "Request, list and assume Teleport roles",
+		Long: `Work with Teleport access requests for just-in-time role elevation.
+
+Requests are filed with the environment's own tsh and session, just like
+'tsh request' would with the right TELEPORT_HOME. Once a request is
+approved, 'tkube request wait' re-issues the session with the requested
+roles, so the next 'tkube <env> <cluster>' uses them.`,
+		Example: `  # Ask for the dba role in prod and wait for approval
+  tkube request create prod --roles dba --reason "INC-1234" --wait
+
+  # Check on requests
+  tkube request ls prod
+
+  # Assume the roles of your newest pending request once it is approved
+  tkube request wait prod`,
+	}
+
+	requestCreateCmd := &cobra.Command{
+		Use:               "create <environment>",
+		Short:             "File an access request for roles",
+		Args:              cobra.ExactArgs(1),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeEnvironment,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roles, _ := cmd.Flags().GetStringSlice("roles")
+			reason, _ := cmd.Flags().GetString("reason")
+			wait, _ := cmd.Flags().GetBool("wait")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			return commandHandler.CreateAccessRequest(args[0], roles, reason, wait, timeout)
+		},
+	}
+	requestCreateCmd.Flags().StringSlice("roles", nil, "Roles to request (comma-separated)")
+	requestCreateCmd.Flags().String("reason", "", "Reason shown to reviewers")
+	requestCreateCmd.Flags().Bool("wait", false, "Wait for the review and assume the roles once approved")
+	requestCreateCmd.Flags().Duration("timeout", 0, "Stop waiting after this long (default: no limit)")
+	requestCreateCmd.MarkFlagRequired("roles")
+
+	requestListCmd := &cobra.Command{
+		Use:               "ls <environment>",
+		Aliases:           []string{"list"},
+		Short:             "List access requests",
+		Args:              cobra.ExactArgs(1),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeEnvironment,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.ListAccessRequests(args[0])
+		},
+	}
+
+	requestWaitCmd := &cobra.Command{
+		Use:   "wait <environment> [request-id]",
+		Short: "Wait for an access request and assume its roles",
+		Long: `Wait until an access request is reviewed. Once approved, the
+environment's session is re-issued with 'tsh login --request-id' so it
+includes the requested roles. Without a request ID, your newest pending
+request is used.`,
+		Args:              cobra.RangeArgs(1, 2),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeEnvironment,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var id string
+			if len(args) > 1 {
+				id = args[1]
+			}
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			return commandHandler.WaitAccessRequest(args[0], id, timeout)
+		},
+	}
+	requestWaitCmd.Flags().Duration("timeout", 0, "Stop waiting after this long (default: no limit)")
+
+	requestCmd.AddCommand(requestCreateCmd)
+	requestCmd.AddCommand(requestListCmd)
+	requestCmd.AddCommand(requestWaitCmd)
+
+	// Create shell command
+	shellCmd := &cobra.Command{
+		Use:   "shell <environment> <cluster>",
+		Short: "Start a shell scoped to a cluster",
+		Long: `Connect to a cluster and start your $SHELL with the cluster's isolated
+credentials: KUBECONFIG points at its own kubeconfig, TELEPORT_HOME at the
+environment's session directory, and the environment's tsh comes first on
+PATH. Two terminals can work on prod and staging at the same time, and
+leaving the shell leaves the global kubectl context untouched.
+
+TKUBE_ENV and TKUBE_CLUSTER are set in the shell, e.g. for your prompt.`,
+		Example: `  # Work on prod in this terminal
+  tkube shell prod my-app-cluster
+
+  # Show the cluster in a bash prompt
+  PS1='${TKUBE_ENV:+[$TKUBE_ENV/$TKUBE_CLUSTER] }\w \$ '`,
+		Args:              cobra.ExactArgs(2),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeEnvironmentCluster,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.Shell(args[0], args[1])
+		},
+	}
+
+	// Create exec command
+	execCmd := &cobra.Command{
+		Use:   "exec <environment> <cluster> -- <command> [args...]",
+		Short: "Run a command against a cluster",
+		Long: `Connect to a cluster without prompting and run a single command with the
+cluster's isolated kubeconfig, the environment's session directory and its
+pinned tsh first on PATH. Nothing global changes, so scripts and runbooks can
+run kubectl, helm or k9s against any cluster.
+
+A missing tsh version is installed automatically, and tkube's own messages go
+to stderr. tkube exits with the command's exit code and forwards signals it
+receives to the command.`,
+		Example: `  # List pods in prod
+  tkube exec prod my-app-cluster -- kubectl get pods -A
+
+  # Run a helm release check in a script
+  tkube exec staging my-app-cluster -- helm status my-app || exit 1`,
+		Args:              cobra.MinimumNArgs(2),
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		ValidArgsFunction: completeEnvironmentCluster,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := args[2:]
+			if len(command) > 0 && command[0] == "--" {
+				command = command[1:]
+			}
+			return commandHandler.Exec(args[0], args[1], command)
+		},
+	}
+	// Everything after the cluster belongs to the command, including its flags
+	execCmd.Flags().SetInterspersed(false)
+
+	// Create kubeconfig command
+	kubeconfigCmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Work with the isolated kubeconfig files",
+		Long: `'tkube <env> <cluster>' writes each cluster's context to its own kubeconfig
+below the state directory (~/.tkube/kube/<env>/<cluster>.yaml), so connecting
+in one terminal never changes the context another terminal is using, and
+logging out removes the environment's kubeconfigs.
+
+Set merge_kubeconfig to also add connected clusters to the global kubeconfig
+(the first file in KUBECONFIG, or ~/.kube/config):
+
+  tkube config set merge_kubeconfig true`,
+		Example: `  # Point kubectl at a cluster for this shell
+  export KUBECONFIG=$(tkube kubeconfig path prod my-app-cluster)`,
+	}
+
+	kubeconfigPathCmd := &cobra.Command{
+		Use:               "path <environment> <cluster>",
+		Short:             "Print the kubeconfig of a cluster",
+		Args:              cobra.ExactArgs(2),
+		SilenceUsage:      true,
+		ValidArgsFunction: completeEnvironmentCluster,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commandHandler.KubeconfigPath(args[0], args[1])
+		},
+	}
+
+	kubeconfigCmd.AddCommand(kubeconfigPathCmd)
+
 	// Add commands to root
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(requestCmd)
+	rootCmd.AddCommand(kubeconfigCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(tshVersionsCmd)
 	rootCmd.AddCommand(installTSHCmd)
@@ -496,6 +1323,41 @@ PowerShell:
 
 	// Execute root command
 	if err := rootCmd.Execute(); err != nil {
+		// 'tkube exec' exits with the exit code of its command
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
+
+// isProfileCommand reports whether args run or complete a 'tkube profile' command
+func isProfileCommand(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--profile":
+			i++
+		case arg == cobra.ShellCompRequestCmd || arg == cobra.ShellCompNoDescRequestCmd || strings.HasPrefix(arg, "-"):
+			continue
+		default:
+			return arg == "profile"
+		}
+	}
+	return false
+}
+
+// profileFromArgs returns the value of the --profile flag, if given before a "--" separator
+func profileFromArgs(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--profile" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--profile="):
+			return strings.TrimPrefix(arg, "--profile=")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/teleport"
+)
+
+// sessionSource is the part of the Teleport client the agent needs
+type sessionSource interface {
+	GetSessionInfo(env, proxy string) *teleport.SessionInfo
+	GetClusters(env string) ([]string, error)
+}
+
+// Agent keeps the sessions and cluster lists of every environment up to date, warns before
+// sessions expire and serves its state over a Unix socket
+type Agent struct {
+	configManager *config.Manager
+	sessions      sessionSource
+	socketPath    string
+	// out receives the agent's log and the terminal bell
+	out io.Writer
+
+	mu      sync.RWMutex
+	state   State
+	options *config.AgentOptions
+
+	// warned records the session expiry each environment and event was last raised for
+	warned   map[string]time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates an agent serving on socketPath
+func New(configManager *config.Manager, teleportClient *teleport.Client, socketPath string) *Agent {
+	return newAgent(configManager, teleportClient, socketPath, os.Stdout)
+}
+
+// newAgent creates an agent reading sessions from the given source
+func newAgent(configManager *config.Manager, sessions sessionSource, socketPath string, out io.Writer) *Agent {
+	return &Agent{
+		configManager: configManager,
+		sessions:      sessions,
+		socketPath:    socketPath,
+		out:           out,
+		state: State{
+			PID:          os.Getpid(),
+			Started:      time.Now(),
+			Environments: make(map[string]EnvironmentState),
+		},
+		warned: make(map[string]time.Time),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Run checks the sessions periodically and serves the state until ctx is done or a stop request arrives
+func (a *Agent) Run(ctx context.Context) error {
+	if _, err := Query(a.socketPath); err == nil {
+		return fmt.Errorf("an agent is already running on %s", a.socketPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create agent directory: %w", err)
+	}
+	// A socket left behind by an agent that did not shut down cleanly would block the listener
+	os.Remove(a.socketPath)
+
+	listener, err := net.Listen("unix", a.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.socketPath, err)
+	}
+	defer os.Remove(a.socketPath)
+	defer listener.Close()
+	os.Chmod(a.socketPath, 0600)
+
+	go a.serve(listener)
+
+	a.refresh()
+	timer := time.NewTimer(a.interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-a.stop:
+			return nil
+		case <-timer.C:
+			a.refresh()
+			timer.Reset(a.interval())
+		}
+	}
+}
+
+// Stop makes Run return
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+// State returns the last known state
+func (a *Agent) State() State {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.state
+}
+
+// interval returns how long to wait between checks
+func (a *Agent) interval() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.options.IntervalDuration()
+}
+
+// refresh checks the session of every environment and refreshes outdated cluster lists.
+// tsh runs without holding the lock, so requests are answered from the previous state meanwhile.
+func (a *Agent) refresh() {
+	cfg, err := a.configManager.Load()
+	if err != nil {
+		a.mu.Lock()
+		a.state.Updated = time.Now()
+		a.state.Error = err.Error()
+		a.mu.Unlock()
+		fmt.Fprintf(a.out, "❌ Error loading configuration: %v\n", err)
+		return
+	}
+
+	previous := a.State().Environments
+	clustersInterval := cfg.Agent.ClustersIntervalDuration()
+
+	environments := make(map[string]EnvironmentState, len(cfg.Environments))
+	for env, envConfig := range cfg.Environments {
+		session := a.sessions.GetSessionInfo(env, envConfig.Proxy)
+
+		envState := EnvironmentState{Proxy: envConfig.Proxy, Session: session}
+		if old, ok := previous[env]; ok && old.Proxy == envConfig.Proxy {
+			envState.Clusters = old.Clusters
+			envState.ClustersUpdated = old.ClustersUpdated
+		}
+
+		if session.IsValid() && time.Since(envState.ClustersUpdated) >= clustersInterval {
+			if clusters, err := a.sessions.GetClusters(env); err != nil {
+				envState.Error = err.Error()
+			} else {
+				envState.Clusters = clusters
+				envState.ClustersUpdated = time.Now()
+			}
+		}
+
+		environments[env] = envState
+		a.checkExpiry(env, envState, cfg.Agent)
+	}
+
+	a.mu.Lock()
+	a.state.Updated = time.Now()
+	a.state.Error = ""
+	a.state.Environments = environments
+	a.options = cfg.Agent
+	a.mu.Unlock()
+}
+
+// checkExpiry raises an event once per session when it is about to expire and when it has expired
+func (a *Agent) checkExpiry(env string, envState EnvironmentState, options *config.AgentOptions) {
+	session := envState.Session
+
+	var name string
+	switch {
+	case session.IsAuthenticated && session.IsExpired:
+		// Sessions that were already gone when the agent started are not news
+		if session.ValidUntil.Before(a.state.Started) {
+			return
+		}
+		name = EventExpired
+	case session.NeedsRenewal(options.WarnBeforeDuration()):
+		name = EventExpiring
+	default:
+		return
+	}
+
+	key := env + " " + name
+	if a.warned[key].Equal(session.ValidUntil) {
+		return
+	}
+	a.warned[key] = session.ValidUntil
+
+	a.warn(Event{
+		Name:       name,
+		Env:        env,
+		Proxy:      envState.Proxy,
+		ValidUntil: session.ValidUntil,
+	}, options)
+}
+
+// serve answers requests until the listener is closed
+func (a *Agent) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go a.handle(conn)
+	}
+}
+
+// handle answers a single request
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	var resp response
+	switch command := strings.TrimSpace(line); command {
+	case requestStatus:
+		state := a.State()
+		resp.State = &state
+	case requestStop:
+		a.Stop()
+	default:
+		resp.Error = fmt.Sprintf("unknown request '%s'", command)
+	}
+
+	json.NewEncoder(conn).Encode(resp)
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/teleport"
+)
+
+// fakeSessions serves fixed sessions and cluster lists and counts cluster fetches
+type fakeSessions struct {
+	mu           sync.Mutex
+	sessions     map[string]*teleport.SessionInfo
+	clusters     map[string][]string
+	clusterCalls int
+}
+
+func (f *fakeSessions) GetSessionInfo(env, proxy string) *teleport.SessionInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if session, ok := f.sessions[env]; ok {
+		copied := *session
+		return &copied
+	}
+	return &teleport.SessionInfo{}
+}
+
+func (f *fakeSessions) GetClusters(env string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clusterCalls++
+	if clusters, ok := f.clusters[env]; ok {
+		return clusters, nil
+	}
+	return nil, fmt.Errorf("no clusters for %s", env)
+}
+
+// newTestManager creates a configuration manager for a temporary TKUBE_HOME with the given config.json
+func newTestManager(t *testing.T, configJSON string) (*config.Manager, string) {
+	t.Helper()
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("TKUBE_HOME", homeDir)
+	t.Setenv("TKUBE_CONFIG", "")
+	t.Setenv("TKUBE_PROFILE", "")
+	os.WriteFile(filepath.Join(homeDir, "config.json"), []byte(configJSON), 0644)
+
+	configManager, err := config.NewManager()
+	if err != nil {
+		t.Fatalf("Failed to create config manager: %v", err)
+	}
+	return configManager, homeDir
+}
+
+func TestAgent_ServesState(t *testing.T) {
+	configManager, homeDir := newTestManager(t, `{
+  "schema_version": 1,
+  "environments": {
+    "prod": {"proxy": "teleport.prod.company.com:443"},
+    "test": {"proxy": "teleport.test.company.com:443"}
+  }
+}`)
+	sessions := &fakeSessions{
+		sessions: map[string]*teleport.SessionInfo{
+			"prod": {IsAuthenticated: true, ValidUntil: time.Now().Add(8 * time.Hour), Username: "alice"},
+		},
+		clusters: map[string][]string{"prod": {"eks-prod", "eks-payments"}},
+	}
+
+	socket := filepath.Join(homeDir, "agent.sock")
+	a := newAgent(configManager, sessions, socket, &bytes.Buffer{})
+
+	done := make(chan error, 1)
+	go func() { done <- a.Run(context.Background()) }()
+
+	var state *State
+	var err error
+	for i := 0; i < 50; i++ {
+		if state, err = Query(socket); err == nil && !state.Updated.IsZero() {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Expected the agent to answer, got %v", err)
+	}
+
+	prod := state.Environments["prod"]
+	if !prod.Session.IsValid() || prod.Session.Username != "alice" {
+		t.Errorf("Expected a valid prod session, got %+v", prod.Session)
+	}
+	if !reflect.DeepEqual(prod.Clusters, []string{"eks-prod", "eks-payments"}) || !prod.HasClusters() {
+		t.Errorf("Expected cached prod clusters, got %+v", prod)
+	}
+	if test := state.Environments["test"]; test.Session.IsAuthenticated || test.HasClusters() {
+		t.Errorf("Expected no session or clusters for test, got %+v", test)
+	}
+
+	if err := Stop(socket); err != nil {
+		t.Fatalf("Expected stop to succeed, got %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected agent to exit cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected agent to stop")
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Error("Expected the socket to be removed")
+	}
+	if _, err := Query(socket); err == nil {
+		t.Error("Expected no answer from a stopped agent")
+	}
+}
+
+func TestAgent_RefreshKeepsClusterCache(t *testing.T) {
+	configManager, homeDir := newTestManager(t, `{
+  "schema_version": 1,
+  "environments": {"prod": {"proxy": "teleport.prod.company.com:443"}},
+  "agent": {"clusters_interval": "1h"}
+}`)
+	sessions := &fakeSessions{
+		sessions: map[string]*teleport.SessionInfo{"prod": {IsAuthenticated: true, ValidUntil: time.Now().Add(time.Hour)}},
+		clusters: map[string][]string{"prod": {"eks-prod"}},
+	}
+
+	a := newAgent(configManager, sessions, filepath.Join(homeDir, "agent.sock"), &bytes.Buffer{})
+	a.refresh()
+	a.refresh()
+	if sessions.clusterCalls != 1 {
+		t.Errorf("Expected clusters to be fetched once within clusters_interval, got %d fetches", sessions.clusterCalls)
+	}
+
+	// Logging out keeps the cached list without fetching again
+	sessions.sessions["prod"] = &teleport.SessionInfo{}
+	a.refresh()
+	if prod := a.State().Environments["prod"]; !reflect.DeepEqual(prod.Clusters, []string{"eks-prod"}) {
+		t.Errorf("Expected the cached clusters to be kept, got %+v", prod)
+	}
+	if sessions.clusterCalls != 1 {
+		t.Errorf("Expected no fetch without a session, got %d fetches", sessions.clusterCalls)
+	}
+}
+
+func TestAgent_WarnsOncePerSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook uses a POSIX shell")
+	}
+
+	eventsFile := filepath.Join(t.TempDir(), "events")
+	configManager, homeDir := newTestManager(t, fmt.Sprintf(`{
+  "schema_version": 1,
+  "environments": {"prod": {"proxy": "teleport.prod.company.com:443"}},
+  "agent": {"warn_before": "15m", "bell": true, "hook": "echo \"$TKUBE_EVENT $TKUBE_ENV $TKUBE_PROXY\" >> %s"}
+}`, eventsFile))
+	sessions := &fakeSessions{
+		sessions: map[string]*teleport.SessionInfo{"prod": {IsAuthenticated: true, ValidUntil: time.Now().Add(5 * time.Minute)}},
+	}
+
+	out := &bytes.Buffer{}
+	a := newAgent(configManager, sessions, filepath.Join(homeDir, "agent.sock"), out)
+	// Pretend the agent has been running for a while, so the expiry below happens after it started
+	a.state.Started = a.state.Started.Add(-time.Hour)
+	a.refresh()
+	a.refresh()
+
+	// A renewed session that is about to expire again is warned about again
+	sessions.sessions["prod"] = &teleport.SessionInfo{IsAuthenticated: true, ValidUntil: time.Now().Add(10 * time.Minute)}
+	a.refresh()
+
+	// Expiry is reported once as well
+	sessions.sessions["prod"] = &teleport.SessionInfo{IsAuthenticated: true, IsExpired: true, ValidUntil: time.Now().Add(-time.Second)}
+	a.refresh()
+	a.refresh()
+
+	data, err := os.ReadFile(eventsFile)
+	if err != nil {
+		t.Fatalf("Expected the hook to run, got %v", err)
+	}
+	expected := "expiring prod teleport.prod.company.com:443\nexpiring prod teleport.prod.company.com:443\nexpired prod teleport.prod.company.com:443\n"
+	if string(data) != expected {
+		t.Errorf("Expected events %q, got %q", expected, string(data))
+	}
+	if !strings.Contains(out.String(), "\a⚠️  prod session") {
+		t.Errorf("Expected a bell with the warning, got %q", out.String())
+	}
+}
+
+func TestAgent_IgnoresSessionsExpiredBeforeStart(t *testing.T) {
+	configManager, homeDir := newTestManager(t, `{
+  "schema_version": 1,
+  "environments": {"prod": {"proxy": "teleport.prod.company.com:443"}}
+}`)
+	sessions := &fakeSessions{
+		sessions: map[string]*teleport.SessionInfo{"prod": {IsAuthenticated: true, IsExpired: true, ValidUntil: time.Now().Add(-time.Hour)}},
+	}
+
+	out := &bytes.Buffer{}
+	a := newAgent(configManager, sessions, filepath.Join(homeDir, "agent.sock"), out)
+	a.refresh()
+
+	if out.Len() != 0 {
+		t.Errorf("Expected no warning for a session that expired before the agent started, got %q", out.String())
+	}
+}
+
+func TestEvent_Message(t *testing.T) {
+	expiring := Event{Name: EventExpiring, Env: "prod", Proxy: "teleport.prod.company.com:443", ValidUntil: time.Now().Add(10*time.Minute + 20*time.Second)}
+	if message := expiring.Message(); message != "prod session (teleport.prod.company.com:443) expires in 10m" {
+		t.Errorf("Unexpected message %q", message)
+	}
+
+	expired := Event{Name: EventExpired, Env: "prod", Proxy: "teleport.prod.company.com:443"}
+	if message := expired.Message(); !strings.Contains(message, "has expired") || !strings.Contains(message, "tkube login prod") {
+		t.Errorf("Unexpected message %q", message)
+	}
+}
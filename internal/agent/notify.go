@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/teleport"
+)
+
+// Session events raised by the agent, passed to hooks as TKUBE_EVENT
+const (
+	EventExpiring = "expiring"
+	EventExpired  = "expired"
+)
+
+// commandTimeout stops notification commands and hooks that do not finish
+const commandTimeout = 30 * time.Second
+
+// Event is a change of a session the user is warned about
+type Event struct {
+	Name       string
+	Env        string
+	Proxy      string
+	ValidUntil time.Time
+}
+
+// Message describes the event for humans
+func (e Event) Message() string {
+	if e.Name == EventExpired {
+		return fmt.Sprintf("%s session (%s) has expired - run 'tkube login %s'", e.Env, e.Proxy, e.Env)
+	}
+
+	remaining := time.Until(e.ValidUntil).Round(time.Minute)
+	if remaining < time.Minute {
+		return fmt.Sprintf("%s session (%s) expires in less than a minute", e.Env, e.Proxy)
+	}
+	return fmt.Sprintf("%s session (%s) expires in %s", e.Env, e.Proxy, strings.TrimSuffix(remaining.String(), "0s"))
+}
+
+// environ returns the variables describing the event to a hook
+func (e Event) environ() []string {
+	return []string{
+		"TKUBE_EVENT=" + e.Name,
+		"TKUBE_ENV=" + e.Env,
+		"TKUBE_PROXY=" + e.Proxy,
+		"TKUBE_VALID_UNTIL=" + e.ValidUntil.Format(time.RFC3339),
+		"TKUBE_MESSAGE=" + e.Message(),
+	}
+}
+
+// warn logs the event and passes it to the terminal bell, the notification command and the hook
+func (a *Agent) warn(event Event, options *config.AgentOptions) {
+	bell := ""
+	if options != nil && options.Bell {
+		bell = "\a"
+	}
+	fmt.Fprintf(a.out, "%s⚠️  %s\n", bell, event.Message())
+
+	if options == nil {
+		return
+	}
+
+	if fields := strings.Fields(options.NotifyCommand); len(fields) > 0 {
+		args := append(fields[1:], "tkube", event.Message())
+		if err := runCommand(fields[0], args, nil); err != nil {
+			fmt.Fprintf(a.out, "⚠️  Notification command failed: %v\n", err)
+		}
+	}
+
+	if options.Hook != "" {
+		name, args := "sh", []string{"-c", options.Hook}
+		if runtime.GOOS == "windows" {
+			name, args = "cmd", []string{"/C", options.Hook}
+		}
+		if err := runCommand(name, args, event.environ()); err != nil {
+			fmt.Fprintf(a.out, "⚠️  Hook failed: %v\n", err)
+		}
+	}
+}
+
+// runCommand runs a notification command or hook with extra environment variables
+func runCommand(name string, args, environ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = teleport.SetEnviron(os.Environ(), environ...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%w: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+	"tkube/internal/teleport"
+)
+
+// requestTimeout bounds a request to the agent, so prompts and completion never hang on it
+const requestTimeout = time.Second
+
+// Requests understood by the agent, one per connection
+const (
+	requestStatus = "status"
+	requestStop   = "stop"
+)
+
+// State is what the agent knows about the environments of its profile
+type State struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	// Updated is when the sessions were last checked
+	Updated time.Time `json:"updated"`
+	// Error is set if the configuration could not be loaded on the last check
+	Error        string                      `json:"error,omitempty"`
+	Environments map[string]EnvironmentState `json:"environments"`
+}
+
+// EnvironmentState is the session and cluster list of an environment
+type EnvironmentState struct {
+	Proxy   string                `json:"proxy"`
+	Session *teleport.SessionInfo `json:"session"`
+	// Clusters is the cached cluster list; it is kept while the session is invalid
+	Clusters        []string  `json:"clusters,omitempty"`
+	ClustersUpdated time.Time `json:"clusters_updated"`
+	// Error is set if the cluster list could not be refreshed
+	Error string `json:"error,omitempty"`
+}
+
+// HasClusters reports whether a cluster list has been fetched for the environment
+func (e EnvironmentState) HasClusters() bool {
+	return !e.ClustersUpdated.IsZero()
+}
+
+// response is the agent's answer to a request
+type response struct {
+	State *State `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Query returns the state of the agent listening on socketPath.
+// Sessions that expired since the agent's last check are marked as expired.
+func Query(socketPath string) (*State, error) {
+	resp, err := request(socketPath, requestStatus)
+	if err != nil {
+		return nil, err
+	}
+	if resp.State == nil {
+		return nil, fmt.Errorf("agent returned no state")
+	}
+
+	now := time.Now()
+	for _, envState := range resp.State.Environments {
+		if session := envState.Session; session != nil && session.IsAuthenticated && !session.ValidUntil.IsZero() && !now.Before(session.ValidUntil) {
+			session.IsExpired = true
+		}
+	}
+	return resp.State, nil
+}
+
+// Stop asks the agent listening on socketPath to exit
+func Stop(socketPath string) error {
+	_, err := request(socketPath, requestStop)
+	return err
+}
+
+// request sends a request to the agent and decodes its response
+func request(socketPath, command string) (*response, error) {
+	conn, err := net.DialTimeout("unix", socketPath, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("agent is not running: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+
+	var resp response
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tkube/internal/paths"
+)
+
+// serviceEnvironment lists the variables that locate tkube's files; the systemd user
+// manager does not inherit them from the shell the service is installed from
+var serviceEnvironment = []string{paths.EnvHome, paths.EnvConfig, "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"}
+
+// ServiceName returns the name of the systemd user unit running the agent of a profile
+func ServiceName(profile string) string {
+	if profile == "" || profile == paths.DefaultProfile {
+		return "tkube-agent.service"
+	}
+	return "tkube-agent-" + profile + ".service"
+}
+
+// ServiceDir returns the directory systemd loads user units from
+func ServiceDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "systemd", "user"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "systemd", "user"), nil
+}
+
+// ServiceUnit renders a systemd user unit running the agent of a profile with the given tkube binary
+func ServiceUnit(executable, profile string) string {
+	execStart := quoteServiceArg(executable)
+	description := "tkube session agent"
+	if profile != "" && profile != paths.DefaultProfile {
+		execStart += " --profile " + quoteServiceArg(profile)
+		description += " (" + profile + " profile)"
+	}
+	execStart += " agent"
+
+	var unit strings.Builder
+	fmt.Fprintf(&unit, "[Unit]\nDescription=%s\nDocumentation=https://github.com/lidin10/tkube\n\n", description)
+	unit.WriteString("[Service]\n")
+	for _, name := range serviceEnvironment {
+		if value := os.Getenv(name); value != "" {
+			fmt.Fprintf(&unit, "Environment=%s\n", quoteServiceArg(name+"="+value))
+		}
+	}
+	fmt.Fprintf(&unit, "ExecStart=%s\n", execStart)
+	unit.WriteString("Restart=on-failure\nRestartSec=10\n\n")
+	unit.WriteString("[Install]\nWantedBy=default.target\n")
+	return unit.String()
+}
+
+// quoteServiceArg quotes a unit file argument containing spaces
+func quoteServiceArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"\\") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceName(t *testing.T) {
+	tests := map[string]string{
+		"":         "tkube-agent.service",
+		"default":  "tkube-agent.service",
+		"customer": "tkube-agent-customer.service",
+	}
+	for profile, expected := range tests {
+		if name := ServiceName(profile); name != expected {
+			t.Errorf("ServiceName(%q) = %s, expected %s", profile, name, expected)
+		}
+	}
+}
+
+func TestServiceUnit(t *testing.T) {
+	t.Setenv("TKUBE_HOME", "/home/alice/tkube home")
+	t.Setenv("TKUBE_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	unit := ServiceUnit("/usr/local/bin/tkube", "default")
+	for _, line := range []string{
+		"ExecStart=/usr/local/bin/tkube agent\n",
+		"Environment=\"TKUBE_HOME=/home/alice/tkube home\"\n",
+		"Restart=on-failure\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, line) {
+			t.Errorf("Expected unit to contain %q, got:\n%s", line, unit)
+		}
+	}
+	if strings.Contains(unit, "XDG_") {
+		t.Errorf("Expected unset variables to be left out, got:\n%s", unit)
+	}
+
+	unit = ServiceUnit("/opt/my tools/tkube", "customer")
+	if !strings.Contains(unit, "ExecStart=\"/opt/my tools/tkube\" --profile customer agent\n") {
+		t.Errorf("Expected a quoted binary and the profile, got:\n%s", unit)
+	}
+	if !strings.Contains(unit, "(customer profile)") {
+		t.Errorf("Expected the profile in the description, got:\n%s", unit)
+	}
+}
+
+func TestServiceDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/alice/.xdg")
+	if dir, err := ServiceDir(); err != nil || dir != filepath.Join("/home/alice/.xdg", "systemd", "user") {
+		t.Errorf("Expected the unit dir below XDG_CONFIG_HOME, got %s (%v)", dir, err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/alice")
+	if dir, err := ServiceDir(); err != nil || dir != filepath.Join("/home/alice", ".config", "systemd", "user") {
+		t.Errorf("Expected the unit dir below ~/.config, got %s (%v)", dir, err)
+	}
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+	"tkube/internal/agent"
+	"tkube/internal/paths"
+)
+
+// agentSocket returns the socket of the active profile's agent
+func (h *Handler) agentSocket() (string, error) {
+	p, err := paths.Resolve()
+	if err != nil {
+		return "", err
+	}
+	return p.AgentSocket, nil
+}
+
+// RunAgent runs the session agent in the foreground until it is stopped
+func (h *Handler) RunAgent() error {
+	socket, err := h.agentSocket()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("🛰️  tkube agent watching sessions of profile %s (socket: %s)\n", h.configManager.ActiveProfile(), socket)
+	if err := agent.New(h.configManager, h.teleportClient, socket).Run(ctx); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	fmt.Println("👋 tkube agent stopped")
+	return nil
+}
+
+// StopAgent asks the running agent to exit
+func (h *Handler) StopAgent() error {
+	socket, err := h.agentSocket()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if err := agent.Stop(socket); err != nil {
+		fmt.Println("ℹ️  No agent is running")
+		return nil
+	}
+	fmt.Println("✅ Agent stopped")
+	fmt.Printf("💡 If it runs under systemd, disable it with: systemctl --user disable %s\n", agent.ServiceName(h.configManager.ActiveProfile()))
+	return nil
+}
+
+// ShowAgentStatus prints the state of the running agent
+func (h *Handler) ShowAgentStatus(jsonOutput bool) error {
+	socket, err := h.agentSocket()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	state, err := agent.Query(socket)
+	if err != nil {
+		if jsonOutput {
+			return err
+		}
+		fmt.Println("ℹ️  No agent is running")
+		fmt.Println("💡 Start it with 'tkube agent' or 'tkube agent install-service'")
+		return nil
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("🛰️  Agent running (pid %d, checked %s ago)\n", state.PID, time.Since(state.Updated).Round(time.Second))
+	if state.Error != "" {
+		fmt.Printf("⚠️  %s\n", state.Error)
+	}
+	fmt.Println()
+
+	envs := make([]string, 0, len(state.Environments))
+	for env := range state.Environments {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	for _, env := range envs {
+		envState := state.Environments[env]
+		session := envState.Session
+		switch {
+		case session == nil || !session.IsAuthenticated:
+			fmt.Printf("  ❌ %s → %s (not authenticated)\n", env, envState.Proxy)
+		case session.IsExpired:
+			fmt.Printf("  ⏰ %s → %s (expired)\n", env, envState.Proxy)
+		case session.ValidUntil.IsZero():
+			fmt.Printf("  ✅ %s → %s (authenticated)\n", env, envState.Proxy)
+		default:
+			fmt.Printf("  ✅ %s → %s (%s left)\n", env, envState.Proxy, h.formatTimeRemaining(session.TimeRemaining().Round(time.Minute).String()))
+		}
+
+		if envState.HasClusters() {
+			fmt.Printf("      ☸️  %d clusters (updated %s ago)\n", len(envState.Clusters), time.Since(envState.ClustersUpdated).Round(time.Second))
+		}
+		if envState.Error != "" {
+			fmt.Printf("      ⚠️  %s\n", envState.Error)
+		}
+	}
+	return nil
+}
+
+// InstallAgentService writes a systemd user unit running the agent of the active profile
+func (h *Handler) InstallAgentService(printOnly bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Printf("❌ Cannot determine the tkube binary: %v\n", err)
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	profile := h.configManager.ActiveProfile()
+	unit := agent.ServiceUnit(executable, profile)
+	name := agent.ServiceName(profile)
+
+	if printOnly {
+		fmt.Print(unit)
+		return nil
+	}
+
+	dir, err := agent.ServiceDir()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("❌ Failed to create %s: %v\n", dir, err)
+		return err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(unit), 0644); err != nil {
+		fmt.Printf("❌ Failed to write %s: %v\n", path, err)
+		return err
+	}
+
+	fmt.Printf("✅ Wrote %s\n", path)
+	fmt.Println("💡 Enable and start it with:")
+	fmt.Printf("   systemctl --user daemon-reload && systemctl --user enable --now %s\n", name)
+	return nil
+}
@@ -1,15 +1,20 @@
 package commands
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 	"tkube/internal/config"
 	"tkube/internal/kubectl"
+	"tkube/internal/paths"
 	"tkube/internal/teleport"
 )
 
@@ -19,6 +24,7 @@ type Handler struct {
 	teleportClient *teleport.Client
 	kubectlClient  *kubectl.Client
 	installer      *teleport.TSHInstaller
+	input          *bufio.Reader
 }
 
 // NewHandler creates a new command handler
@@ -28,29 +34,49 @@ func NewHandler(configManager *config.Manager, teleportClient *teleport.Client,
 		teleportClient: teleportClient,
 		kubectlClient:  kubectlClient,
 		installer:      installer,
+		input:          bufio.NewReader(os.Stdin),
 	}
 }
 
 // ConnectToCluster connects to a Kubernetes cluster via Teleport
 func (h *Handler) ConnectToCluster(env, cluster string) error {
-	config, err := h.configManager.Load()
+	config, cluster, err := h.connect(env, cluster, true, os.Stdout)
 	if err != nil {
-		fmt.Printf("❌ Error loading configuration: %v\n", err)
-		fmt.Println("💡 Run 'tkube config path' to see the expected config location")
 		return err
 	}
 
+	h.useKubeconfig(config, env, cluster)
+	return nil
+}
+
+// connect authenticates to an environment and logs in to one of its clusters, installing tsh if needed.
+// Unless interactive, tsh is installed without asking and denied access is not offered as an access request.
+// Progress is written to out. It returns the configuration and the cluster name with aliases resolved.
+func (h *Handler) connect(env, cluster string, interactive bool, out io.Writer) (*config.Config, string, error) {
+	config, err := h.configManager.Load()
+	if err != nil {
+		fmt.Fprintf(out, "❌ Error loading configuration: %v\n", err)
+		fmt.Fprintln(out, "💡 Run 'tkube config path' to see the expected config location")
+		return nil, "", err
+	}
+
 	envConfig, exists := config.Environments[env]
 	if !exists {
-		fmt.Printf("❌ Unknown environment '%s'\n", env)
-		fmt.Println()
-		fmt.Printf("Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
-		fmt.Println()
-		fmt.Println("💡 Tips:")
-		fmt.Println("   • Run 'tkube status' to see all configured environments")
-		fmt.Println("   • Run 'tkube config show' to see your configuration")
-		fmt.Println("   • Use tab completion: tkube <TAB>")
-		return fmt.Errorf("unknown environment")
+		fmt.Fprintf(out, "❌ Unknown environment '%s'\n", env)
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "💡 Tips:")
+		fmt.Fprintln(out, "   • Run 'tkube status' to see all configured environments")
+		fmt.Fprintln(out, "   • Run 'tkube config show' to see your configuration")
+		fmt.Fprintln(out, "   • Use tab completion: tkube <TAB>")
+		return nil, "", fmt.Errorf("unknown environment")
+	}
+
+	// Resolve cluster aliases to the real Teleport cluster name
+	if resolved := envConfig.ResolveCluster(cluster); resolved != cluster {
+		fmt.Fprintf(out, "🔗 %s → %s\n", cluster, resolved)
+		cluster = resolved
 	}
 
 	// Auto-detect tsh version if not set
@@ -61,68 +87,92 @@ func (h *Handler) ConnectToCluster(env, cluster string) error {
 			// Update configuration with detected version
 			if err := h.configManager.UpdateEnvironmentTSHVersion(env, version); err == nil {
 				envConfig.TSHVersion = version
-				fmt.Printf("🔍 Auto-detected tsh version %s\n", version)
+				fmt.Fprintf(out, "🔍 Auto-detected tsh version %s\n", version)
 			}
 		} else {
-			fmt.Printf("⚠️  Could not auto-detect tsh version: %v\n", err)
-			fmt.Println("💡 You can manually set the version in your config file")
+			fmt.Fprintf(out, "⚠️  Could not auto-detect tsh version: %v\n", err)
+			fmt.Fprintln(out, "💡 You can manually set the version in your config file")
 		}
 	}
 
 	// Check if required tsh version is installed
 	if envConfig.TSHVersion != "" {
 		if !h.installer.IsVersionInstalled(envConfig.TSHVersion) {
-			fmt.Printf("📦 tsh v%s not installed - installing...\n", envConfig.TSHVersion)
+			fmt.Fprintf(out, "📦 tsh v%s not installed - installing...\n", envConfig.TSHVersion)
 
 			// Ask user if they want to install automatically
-			if h.promptForInstallation(envConfig.TSHVersion) {
+			if !interactive || h.promptForInstallation(envConfig.TSHVersion) {
 				if err := h.installer.InstallTSH(envConfig.TSHVersion); err != nil {
-					fmt.Printf("❌ Installation failed: %v\n", err)
-					fmt.Printf("💡 Try: tkube install-tsh %s\n", envConfig.TSHVersion)
-					return fmt.Errorf("installation failed")
+					fmt.Fprintf(out, "❌ Installation failed: %v\n", err)
+					fmt.Fprintf(out, "💡 Try: tkube install-tsh %s\n", envConfig.TSHVersion)
+					return nil, "", fmt.Errorf("installation failed")
 				}
-				fmt.Printf("✅ tsh v%s installed\n", envConfig.TSHVersion)
+				fmt.Fprintf(out, "✅ tsh v%s installed\n", envConfig.TSHVersion)
 
 				// Give filesystem time to sync and verify installation
 				time.Sleep(100 * time.Millisecond)
 				if !h.installer.IsVersionInstalled(envConfig.TSHVersion) {
-					fmt.Printf("⚠️  Installation completed but verification failed\n")
-					fmt.Printf("💡 Try running the command again\n")
-					return fmt.Errorf("installation verification failed")
+					fmt.Fprintf(out, "⚠️  Installation completed but verification failed\n")
+					fmt.Fprintf(out, "💡 Try running the command again\n")
+					return nil, "", fmt.Errorf("installation verification failed")
 				}
 			} else {
-				fmt.Printf("💡 Run: tkube install-tsh %s\n", envConfig.TSHVersion)
-				return fmt.Errorf("required tsh version %s not installed", envConfig.TSHVersion)
+				fmt.Fprintf(out, "💡 Run: tkube install-tsh %s\n", envConfig.TSHVersion)
+				return nil, "", fmt.Errorf("required tsh version %s not installed", envConfig.TSHVersion)
 			}
 		}
 	}
 
 	// Check authentication status
-	if !h.teleportClient.IsAuthenticatedWithEnv(env, envConfig.Proxy) {
+	session := h.teleportClient.SessionInfoWithEnv(env, envConfig.Proxy)
+	if threshold := config.RenewThreshold(env); session.NeedsRenewal(threshold) {
+		// Renew now rather than being logged out in the middle of the work
+		timeStr := h.formatTimeRemaining(session.TimeRemaining().Round(time.Minute).String())
+		if config.AutoLogin {
+			fmt.Fprintf(out, "🔄 Session expires in %s (renew_before %s) - renewing...\n", timeStr, h.formatTimeRemaining(threshold.String()))
+			if err := h.teleportClient.LoginWithEnv(env, envConfig.Proxy, out); err != nil {
+				fmt.Fprintf(out, "⚠️  Renewal failed, continuing with the current session: %v\n", err)
+			}
+		} else {
+			fmt.Fprintf(out, "⚠️  Session expires in %s\n", timeStr)
+			fmt.Fprintf(out, "💡 Run: tkube login %s\n", env)
+		}
+	} else if !session.IsValid() {
 		if config.AutoLogin {
-			fmt.Printf("🔐 Authenticating to %s...\n", envConfig.Proxy)
-			if err := h.teleportClient.LoginWithEnv(env, envConfig.Proxy); err != nil {
-				fmt.Printf("❌ Authentication failed\n")
-				fmt.Printf("💡 Try: tsh login --proxy=%s\n", envConfig.Proxy)
-				return err
+			fmt.Fprintf(out, "🔐 Authenticating to %s...\n", envConfig.Proxy)
+			if err := h.teleportClient.LoginWithEnv(env, envConfig.Proxy, out); err != nil {
+				fmt.Fprintf(out, "❌ Authentication failed\n")
+				fmt.Fprintf(out, "💡 Try: tsh login --proxy=%s\n", envConfig.Proxy)
+				return nil, "", err
 			}
 		} else {
-			fmt.Printf("❌ Not authenticated to %s\n", envConfig.Proxy)
-			fmt.Printf("💡 Run: tsh login --proxy=%s\n", envConfig.Proxy)
-			return fmt.Errorf("authentication required")
+			fmt.Fprintf(out, "❌ Not authenticated to %s\n", envConfig.Proxy)
+			fmt.Fprintf(out, "💡 Run: tsh login --proxy=%s\n", envConfig.Proxy)
+			return nil, "", fmt.Errorf("authentication required")
 		}
 	}
 
 	// Connect to Kubernetes cluster
-	fmt.Printf("🚀 Connecting to %s/%s...\n", env, cluster)
-	if err := h.teleportClient.KubeLoginWithEnv(env, envConfig.Proxy, cluster); err != nil {
-		fmt.Printf("❌ Connection failed\n")
-		fmt.Printf("💡 Check cluster name with: tkube %s <TAB>\n", env)
-		return err
+	fmt.Fprintf(out, "🚀 Connecting to %s/%s...\n", env, cluster)
+	if err := h.teleportClient.KubeLoginWithEnv(env, envConfig.Proxy, cluster, out); err != nil {
+		switch {
+		case errors.Is(err, teleport.ErrKubeAccessDenied) && interactive:
+			if err := h.requestKubeAccess(env, envConfig.Proxy, cluster); err != nil {
+				return nil, "", err
+			}
+		case errors.Is(err, teleport.ErrKubeAccessDenied):
+			fmt.Fprintf(out, "🚫 Access to %s/%s was denied\n", env, cluster)
+			fmt.Fprintf(out, "💡 Request access with: tkube %s %s\n", env, cluster)
+			return nil, "", err
+		default:
+			fmt.Fprintf(out, "❌ Connection failed\n")
+			fmt.Fprintf(out, "💡 Check cluster name with: tkube %s <TAB>\n", env)
+			return nil, "", err
+		}
 	}
 
-	fmt.Printf("✅ Connected to %s/%s\n", env, cluster)
-	return nil
+	fmt.Fprintf(out, "✅ Connected to %s/%s\n", env, cluster)
+	return config, cluster, nil
 }
 
 // ShowVersion displays version information
@@ -179,31 +229,28 @@ func (h *Handler) ShowVersion(version string) {
 	fmt.Println("🔧 Installed tsh versions:")
 
 	// Show all installed tsh versions with paths
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		tshBaseDir := filepath.Join(homeDir, ".tkube", "tsh")
-		if _, err := os.Stat(tshBaseDir); err == nil {
-			// List installed versions
-			versions, err := h.teleportClient.GetInstalledTSHVersions()
-			if err == nil && len(versions) > 0 {
-				for _, version := range versions {
-					tshPath := filepath.Join(tshBaseDir, version, "tsh")
-					if h.installer.IsVersionInstalled(version) {
-						versionInfo := h.installer.GetTSHVersionInfo(tshPath)
-						fmt.Printf("  ✅ tsh %s: %s\n", version, tshPath)
-						fmt.Printf("      Version: %s\n", versionInfo)
-					} else {
-						fmt.Printf("  ⚠️  tsh %s: %s (not fully installed)\n", version, tshPath)
-					}
+	tshBaseDir := h.installer.GetBaseDir()
+	if _, err := os.Stat(tshBaseDir); err == nil {
+		// List installed versions
+		versions, err := h.teleportClient.GetInstalledTSHVersions()
+		if err == nil && len(versions) > 0 {
+			for _, version := range versions {
+				tshPath := filepath.Join(tshBaseDir, version, "tsh")
+				if h.installer.IsVersionInstalled(version) {
+					versionInfo := h.installer.GetTSHVersionInfo(tshPath)
+					fmt.Printf("  ✅ tsh %s: %s\n", version, tshPath)
+					fmt.Printf("      Version: %s\n", versionInfo)
+				} else {
+					fmt.Printf("  ⚠️  tsh %s: %s (not fully installed)\n", version, tshPath)
 				}
-			} else {
-				fmt.Println("  📁 No custom tsh versions installed")
-				fmt.Println("     Use 'tkube install-tsh <version>' to install specific versions")
 			}
 		} else {
-			fmt.Println("  📁 No custom tsh versions directory found")
+			fmt.Println("  📁 No custom tsh versions installed")
 			fmt.Println("     Use 'tkube install-tsh <version>' to install specific versions")
 		}
+	} else {
+		fmt.Println("  📁 No custom tsh versions directory found")
+		fmt.Println("     Use 'tkube install-tsh <version>' to install specific versions")
 	}
 
 	fmt.Println()
@@ -217,14 +264,14 @@ func (h *Handler) ShowVersion(version string) {
 	fmt.Println("   tkube install-tsh         # Install specific tsh version")
 }
 
-// ShowStatus displays environment status
-func (h *Handler) ShowStatus() {
+// ShowStatus displays environment status, limited to the environments matching selector if it is set
+func (h *Handler) ShowStatus(selector string) error {
 	config, err := h.configManager.Load()
 	if err != nil {
 		fmt.Printf("❌ Error loading configuration: %v\n", err)
 		fmt.Println()
 		fmt.Println("💡 Tip: Run 'tkube config path' to see the config file location")
-		return
+		return err
 	}
 
 	if len(config.Environments) == 0 {
@@ -249,36 +296,51 @@ func (h *Handler) ShowStatus() {
 		fmt.Println("  }")
 		fmt.Println()
 		fmt.Println("💡 Tip: Run 'tkube config show' to see your current configuration")
-		return
+		return nil
 	}
 
+	envs, err := h.selectEnvironments(config, selector)
+	if err != nil {
+		return err
+	}
+
+	if profile := h.configManager.ActiveProfile(); profile != paths.DefaultProfile {
+		fmt.Printf("👤 Profile: %s\n", profile)
+	}
 	fmt.Println("🌍 Available environments and authentication status:")
 	fmt.Println()
 
-	for env, envConfig := range config.Environments {
+	for _, env := range envs {
+		envConfig := config.Environments[env]
 		sessionInfo := h.teleportClient.GetSessionInfo(env, envConfig.Proxy)
 
 		if sessionInfo.IsAuthenticated {
 			if sessionInfo.IsExpired {
 				fmt.Printf("  \033[33m⏰ %s → %s (expired)\033[0m\n", env, envConfig.Proxy)
-			} else if sessionInfo.TimeRemaining != "" {
+			} else if !sessionInfo.ValidUntil.IsZero() {
 				// Format time remaining for better readability
-				timeStr := h.formatTimeRemaining(sessionInfo.TimeRemaining)
+				remaining := sessionInfo.TimeRemaining()
+				timeStr := h.formatTimeRemaining(remaining.Round(time.Minute).String())
 
 				// Color code based on time remaining
 				var color string
-				if !strings.Contains(timeStr, "h") {
-					// Less than 1 hour - yellow warning
-					color = "\033[33m⚠️"
-				} else if strings.HasPrefix(timeStr, "1h") || strings.HasPrefix(timeStr, "2h") {
-					// 1-2 hours - yellow warning
+				if remaining < 3*time.Hour {
+					// Less than 3 hours - yellow warning
 					color = "\033[33m⚠️"
 				} else {
-					// More than 2 hours - green
+					// 3 hours or more - green
 					color = "\033[32m✅"
 				}
 
-				fmt.Printf("  %s %s → %s (%s left)\033[0m\n", color, env, envConfig.Proxy, timeStr)
+				if threshold := config.RenewThreshold(env); sessionInfo.NeedsRenewal(threshold) {
+					renewal := "renewed on next connect"
+					if !config.AutoLogin {
+						renewal = "run 'tkube login " + env + "'"
+					}
+					fmt.Printf("  \033[33m🔄 %s → %s (%s left, below renew_before %s - %s)\033[0m\n", env, envConfig.Proxy, timeStr, h.formatTimeRemaining(threshold.String()), renewal)
+				} else {
+					fmt.Printf("  %s %s → %s (%s left)\033[0m\n", color, env, envConfig.Proxy, timeStr)
+				}
 			} else {
 				fmt.Printf("  \033[32m✅ %s → %s (authenticated)\033[0m\n", env, envConfig.Proxy)
 			}
@@ -297,6 +359,10 @@ func (h *Handler) ShowStatus() {
 		} else {
 			fmt.Printf("      🔧 Using system tsh\n")
 		}
+
+		if len(envConfig.Tags) > 0 {
+			fmt.Printf("      🏷️  %s\n", formatTags(envConfig.Tags))
+		}
 	}
 
 	fmt.Println()
@@ -308,28 +374,37 @@ func (h *Handler) ShowStatus() {
 	fmt.Println()
 	fmt.Println("💡 Usage: tkube <environment> <cluster>")
 	fmt.Println("💡 Tab completion: tkube <TAB> to see environments, tkube prod <TAB> to see clusters")
+	return nil
 }
 
-// ShowConfig displays the current configuration
+// ShowConfig displays the current configuration, annotating each value with the layer it came from
 func (h *Handler) ShowConfig() {
-	config, err := h.configManager.Load()
+	config, sources, err := h.configManager.LoadWithSources()
 	if err != nil {
 		fmt.Printf("❌ Error loading configuration: %v\n", err)
 		return
 	}
 
-	fmt.Printf("📍 Configuration file: %s\n", h.configManager.GetPath())
+	h.printConfigLayers()
 	fmt.Println()
 
-	// Pretty print the configuration
-	data, err := json.MarshalIndent(config, "", "  ")
+	// Render the effective configuration through its JSON representation
+	data, err := json.Marshal(config)
 	if err != nil {
 		fmt.Printf("❌ Error formatting configuration: %v\n", err)
 		return
 	}
 
+	var merged map[string]interface{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		fmt.Printf("❌ Error formatting configuration: %v\n", err)
+		return
+	}
+
 	fmt.Println("📄 Current configuration:")
-	fmt.Println(string(data))
+	var out strings.Builder
+	writeAnnotatedConfig(&out, merged, "", "", sources)
+	fmt.Println(out.String())
 	fmt.Println()
 
 	if len(config.Environments) > 0 {
@@ -340,8 +415,44 @@ func (h *Handler) ShowConfig() {
 	}
 }
 
+// writeAnnotatedConfig renders a configuration object as JSON with a trailing comment naming the source layer of each value
+func writeAnnotatedConfig(out *strings.Builder, node map[string]interface{}, path, indent string, sources config.Sources) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out.WriteString("{\n")
+	for i, key := range keys {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		comma := ","
+		if i == len(keys)-1 {
+			comma = ""
+		}
+
+		fmt.Fprintf(out, "%s  %q: ", indent, key)
+		if child, ok := node[key].(map[string]interface{}); ok && len(child) > 0 {
+			writeAnnotatedConfig(out, child, childPath, indent+"  ", sources)
+			out.WriteString(comma + "\n")
+			continue
+		}
+
+		value, _ := json.Marshal(node[key])
+		fmt.Fprintf(out, "%s%s  // %s\n", value, comma, sources.Lookup(childPath))
+	}
+	out.WriteString(indent + "}")
+}
+
 // ShowConfigPath displays the configuration file path
 func (h *Handler) ShowConfigPath() {
+	if profile := h.configManager.ActiveProfile(); profile != paths.DefaultProfile {
+		fmt.Printf("👤 Profile: %s\n", profile)
+	}
 	fmt.Printf("📍 Configuration file location:\n")
 	fmt.Printf("   %s\n", h.configManager.GetPath())
 	fmt.Println()
@@ -353,6 +464,21 @@ func (h *Handler) ShowConfigPath() {
 		fmt.Println("✅ Configuration file exists.")
 		fmt.Println("💡 Run 'tkube config show' to see its contents.")
 	}
+
+	fmt.Println()
+	h.printConfigLayers()
+}
+
+// printConfigLayers lists the configuration layers and whether their files exist
+func (h *Handler) printConfigLayers() {
+	fmt.Println("📚 Configuration layers (lowest to highest precedence):")
+	for _, layer := range h.configManager.Layers() {
+		if layer.Exists {
+			fmt.Printf("   ✅ %-8s %s\n", layer.Name, layer.Path)
+		} else {
+			fmt.Printf("   ➖ %-8s %s (not present)\n", layer.Name, layer.Path)
+		}
+	}
 }
 
 // InstallTSH installs a specific tsh version
@@ -383,13 +509,7 @@ func (h *Handler) AutoInstallTSH(version string) error {
 
 // ShowTSHVersions displays installed tsh versions
 func (h *Handler) ShowTSHVersions() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Printf("❌ Error getting home directory: %v\n", err)
-		return
-	}
-
-	tshBaseDir := filepath.Join(homeDir, ".tkube", "tsh")
+	tshBaseDir := h.installer.GetBaseDir()
 
 	// Check if tsh directory exists
 	if _, err := os.Stat(tshBaseDir); os.IsNotExist(err) {
@@ -521,22 +641,7 @@ func (h *Handler) AutoDetectVersions() {
 
 // promptForInstallation asks the user if they want to install the required tsh version
 func (h *Handler) promptForInstallation(version string) bool {
-	fmt.Printf("🔧 Would you like to automatically install tsh version %s? (Y/n): ", version)
-
-	// Read user input
-	var response string
-	fmt.Scanln(&response)
-
-	// Normalize response
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	// Default to yes if no input or "y", "yes"
-	if response == "" || response == "y" || response == "yes" {
-		return true
-	}
-
-	// Return false for "n", "no", or any other input
-	return false
+	return h.confirm(fmt.Sprintf("🔧 Would you like to automatically install tsh version %s?", version), true)
 }
 
 // formatTimeRemaining formats time duration for better readability
@@ -561,32 +666,9 @@ func (h *Handler) formatTimeRemaining(timeStr string) string {
 
 	return timeStr
 }
-// AddEnvironmentInteractive adds a new environment interactively
-func (h *Handler) AddEnvironmentInteractive() error {
-	// TODO: Implement interactive environment management
-	fmt.Println("❌ Interactive environment management not yet implemented")
-	fmt.Println("💡 Please edit your config file manually: tkube config path")
-	return fmt.Errorf("interactive management not implemented")
-}
-
-// EditEnvironmentInteractive edits an existing environment interactively
-func (h *Handler) EditEnvironmentInteractive(name string) error {
-	// TODO: Implement interactive environment management
-	fmt.Println("❌ Interactive environment management not yet implemented")
-	fmt.Println("💡 Please edit your config file manually: tkube config path")
-	return fmt.Errorf("interactive management not implemented")
-}
-
-// RemoveEnvironmentInteractive removes an environment interactively
-func (h *Handler) RemoveEnvironmentInteractive(name string) error {
-	// TODO: Implement interactive environment management
-	fmt.Println("❌ Interactive environment management not yet implemented")
-	fmt.Println("💡 Please edit your config file manually: tkube config path")
-	return fmt.Errorf("interactive management not implemented")
-}
-
-// Logout logs out from Teleport environments
-func (h *Handler) Logout(env string) error {
+// Logout logs out from Teleport environments. Without env it logs out from every
+// environment, or from those matching selector if it is set.
+func (h *Handler) Logout(env, selector string) error {
 	config, err := h.configManager.Load()
 	if err != nil {
 		fmt.Printf("❌ Error loading configuration: %v\n", err)
@@ -594,22 +676,41 @@ func (h *Handler) Logout(env string) error {
 	}
 
 	if env == "" {
-		// Logout from all environments
-		fmt.Println("🔓 Logging out from all environments...")
-		
-		for envName, envConfig := range config.Environments {
+		envs, err := h.selectEnvironments(config, selector)
+		if err != nil {
+			return err
+		}
+
+		if selector == "" {
+			fmt.Println("🔓 Logging out from all environments...")
+		} else {
+			fmt.Printf("🔓 Logging out from environments matching '%s'...\n", selector)
+		}
+
+		for _, envName := range envs {
+			envConfig := config.Environments[envName]
 			fmt.Printf("🔓 Logging out from %s (%s)...\n", envName, envConfig.Proxy)
 			if err := h.teleportClient.LogoutWithEnv(envName, envConfig.Proxy); err != nil {
 				fmt.Printf("⚠️  Failed to logout from %s: %v\n", envName, err)
 			} else {
+				h.removeKubeconfigs(config, envName)
 				fmt.Printf("✅ Logged out from %s\n", envName)
 			}
 		}
-		
-		fmt.Println("✅ Logout from all environments completed")
+
+		if selector == "" {
+			fmt.Println("✅ Logout from all environments completed")
+		} else {
+			fmt.Printf("✅ Logout from %d environment(s) completed\n", len(envs))
+		}
 		return nil
 	}
 
+	if selector != "" {
+		fmt.Println("❌ Specify either an environment or --selector, not both")
+		return fmt.Errorf("environment and selector are mutually exclusive")
+	}
+
 	// Logout from specific environment
 	envConfig, exists := config.Environments[env]
 	if !exists {
@@ -623,15 +724,437 @@ func (h *Handler) Logout(env string) error {
 		fmt.Printf("❌ Failed to logout from %s: %v\n", env, err)
 		return err
 	}
+	h.removeKubeconfigs(config, env)
 
 	fmt.Printf("✅ Logged out from %s\n", env)
 	return nil
 }
 
-// ValidateConfiguration validates the current configuration
-func (h *Handler) ValidateConfiguration() error {
-	// TODO: Implement configuration validation
-	fmt.Println("❌ Configuration validation not yet implemented")
-	fmt.Println("💡 Please check your config file manually: tkube config show")
-	return fmt.Errorf("validation not implemented")
-}
\ No newline at end of file
+// Login authenticates to an environment, or to every environment matching selector.
+// Environments with a valid session are skipped.
+func (h *Handler) Login(env, selector string) error {
+	// Logging in needs the network anyway, so a missing or outdated team config is fetched first
+	if result, err := h.configManager.RefreshTeamConfig(); err != nil {
+		fmt.Printf("⚠️  Team config not refreshed: %v\n", err)
+		fmt.Println("💡 Run 'tkube config sync' to retry")
+	} else if result != nil && !result.NotModified {
+		fmt.Printf("🔄 Team config refreshed from %s (%d change(s))\n", result.Source, len(result.Changes))
+	}
+
+	config, err := h.configManager.Load()
+	if err != nil {
+		fmt.Printf("❌ Error loading configuration: %v\n", err)
+		return err
+	}
+
+	var envs []string
+	switch {
+	case env != "" && selector != "":
+		fmt.Println("❌ Specify either an environment or --selector, not both")
+		return fmt.Errorf("environment and selector are mutually exclusive")
+	case env != "":
+		if _, exists := config.Environments[env]; !exists {
+			fmt.Printf("❌ Unknown environment '%s'\n", env)
+			fmt.Printf("Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+			return fmt.Errorf("unknown environment")
+		}
+		envs = []string{env}
+	case selector != "":
+		if envs, err = h.selectEnvironments(config, selector); err != nil {
+			return err
+		}
+	default:
+		fmt.Println("❌ Specify an environment or --selector")
+		fmt.Println("💡 Usage: tkube login <environment> or tkube login --selector tier=prod")
+		return fmt.Errorf("no environment specified")
+	}
+
+	var failed []string
+	for _, envName := range envs {
+		envConfig := config.Environments[envName]
+		session := h.teleportClient.SessionInfoWithEnv(envName, envConfig.Proxy)
+		if session.IsValid() && !session.NeedsRenewal(config.RenewThreshold(envName)) {
+			fmt.Printf("✅ Already authenticated to %s (%s)\n", envName, envConfig.Proxy)
+			continue
+		}
+
+		fmt.Printf("🔐 Authenticating to %s (%s)...\n", envName, envConfig.Proxy)
+		if err := h.teleportClient.LoginWithEnv(envName, envConfig.Proxy, os.Stdout); err != nil {
+			fmt.Printf("❌ Authentication to %s failed: %v\n", envName, err)
+			failed = append(failed, envName)
+			continue
+		}
+		fmt.Printf("✅ Logged in to %s\n", envName)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("login failed for: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// selectEnvironments returns the sorted names of the environments matching selector
+func (h *Handler) selectEnvironments(cfg *config.Config, selector string) ([]string, error) {
+	parsed, err := config.ParseSelector(selector)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Println("💡 Selectors look like: tier=prod,region=eu or region!=us or !legacy")
+		return nil, err
+	}
+
+	envs := cfg.SelectEnvironments(parsed)
+	if len(envs) == 0 {
+		fmt.Printf("❌ No environments match selector '%s'\n", selector)
+		return nil, fmt.Errorf("no environments match selector '%s'", selector)
+	}
+	return envs, nil
+}
+
+// formatTags renders environment tags as sorted key=value pairs
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// MigrateConfig upgrades the user configuration to the current schema version.
+// With dryRun set it only shows the changes that would be applied.
+func (h *Handler) MigrateConfig(dryRun bool) error {
+	plan, err := h.configManager.PlanMigration()
+	if err != nil {
+		fmt.Printf("❌ Error planning migration: %v\n", err)
+		return err
+	}
+
+	if !plan.NeedsMigration() {
+		fmt.Printf("✅ Configuration is already at schema version %d\n", plan.To)
+		return nil
+	}
+
+	fmt.Printf("🔄 Migrating %s from schema version %d to %d:\n", plan.Path, plan.From, plan.To)
+	for _, step := range plan.Steps {
+		fmt.Printf("   • %s\n", step)
+	}
+	fmt.Println()
+
+	if diff := plan.Diff(); diff != "" {
+		fmt.Print(diff)
+		fmt.Println()
+	}
+
+	if dryRun {
+		fmt.Println("💡 Dry run - no changes written. Run 'tkube config migrate' to apply.")
+		return nil
+	}
+
+	_, backupPath, err := h.configManager.Migrate()
+	if err != nil {
+		fmt.Printf("❌ Migration failed: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Configuration migrated (backup: %s)\n", backupPath)
+	return nil
+}
+
+// ShowConfigHistory lists the backups of previous configuration versions
+func (h *Handler) ShowConfigHistory() error {
+	backups, err := h.configManager.ListBackups()
+	if err != nil {
+		fmt.Printf("❌ Error reading configuration history: %v\n", err)
+		return err
+	}
+
+	if len(backups) == 0 {
+		fmt.Println("📭 No configuration backups yet")
+		fmt.Printf("💡 A backup is stored in %s every time tkube changes your configuration\n", h.configManager.GetBackupDir())
+		return nil
+	}
+
+	fmt.Printf("🕘 Configuration history (%s, newest first):\n", h.configManager.GetBackupDir())
+	fmt.Println()
+	for _, backup := range backups {
+		fmt.Printf("   %-24s %s  %6d bytes\n", backup.ID, backup.Time.Format("2006-01-02 15:04:05"), backup.Size)
+	}
+	fmt.Println()
+	fmt.Println("💡 Restore a version: tkube config restore <id>")
+	return nil
+}
+
+// RestoreConfig replaces the user configuration with a backup after showing the diff.
+// Unless yes is set the user is asked for confirmation.
+func (h *Handler) RestoreConfig(id string, yes bool) error {
+	plan, err := h.configManager.PlanRestore(id)
+	if err != nil {
+		fmt.Printf("❌ Cannot restore backup: %v\n", err)
+		return err
+	}
+
+	diff := plan.Diff()
+	if diff == "" {
+		fmt.Printf("✅ Configuration already matches backup %s\n", id)
+		return nil
+	}
+
+	fmt.Printf("🕘 Restoring %s from backup %s:\n", plan.Path, id)
+	fmt.Println()
+	fmt.Print(diff)
+	fmt.Println()
+
+	if !yes && !h.confirm("❓ Restore this version?", false) {
+		fmt.Println("⏭️  Restore cancelled")
+		return nil
+	}
+
+	if err := h.configManager.RestoreBackup(id); err != nil {
+		fmt.Printf("❌ Restore failed: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Configuration restored from backup %s\n", id)
+	fmt.Println("💡 The replaced version was added to the history: tkube config history")
+	return nil
+}
+
+// GetConfigValue prints the effective value of a configuration key.
+// Strings are printed as they are and everything else as JSON, so the output can be used in scripts.
+func (h *Handler) GetConfigValue(key string, showSource bool) error {
+	value, layer, err := h.configManager.GetValue(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return err
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	if showSource {
+		fmt.Printf("%s  // %s\n", text, layer)
+	} else {
+		fmt.Println(text)
+	}
+	return nil
+}
+
+// SetConfigValue stores a value at a configuration key in the user layer
+func (h *Handler) SetConfigValue(key, value string) error {
+	if err := h.configManager.SetValue(key, value); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Set %s\n", key)
+	return nil
+}
+
+// UnsetConfigValue removes a configuration key from the user layer
+func (h *Handler) UnsetConfigValue(key string) error {
+	if err := h.configManager.UnsetValue(key); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Unset %s\n", key)
+	if value, layer, err := h.configManager.GetValue(key); err == nil {
+		data, _ := json.Marshal(value)
+		fmt.Printf("💡 Now %s from the %s layer\n", data, layer)
+	}
+	return nil
+}
+
+// ShowConfigSchema prints the JSON Schema of the configuration file
+func (h *Handler) ShowConfigSchema() error {
+	data, err := config.JSONSchemaBytes()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// SyncTeamConfig refreshes the shared team configuration and shows what upstream changed
+func (h *Handler) SyncTeamConfig() error {
+	source, err := h.configManager.TeamSource()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	if source == nil {
+		fmt.Println("❌ No team configuration is set")
+		fmt.Printf("💡 Add a team source to %s:\n", h.configManager.GetPath())
+		fmt.Println(`   "team": {"url": "https://config.company.com/tkube/team.json"}`)
+		fmt.Println(`   "team": {"path": "~/src/platform-config/tkube/team.json"}`)
+		return fmt.Errorf("no team configuration is set")
+	}
+
+	fmt.Printf("🔄 Syncing team config from %s...\n", source.Location())
+	result, err := h.configManager.SyncTeamConfig()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	if result.NotModified {
+		fmt.Println("✅ Team config is up to date")
+		return nil
+	}
+
+	fmt.Println()
+	for _, change := range result.Changes {
+		note := ""
+		if change.Overridden {
+			note = " (overridden by your config)"
+		}
+		switch {
+		case change.Before == "":
+			fmt.Printf("  \033[32m+ %s: %s\033[0m%s\n", change.Path, change.After, note)
+		case change.After == "":
+			fmt.Printf("  \033[31m- %s: %s\033[0m%s\n", change.Path, change.Before, note)
+		default:
+			fmt.Printf("  \033[33m~ %s: %s → %s\033[0m%s\n", change.Path, change.Before, change.After, note)
+		}
+	}
+	fmt.Println()
+
+	if result.Revision != "" {
+		fmt.Printf("✅ Team config synced at revision %s (%d change(s))\n", result.Revision, len(result.Changes))
+	} else {
+		fmt.Printf("✅ Team config synced (%d change(s))\n", len(result.Changes))
+	}
+
+	for _, change := range result.Changes {
+		if strings.HasSuffix(change.Path, ".tsh_version") && change.Before != "" && change.After != "" && !change.Overridden {
+			fmt.Println("💡 A tsh pin changed; run 'tkube config validate' to check the required versions are installed")
+			break
+		}
+	}
+	return nil
+}
+
+// ValidateOptions controls the checks and output format of configuration validation
+type ValidateOptions struct {
+	Output       string
+	CheckProxies bool
+}
+
+// validationReport is the JSON form of a validation run
+type validationReport struct {
+	Valid       bool                `json:"valid"`
+	Errors      int                 `json:"errors"`
+	Warnings    int                 `json:"warnings"`
+	Diagnostics []config.Diagnostic `json:"diagnostics"`
+}
+
+// ValidateConfiguration validates the current configuration and reports every finding.
+// It returns an error if any finding has error severity.
+func (h *Handler) ValidateConfiguration(opts ValidateOptions) error {
+	if opts.Output != "" && opts.Output != "text" && opts.Output != "json" {
+		fmt.Printf("❌ Unknown output format '%s' (use text or json)\n", opts.Output)
+		return fmt.Errorf("unknown output format: %s", opts.Output)
+	}
+
+	validation := config.ValidationOptions{
+		IsVersionInstalled: h.installer.IsVersionInstalled,
+	}
+	if opts.CheckProxies {
+		detector := teleport.NewVersionDetector()
+		validation.ProbeProxy = func(proxy string) error {
+			_, err := detector.ProbeProxy(proxy)
+			return err
+		}
+	}
+
+	diagnostics, err := h.configManager.Validate(validation)
+	if err != nil {
+		fmt.Printf("❌ Validation failed: %v\n", err)
+		return err
+	}
+
+	report := validationReport{Diagnostics: diagnostics}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []config.Diagnostic{}
+	}
+	for _, diagnostic := range diagnostics {
+		switch diagnostic.Severity {
+		case config.SeverityError:
+			report.Errors++
+		case config.SeverityWarning:
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+
+	if opts.Output == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal validation report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		h.printDiagnostics(report)
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("configuration has %d error(s)", report.Errors)
+	}
+	return nil
+}
+
+// printDiagnostics prints a validation report for humans
+func (h *Handler) printDiagnostics(report validationReport) {
+	fmt.Println("🔍 Validating tkube configuration...")
+	fmt.Println()
+
+	icons := map[config.Severity]string{
+		config.SeverityError:   "❌",
+		config.SeverityWarning: "⚠️ ",
+		config.SeverityInfo:    "ℹ️ ",
+	}
+
+	for _, diagnostic := range report.Diagnostics {
+		location := diagnostic.Path
+		if diagnostic.File != "" {
+			if location != "" {
+				location += " in "
+			}
+			location += diagnostic.File
+		}
+
+		fmt.Printf("%s %s: %s\n", icons[diagnostic.Severity], diagnostic.Severity, diagnostic.Message)
+		if location != "" {
+			fmt.Printf("   📍 %s\n", location)
+		}
+		if diagnostic.Fix != "" {
+			fmt.Printf("   💡 %s\n", diagnostic.Fix)
+		}
+		fmt.Println()
+	}
+
+	if report.Valid && report.Warnings == 0 {
+		fmt.Println("✅ Configuration is valid")
+		return
+	}
+	if report.Valid {
+		fmt.Printf("✅ Configuration is valid with %d warning(s)\n", report.Warnings)
+		return
+	}
+	fmt.Printf("❌ Configuration has %d error(s) and %d warning(s)\n", report.Errors, report.Warnings)
+}
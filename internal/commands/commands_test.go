@@ -81,7 +81,7 @@ func TestHandler_ShowStatus(t *testing.T) {
 	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
 	
 	// This should not panic or error
-	handler.ShowStatus()
+	handler.ShowStatus("")
 }
 
 func TestHandler_ShowConfig(t *testing.T) {
@@ -157,37 +157,6 @@ func TestHandler_AutoDetectVersions(t *testing.T) {
 	handler.AutoDetectVersions()
 }
 
-// Test interactive methods return not implemented errors
-func TestHandler_InteractiveMethods(t *testing.T) {
-	configManager, _ := config.NewManager()
-	teleportClient, _ := teleport.NewClient(configManager)
-	kubectlClient := kubectl.NewClient()
-	installer, _ := teleport.NewTSHInstaller()
-	
-	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
-	
-	// Test that interactive methods return not implemented errors
-	err := handler.AddEnvironmentInteractive()
-	if err == nil || !contains(err.Error(), "not implemented") {
-		t.Error("Expected AddEnvironmentInteractive to return 'not implemented' error")
-	}
-	
-	err = handler.EditEnvironmentInteractive("test")
-	if err == nil || !contains(err.Error(), "not implemented") {
-		t.Error("Expected EditEnvironmentInteractive to return 'not implemented' error")
-	}
-	
-	err = handler.RemoveEnvironmentInteractive("test")
-	if err == nil || !contains(err.Error(), "not implemented") {
-		t.Error("Expected RemoveEnvironmentInteractive to return 'not implemented' error")
-	}
-	
-	err = handler.ValidateConfiguration()
-	if err == nil || !contains(err.Error(), "not implemented") {
-		t.Error("Expected ValidateConfiguration to return 'not implemented' error")
-	}
-}
-
 func TestHandler_GetEnvironments(t *testing.T) {
 	configManager, _ := config.NewManager()
 	teleportClient, _ := teleport.NewClient(configManager)
@@ -198,7 +167,7 @@ func TestHandler_GetEnvironments(t *testing.T) {
 	
 	// Test getEnvironments method (it's not exported, but we can test through other methods)
 	// This is tested indirectly through ShowStatus
-	handler.ShowStatus()
+	handler.ShowStatus("")
 }
 
 func TestHandler_PromptForInstallation(t *testing.T) {
@@ -225,7 +194,7 @@ func TestHandler_FormatTimeRemaining(t *testing.T) {
 	
 	// We can't test the private method directly, but it's used in ShowStatus
 	// This is tested indirectly through ShowStatus
-	handler.ShowStatus()
+	handler.ShowStatus("")
 }
 
 // Helper function to check if string contains substring
@@ -300,7 +269,7 @@ func TestHandler_ShowStatus_WithEnvironments(t *testing.T) {
 	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
 	
 	// This should display environment status
-	handler.ShowStatus()
+	handler.ShowStatus("")
 }
 
 func TestHandler_ShowConfig_WithConfig(t *testing.T) {
@@ -408,7 +377,7 @@ func TestHandler_GetEnvironments_Method(t *testing.T) {
 	
 	// The getEnvironments method is private, but it's used in ShowStatus
 	// We can test it indirectly by calling ShowStatus
-	handler.ShowStatus()
+	handler.ShowStatus("")
 }
 
 func TestHandler_AutoDetectVersions_WithEnvironments(t *testing.T) {
@@ -447,7 +416,7 @@ func TestHandler_Logout(t *testing.T) {
 	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
 	
 	// Test logout from all environments (should not panic)
-	err := handler.Logout("")
+	err := handler.Logout("", "")
 	if err != nil {
 		t.Logf("Logout from all environments failed as expected: %v", err)
 	} else {
@@ -464,7 +433,7 @@ func TestHandler_Logout_SpecificEnvironment(t *testing.T) {
 	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
 	
 	// Test logout from non-existent environment
-	err := handler.Logout("nonexistent")
+	err := handler.Logout("nonexistent", "")
 	if err == nil {
 		t.Error("Expected error when logging out from non-existent environment")
 	} else {
@@ -496,7 +465,7 @@ func TestHandler_Logout_WithConfig(t *testing.T) {
 	handler := NewHandler(configManager, teleportClient, kubectlClient, installer)
 	
 	// Test logout from all environments
-	err := handler.Logout("")
+	err := handler.Logout("", "")
 	if err != nil {
 		t.Logf("Logout from all environments failed: %v", err)
 	} else {
@@ -504,7 +473,7 @@ func TestHandler_Logout_WithConfig(t *testing.T) {
 	}
 	
 	// Test logout from specific environment
-	err = handler.Logout("prod")
+	err = handler.Logout("prod", "")
 	if err != nil {
 		t.Logf("Logout from prod environment failed: %v", err)
 	} else {
@@ -512,7 +481,7 @@ func TestHandler_Logout_WithConfig(t *testing.T) {
 	}
 	
 	// Test logout from environment without TSH version
-	err = handler.Logout("test")
+	err = handler.Logout("test", "")
 	if err != nil {
 		t.Logf("Logout from test environment failed: %v", err)
 	} else {
@@ -531,7 +500,7 @@ func TestHandler_Logout_ConfigLoadError(t *testing.T) {
 	
 	// Force a config load error by trying to logout when no config exists
 	// This depends on how the config manager handles missing configs
-	err := handler.Logout("test")
+	err := handler.Logout("test", "")
 	if err != nil {
 		t.Logf("Logout failed as expected when config has issues: %v", err)
 	}
@@ -0,0 +1,544 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/teleport"
+)
+
+// AddEnvironmentOptions holds answers for the add wizard supplied as flags.
+// Empty values are asked for interactively unless NonInteractive is set.
+type AddEnvironmentOptions struct {
+	Name           string
+	Proxy          string
+	User           string
+	TSHVersion     string
+	SkipProbe      bool
+	InstallTSH     bool
+	NonInteractive bool
+}
+
+// AddEnvironmentInteractive adds a new environment, asking for every value not given in opts
+func (h *Handler) AddEnvironmentInteractive(opts AddEnvironmentOptions) error {
+	cfg, err := h.configManager.Load()
+	if err != nil {
+		fmt.Printf("❌ Error loading configuration: %v\n", err)
+		return err
+	}
+
+	if !opts.NonInteractive {
+		fmt.Println("➕ Add a new Teleport environment")
+		fmt.Println()
+	}
+
+	// Environment name
+	name, err := h.askValue(opts.Name, "Environment name", "", opts.NonInteractive, func(value string) (string, error) {
+		if err := config.ValidateEnvironmentName(value); err != nil {
+			return "", err
+		}
+		if _, exists := cfg.Environments[value]; exists {
+			return "", fmt.Errorf("environment '%s' already exists (use 'tkube config edit %s')", value, value)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Proxy address
+	proxy, err := h.askValue(opts.Proxy, "Teleport proxy (host:port)", "", opts.NonInteractive, config.NormalizeProxy)
+	if err != nil {
+		return err
+	}
+
+	// Probe the proxy to check reachability and pre-fill the tsh version
+	detectedVersion := ""
+	if !opts.SkipProbe {
+		fmt.Printf("🔍 Probing %s...\n", proxy)
+		version, err := teleport.NewVersionDetector().ProbeProxy(proxy)
+		switch {
+		case err == nil:
+			detectedVersion = version
+			fmt.Printf("✅ Proxy is reachable (Teleport %s)\n", version)
+		case opts.NonInteractive:
+			fmt.Printf("❌ %v\n", err)
+			fmt.Println("💡 Use --no-probe to add the environment anyway")
+			return err
+		default:
+			fmt.Printf("⚠️  %v\n", err)
+			if !h.confirm("❓ Add the environment anyway?", false) {
+				fmt.Println("⏭️  Environment not added")
+				return nil
+			}
+		}
+	}
+
+	// Teleport user
+	userLabel := "Teleport user (empty for the default user)"
+	if cfg.DefaultUser != "" {
+		userLabel = fmt.Sprintf("Teleport user (empty for default_user %s)", cfg.DefaultUser)
+	}
+	user := opts.User
+	if user == "" && !opts.NonInteractive {
+		user = h.prompt(userLabel, "")
+	}
+
+	// tsh version, empty means auto-detect on first connect
+	tshVersion := opts.TSHVersion
+	if tshVersion == "" {
+		tshVersion = detectedVersion
+		if !opts.NonInteractive {
+			tshVersion = h.prompt("tsh version (empty to auto-detect on connect)", detectedVersion)
+		}
+	}
+	if tshVersion != "" {
+		if tshVersion, err = config.NormalizeTSHVersion(tshVersion); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+	}
+
+	env := config.Environment{
+		Proxy:      proxy,
+		TSHVersion: tshVersion,
+		User:       user,
+	}
+	if err := h.configManager.AddEnvironment(name, env); err != nil {
+		fmt.Printf("❌ Failed to save environment: %v\n", err)
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("✅ Added environment '%s'\n", name)
+	fmt.Printf("   Proxy: %s\n", env.Proxy)
+	if env.User != "" {
+		fmt.Printf("   User: %s\n", env.User)
+	}
+	if env.TSHVersion != "" {
+		fmt.Printf("   TSH Version: %s\n", env.TSHVersion)
+	}
+
+	if err := h.offerTSHInstall(env.TSHVersion, opts.InstallTSH, opts.NonInteractive); err != nil {
+		return err
+	}
+
+	fmt.Printf("💡 Connect with: tkube %s <cluster>\n", name)
+	return nil
+}
+
+// askValue returns the flag value if given, otherwise prompts until validate accepts the answer.
+// In non-interactive mode a missing or invalid value is an error.
+func (h *Handler) askValue(value, label, defaultValue string, nonInteractive bool, validate func(string) (string, error)) (string, error) {
+	if value != "" || nonInteractive {
+		if value == "" {
+			value = defaultValue
+		}
+		normalized, err := validate(value)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+		return normalized, err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		normalized, err := validate(h.prompt(label, defaultValue))
+		if err == nil {
+			return normalized, nil
+		}
+		fmt.Printf("❌ %v\n", err)
+	}
+
+	return "", fmt.Errorf("no valid value for %s", label)
+}
+
+// offerTSHInstall installs a pinned tsh version that is missing, asking first unless install is set
+func (h *Handler) offerTSHInstall(version string, install, nonInteractive bool) error {
+	if version == "" || h.installer.IsVersionInstalled(version) {
+		return nil
+	}
+
+	if !install && !nonInteractive {
+		install = h.promptForInstallation(version)
+	}
+
+	if !install {
+		fmt.Printf("💡 Install it later with: tkube install-tsh %s\n", version)
+		return nil
+	}
+
+	if err := h.installer.InstallTSH(version); err != nil {
+		fmt.Printf("❌ Installation failed: %v\n", err)
+		fmt.Printf("💡 Try: tkube install-tsh %s\n", version)
+		return fmt.Errorf("installation failed")
+	}
+	fmt.Printf("✅ tsh v%s installed\n", version)
+	return nil
+}
+
+// EditEnvironmentOptions holds new values for the edit wizard supplied as flags.
+// Empty values keep the current setting, and "-" clears an optional field.
+type EditEnvironmentOptions struct {
+	Proxy          string
+	User           string
+	TSHVersion     string
+	InstallTSH     bool
+	NonInteractive bool
+}
+
+// clearValue is the answer that removes an optional setting in the edit wizard
+const clearValue = "-"
+
+// EditEnvironmentInteractive edits an existing environment, offering the current values as defaults
+func (h *Handler) EditEnvironmentInteractive(name string, opts EditEnvironmentOptions) error {
+	current, err := h.configManager.GetEnvironment(name)
+	if err != nil {
+		fmt.Printf("❌ Unknown environment '%s'\n", name)
+		fmt.Printf("Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+		return err
+	}
+
+	if !opts.NonInteractive {
+		fmt.Printf("✏️  Editing environment '%s'\n", name)
+		fmt.Printf("💡 Press Enter to keep the current value, enter '%s' to clear an optional one\n", clearValue)
+		fmt.Println()
+	}
+
+	// Proxy address
+	proxy, err := h.askValue(opts.Proxy, "Teleport proxy (host:port)", current.Proxy, opts.NonInteractive, config.NormalizeProxy)
+	if err != nil {
+		return err
+	}
+
+	// Teleport user
+	user := opts.User
+	if user == "" {
+		user = current.User
+		if !opts.NonInteractive {
+			user = h.prompt("Teleport user", current.User)
+		}
+	}
+	if user == clearValue {
+		user = ""
+	}
+
+	// tsh version
+	tshVersion, err := h.askValue(opts.TSHVersion, "tsh version", current.TSHVersion, opts.NonInteractive, func(value string) (string, error) {
+		if value == "" || value == clearValue {
+			return "", nil
+		}
+		return config.NormalizeTSHVersion(value)
+	})
+	if err != nil {
+		return err
+	}
+
+	updated := *current
+	updated.Proxy = proxy
+	updated.TSHVersion = tshVersion
+	updated.User = user
+	if reflect.DeepEqual(updated, *current) {
+		fmt.Printf("✅ No changes to environment '%s'\n", name)
+		return nil
+	}
+
+	if err := h.configManager.UpdateEnvironment(name, updated); err != nil {
+		fmt.Printf("❌ Failed to save environment: %v\n", err)
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("✅ Updated environment '%s'\n", name)
+	printFieldChange("Proxy", current.Proxy, updated.Proxy)
+	printFieldChange("User", current.User, updated.User)
+	printFieldChange("TSH Version", current.TSHVersion, updated.TSHVersion)
+
+	if updated.Proxy != current.Proxy {
+		fmt.Printf("💡 Log in to the new proxy with: tkube logout %s && tkube %s <cluster>\n", name, name)
+	}
+
+	return h.offerTSHInstall(updated.TSHVersion, opts.InstallTSH, opts.NonInteractive)
+}
+
+// printFieldChange prints a changed environment field
+func printFieldChange(field, before, after string) {
+	if before == after {
+		return
+	}
+	if before == "" {
+		before = "(not set)"
+	}
+	if after == "" {
+		after = "(not set)"
+	}
+	fmt.Printf("   %s: %s → %s\n", field, before, after)
+}
+
+// RemoveEnvironmentInteractive removes an environment after confirmation, logging out and
+// deleting its session directory. Unless yes is set the user is asked for confirmation.
+func (h *Handler) RemoveEnvironmentInteractive(name string, yes bool) error {
+	env, err := h.configManager.GetEnvironment(name)
+	if err != nil {
+		fmt.Printf("❌ Unknown environment '%s'\n", name)
+		fmt.Printf("Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+		return err
+	}
+
+	if err := h.configManager.CanRemoveEnvironment(name); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Println("💡 Run 'tkube config show' to see which layer defines it")
+		return err
+	}
+
+	fmt.Printf("🗑️  Environment '%s'\n", name)
+	fmt.Printf("   Proxy: %s\n", env.Proxy)
+	if env.User != "" {
+		fmt.Printf("   User: %s\n", env.User)
+	}
+	if env.TSHVersion != "" {
+		fmt.Printf("   TSH Version: %s\n", env.TSHVersion)
+	}
+	fmt.Println()
+
+	if !yes && !h.confirm(fmt.Sprintf("❓ Remove environment '%s' and its session?", name), false) {
+		fmt.Println("⏭️  Environment not removed")
+		return nil
+	}
+
+	// Log out while the environment's tsh version is still known
+	fmt.Printf("🔓 Logging out from %s (%s)...\n", name, env.Proxy)
+	if err := h.teleportClient.LogoutWithEnv(name, env.Proxy); err != nil {
+		fmt.Printf("⚠️  Failed to logout from %s: %v\n", name, err)
+	}
+	if cfg, err := h.configManager.Load(); err == nil {
+		h.removeKubeconfigs(cfg, name)
+	}
+
+	if err := h.configManager.RemoveEnvironment(name); err != nil {
+		fmt.Printf("❌ Failed to remove environment: %v\n", err)
+		return err
+	}
+
+	if err := h.teleportClient.RemoveSession(name); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	fmt.Printf("✅ Removed environment '%s'\n", name)
+
+	h.reportUnusedTSHVersions()
+	return nil
+}
+
+// reportUnusedTSHVersions lists installed tsh versions no environment refers to anymore
+func (h *Handler) reportUnusedTSHVersions() {
+	cfg, err := h.configManager.Load()
+	if err != nil {
+		return
+	}
+
+	installed, err := h.installer.GetInstalledVersions()
+	if err != nil {
+		return
+	}
+
+	used := make(map[string]bool)
+	for _, env := range cfg.Environments {
+		used[env.TSHVersion] = true
+	}
+
+	var unused []string
+	for _, version := range installed {
+		if !used[version] {
+			unused = append(unused, version)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+
+	sort.Strings(unused)
+	fmt.Println()
+	fmt.Println("📦 tsh versions no longer used by any environment:")
+	for _, version := range unused {
+		fmt.Printf("   • %s (%s)\n", version, filepath.Dir(h.installer.GetTSHPath(version)))
+	}
+	fmt.Println("💡 Delete a directory above to free the disk space")
+}
+
+// ImportTSHOptions controls importing environments from plain tsh profiles
+type ImportTSHOptions struct {
+	// Dir is the tsh profile directory; empty means TELEPORT_HOME or ~/.tsh
+	Dir          string
+	CopySessions bool
+	SkipProbe    bool
+	Yes          bool
+}
+
+// invalidNameChars matches the characters not allowed in environment names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ImportTSHProfiles creates environments from the profiles of plain tsh or Teleport Connect.
+// Profiles whose proxy is already configured are skipped. With CopySessions the still-valid
+// keys are copied into the new environment's session directory.
+func (h *Handler) ImportTSHProfiles(opts ImportTSHOptions) error {
+	dir := opts.Dir
+	if dir == "" {
+		defaultDir, err := teleport.DefaultTSHProfileDir()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		dir = defaultDir
+	}
+
+	profiles, err := teleport.LoadTSHProfiles(dir)
+	if err != nil {
+		fmt.Printf("❌ Error reading tsh profiles: %v\n", err)
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Printf("❌ No tsh profiles found in %s\n", dir)
+		fmt.Println("💡 Log in once with plain tsh or use --tsh-dir to point at another directory")
+		return fmt.Errorf("no tsh profiles found")
+	}
+
+	cfg, err := h.configManager.Load()
+	if err != nil {
+		fmt.Printf("❌ Error loading configuration: %v\n", err)
+		return err
+	}
+
+	if cfg.Environments == nil {
+		cfg.Environments = make(map[string]config.Environment)
+	}
+
+	configuredProxies := make(map[string]string)
+	for name, env := range cfg.Environments {
+		if proxy, err := config.NormalizeProxy(env.Proxy); err == nil {
+			configuredProxies[proxy] = name
+		}
+	}
+
+	fmt.Printf("📥 Found %d tsh profile(s) in %s\n", len(profiles), dir)
+	fmt.Println()
+
+	imported := 0
+	for _, profile := range profiles {
+		proxy, err := config.NormalizeProxy(profile.Proxy)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping %s: %v\n", profile.Name, err)
+			continue
+		}
+		if existing, ok := configuredProxies[proxy]; ok {
+			fmt.Printf("⏭️  Skipping %s: proxy already configured as '%s'\n", profile.Name, existing)
+			continue
+		}
+
+		fmt.Printf("📄 %s\n", profile.Name)
+		fmt.Printf("   Proxy: %s\n", proxy)
+		if profile.User != "" {
+			fmt.Printf("   User: %s\n", profile.User)
+		}
+		if profile.Cluster != "" {
+			fmt.Printf("   Cluster: %s\n", profile.Cluster)
+		}
+		if profile.KubeCluster != "" {
+			fmt.Printf("   Kubernetes cluster: %s\n", profile.KubeCluster)
+		}
+		if profile.IsValid() {
+			fmt.Printf("   Session: valid for %s\n", time.Until(profile.ValidUntil).Round(time.Minute))
+		}
+
+		name := suggestEnvironmentName(profile, cfg)
+		if !opts.Yes {
+			if !h.confirm(fmt.Sprintf("❓ Import profile %s?", profile.Name), true) {
+				fmt.Println("⏭️  Profile not imported")
+				fmt.Println()
+				continue
+			}
+			name, err = h.askValue("", "Environment name", name, false, func(value string) (string, error) {
+				if err := config.ValidateEnvironmentName(value); err != nil {
+					return "", err
+				}
+				if _, exists := cfg.Environments[value]; exists {
+					return "", fmt.Errorf("environment '%s' already exists", value)
+				}
+				return value, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		env := config.Environment{
+			Proxy: proxy,
+			User:  profile.User,
+		}
+		if env.User == cfg.DefaultUser {
+			env.User = ""
+		}
+		if !opts.SkipProbe {
+			if version, err := teleport.NewVersionDetector().ProbeProxy(proxy); err == nil {
+				env.TSHVersion = version
+			} else {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}
+
+		if err := h.configManager.AddEnvironment(name, env); err != nil {
+			fmt.Printf("❌ Failed to save environment: %v\n", err)
+			return err
+		}
+		cfg.Environments[name] = env
+		configuredProxies[proxy] = name
+		imported++
+		fmt.Printf("✅ Added environment '%s'\n", name)
+
+		if opts.CopySessions {
+			if !profile.IsValid() {
+				fmt.Println("   🔐 Session expired, log in on first connect")
+			} else if err := h.teleportClient.ImportSession(name, dir, profile); err != nil {
+				fmt.Printf("⚠️  Failed to copy session: %v\n", err)
+			} else {
+				fmt.Println("   🔐 Copied the still-valid session")
+			}
+		}
+
+		if profile.KubeCluster != "" {
+			fmt.Printf("💡 Connect with: tkube %s %s\n", name, profile.KubeCluster)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("✅ Imported %d environment(s)\n", imported)
+	if imported > 0 && !opts.CopySessions {
+		fmt.Println("💡 Use --copy-sessions to reuse still-valid tsh logins")
+	}
+	return nil
+}
+
+// suggestEnvironmentName derives an unused environment name from a tsh profile,
+// preferring the Teleport cluster name over the proxy host
+func suggestEnvironmentName(profile teleport.TSHProfile, cfg *config.Config) string {
+	base := profile.Cluster
+	if base == "" {
+		base = profile.Name
+	}
+	base = strings.Trim(invalidNameChars.ReplaceAllString(base, "-"), "-_")
+	if base == "" {
+		base = "imported"
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		if _, exists := cfg.Environments[name]; !exists {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/kubectl"
+	"tkube/internal/teleport"
+	"tkube/internal/teleport/teleporttest"
+)
+
+// newTestHandler creates a handler using a temporary home directory and the given stdin answers
+func newTestHandler(t *testing.T, input string) (*Handler, *config.Manager) {
+	t.Helper()
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("TKUBE_HOME", filepath.Join(homeDir, ".tkube"))
+	t.Setenv("TKUBE_CONFIG", "")
+	t.Setenv("TKUBE_PROFILE", "")
+	os.MkdirAll(filepath.Join(homeDir, ".tkube"), 0755)
+	os.WriteFile(filepath.Join(homeDir, ".tkube", "config.json"), []byte(`{
+  "environments": {
+    "prod": {"proxy": "teleport.prod.company.com:443"}
+  },
+  "auto_login": true
+}
+`), 0644)
+
+	configManager, err := config.NewManager()
+	if err != nil {
+		t.Fatalf("Failed to create config manager: %v", err)
+	}
+	teleportClient, _ := teleport.NewClient(configManager)
+	installer, _ := teleport.NewTSHInstaller()
+
+	handler := NewHandler(configManager, teleportClient, kubectl.NewClient(), installer)
+	handler.input = bufio.NewReader(strings.NewReader(input))
+	return handler, configManager
+}
+
+func TestHandler_AddEnvironmentInteractive_Prompts(t *testing.T) {
+	// Invalid name first, then valid answers; the install offer is declined
+	handler, configManager := newTestHandler(t, "bad name\nstaging\nteleport.staging.company.com\nalice\nv17.7.1\nn\n")
+
+	err := handler.AddEnvironmentInteractive(AddEnvironmentOptions{SkipProbe: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	env, err := configManager.GetEnvironment("staging")
+	if err != nil {
+		t.Fatalf("Expected environment to be added, got %v", err)
+	}
+	if env.Proxy != "teleport.staging.company.com:443" {
+		t.Errorf("Expected proxy with default port, got '%s'", env.Proxy)
+	}
+	if env.User != "alice" {
+		t.Errorf("Expected user 'alice', got '%s'", env.User)
+	}
+	if env.TSHVersion != "17.7.1" {
+		t.Errorf("Expected normalized TSH version '17.7.1', got '%s'", env.TSHVersion)
+	}
+}
+
+func TestHandler_AddEnvironmentInteractive_NonInteractive(t *testing.T) {
+	handler, configManager := newTestHandler(t, "")
+
+	opts := AddEnvironmentOptions{
+		Name:           "dev",
+		Proxy:          "teleport.dev.company.com:3080",
+		SkipProbe:      true,
+		NonInteractive: true,
+	}
+	if err := handler.AddEnvironmentInteractive(opts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	env, err := configManager.GetEnvironment("dev")
+	if err != nil {
+		t.Fatalf("Expected environment to be added, got %v", err)
+	}
+	if env.Proxy != "teleport.dev.company.com:3080" || env.TSHVersion != "" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+}
+
+func TestHandler_AddEnvironmentInteractive_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts AddEnvironmentOptions
+	}{
+		{"existing environment", AddEnvironmentOptions{Name: "prod", Proxy: "other.company.com:443"}},
+		{"missing proxy", AddEnvironmentOptions{Name: "dev"}},
+		{"invalid proxy", AddEnvironmentOptions{Name: "dev", Proxy: "host:port"}},
+		{"invalid version", AddEnvironmentOptions{Name: "dev", Proxy: "dev.company.com", TSHVersion: "latest"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, configManager := newTestHandler(t, "")
+
+			tt.opts.SkipProbe = true
+			tt.opts.NonInteractive = true
+			if err := handler.AddEnvironmentInteractive(tt.opts); err == nil {
+				t.Error("Expected error")
+			}
+
+			envs, _ := configManager.GetEnvironments()
+			if len(envs) != 1 {
+				t.Errorf("Expected configuration to be unchanged, got %v", envs)
+			}
+		})
+	}
+}
+
+func TestHandler_EditEnvironmentInteractive(t *testing.T) {
+	// Keep the proxy, set a user, then pin a version; the install offer is declined
+	handler, configManager := newTestHandler(t, "\nalice\n17.7.1\nn\n")
+
+	if err := handler.EditEnvironmentInteractive("prod", EditEnvironmentOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	env, _ := configManager.GetEnvironment("prod")
+	if env.Proxy != "teleport.prod.company.com:443" {
+		t.Errorf("Expected proxy to be kept, got '%s'", env.Proxy)
+	}
+	if env.User != "alice" || env.TSHVersion != "17.7.1" {
+		t.Errorf("Expected user and version to be updated, got %+v", env)
+	}
+
+	// Clearing values with flags
+	opts := EditEnvironmentOptions{User: "-", TSHVersion: "-", NonInteractive: true}
+	if err := handler.EditEnvironmentInteractive("prod", opts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	env, _ = configManager.GetEnvironment("prod")
+	if env.User != "" || env.TSHVersion != "" {
+		t.Errorf("Expected user and version to be cleared, got %+v", env)
+	}
+
+	if err := handler.EditEnvironmentInteractive("missing", EditEnvironmentOptions{NonInteractive: true}); err == nil {
+		t.Error("Expected error for unknown environment")
+	}
+}
+
+func TestHandler_RemoveEnvironmentInteractive(t *testing.T) {
+	handler, configManager := newTestHandler(t, "n\n")
+
+	sessionDir := filepath.Join(os.Getenv("HOME"), ".tkube", "sessions", "prod")
+	os.MkdirAll(sessionDir, 0700)
+
+	// Declined confirmation keeps everything
+	if err := handler.RemoveEnvironmentInteractive("prod", false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := configManager.GetEnvironment("prod"); err != nil {
+		t.Error("Expected environment to be kept after declining")
+	}
+
+	if err := handler.RemoveEnvironmentInteractive("prod", true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := configManager.GetEnvironment("prod"); err == nil {
+		t.Error("Expected environment to be removed")
+	}
+	if _, err := os.Stat(sessionDir); !os.IsNotExist(err) {
+		t.Error("Expected session directory to be removed")
+	}
+
+	if err := handler.RemoveEnvironmentInteractive("prod", true); err == nil {
+		t.Error("Expected error for unknown environment")
+	}
+}
+
+func TestHandler_ValidateConfiguration(t *testing.T) {
+	handler, configManager := newTestHandler(t, "")
+
+	if err := handler.ValidateConfiguration(ValidateOptions{Output: "json"}); err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+
+	if err := handler.ValidateConfiguration(ValidateOptions{Output: "yaml"}); err == nil {
+		t.Error("Expected error for unknown output format")
+	}
+
+	configManager.AddEnvironment("broken", config.Environment{Proxy: "host:port"})
+	if err := handler.ValidateConfiguration(ValidateOptions{}); err == nil {
+		t.Error("Expected error for invalid configuration")
+	}
+}
+
+func TestHandler_SelectorCommands(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+
+	// The test configuration has no tags, so nothing matches
+	if err := handler.ShowStatus("tier=prod"); err == nil {
+		t.Error("Expected error when no environment matches the selector")
+	}
+	if err := handler.Logout("", "tier=prod"); err == nil {
+		t.Error("Expected error when no environment matches the selector")
+	}
+	if err := handler.Login("", "tier=prod"); err == nil {
+		t.Error("Expected error when no environment matches the selector")
+	}
+
+	if err := handler.ShowStatus("=prod"); err == nil {
+		t.Error("Expected error for an invalid selector")
+	}
+	if err := handler.Login("prod", "tier=prod"); err == nil {
+		t.Error("Expected error when both an environment and a selector are given")
+	}
+	if err := handler.Login("", ""); err == nil {
+		t.Error("Expected error when neither an environment nor a selector is given")
+	}
+	if err := handler.Login("nonexistent", ""); err == nil {
+		t.Error("Expected error for an unknown environment")
+	}
+}
+
+func TestHandler_ImportTSHProfiles(t *testing.T) {
+	handler, configManager := newTestHandler(t, "")
+
+	tshDir := t.TempDir()
+	profiles := map[string]string{
+		"teleport.prod.company.com":    "web_proxy_addr: teleport.prod.company.com:443\nuser: alice\ncluster: prod\n",
+		"teleport.staging.company.com": "web_proxy_addr: teleport.staging.company.com:443\nuser: alice\ncluster: staging.company\nkube_cluster: eks-staging\n",
+	}
+	for name, profile := range profiles {
+		if err := teleporttest.WriteTSHProfile(tshDir, name, profile, "alice", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to write profile: %v", err)
+		}
+	}
+
+	err := handler.ImportTSHProfiles(ImportTSHOptions{Dir: tshDir, CopySessions: true, SkipProbe: true, Yes: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// prod's proxy is already configured, so only staging is imported
+	cfg, _ := configManager.Load()
+	if len(cfg.Environments) != 2 {
+		t.Fatalf("Expected 2 environments, got %v", cfg.Environments)
+	}
+	env, err := configManager.GetEnvironment("staging-company")
+	if err != nil {
+		t.Fatalf("Expected environment named after the cluster, got %v", err)
+	}
+	if env.Proxy != "teleport.staging.company.com:443" || env.User != "alice" {
+		t.Errorf("Unexpected environment %+v", env)
+	}
+
+	sessionFile := filepath.Join(os.Getenv("TKUBE_HOME"), "sessions", "staging-company", "teleport.staging.company.com.yaml")
+	if _, err := os.Stat(sessionFile); err != nil {
+		t.Errorf("Expected the session to be copied: %v", err)
+	}
+
+	// An empty directory has nothing to import
+	if err := handler.ImportTSHProfiles(ImportTSHOptions{Dir: t.TempDir(), Yes: true}); err == nil {
+		t.Error("Expected error when no profiles are found")
+	}
+}
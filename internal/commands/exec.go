@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ExitError carries the exit code of a command run by 'tkube exec', which tkube exits with
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// forwardedSignals are passed on to a command run by 'tkube exec'. Ctrl+C is not among them:
+// the command shares tkube's process group, so the terminal already interrupts it directly
+// and forwarding would deliver a second interrupt.
+var forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
+
+// lookPath finds a command in the PATH of environ rather than tkube's own PATH, so the
+// environment's pinned tsh is found before any other
+func lookPath(name string, environ []string) (string, error) {
+	if strings.ContainsRune(name, os.PathSeparator) || strings.ContainsRune(name, '/') {
+		return exec.LookPath(name)
+	}
+
+	var path string
+	for _, entry := range environ {
+		if strings.HasPrefix(entry, "PATH=") {
+			path = strings.TrimPrefix(entry, "PATH=")
+			break
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		if found, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("%s: command not found", name)
+}
+
+// exitCode returns the exit code of a finished command, using the shell convention of 128+n
+// for commands killed by signal n
+func exitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return err.ExitCode()
+}
+
+// Exec connects to a cluster without prompting and runs a single command with the cluster's
+// isolated kubeconfig, session directory and tsh. The command's exit code is returned as an
+// ExitError, and termination signals tkube receives are forwarded to it.
+func (h *Handler) Exec(env, cluster string, command []string) error {
+	if len(command) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ Specify the command to run after --")
+		fmt.Fprintln(os.Stderr, "💡 Usage: tkube exec <environment> <cluster> -- <command> [args...]")
+		return fmt.Errorf("no command specified")
+	}
+
+	// The command's output belongs on stdout, so tkube's own messages go to stderr
+	_, cluster, err := h.connect(env, cluster, false, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	environ, err := h.clusterEnviron(env, cluster)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return err
+	}
+
+	path, err := lookPath(command[0], environ)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return &ExitError{Code: 127}
+	}
+
+	cmd := exec.Command(path, command[1:]...)
+	cmd.Args[0] = command[0]
+	cmd.Env = environ
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to run %s: %v\n", command[0], err)
+		return &ExitError{Code: 126}
+	}
+
+	// tkube waits for the command on Ctrl+C, so the command's exit code is still returned
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append([]os.Signal{os.Interrupt}, forwardedSignals...)...)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		for sig := range signals {
+			if sig != os.Interrupt {
+				cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitCode(exitErr)}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLookPath_UsesCommandPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are POSIX shell scripts")
+	}
+
+	pinned := t.TempDir()
+	os.WriteFile(filepath.Join(pinned, "tsh"), []byte("#!/bin/sh\nexit 0\n"), 0755)
+	os.WriteFile(filepath.Join(pinned, "notes"), []byte("not executable"), 0644)
+	environ := []string{"HOME=/home/alice", "PATH=" + pinned + string(os.PathListSeparator) + "/usr/bin"}
+
+	if path, err := lookPath("tsh", environ); err != nil || path != filepath.Join(pinned, "tsh") {
+		t.Errorf("Expected the tsh on the command's PATH, got %s (%v)", path, err)
+	}
+	if _, err := lookPath("notes", environ); err == nil {
+		t.Error("Expected non-executable files to be skipped")
+	}
+	if _, err := lookPath("tkube-no-such-command", environ); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+	if path, err := lookPath(filepath.Join(pinned, "tsh"), nil); err != nil || path != filepath.Join(pinned, "tsh") {
+		t.Errorf("Expected paths to be used as given, got %s (%v)", path, err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	tests := map[string]int{
+		"exit 3":        3,
+		"kill -TERM $$": 128 + 15,
+	}
+	for script, expected := range tests {
+		err := exec.Command("/bin/sh", "-c", script).Run()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("Expected %q to fail, got %v", script, err)
+		}
+		if code := exitCode(exitErr); code != expected {
+			t.Errorf("exitCode(%q) = %d, expected %d", script, code, expected)
+		}
+	}
+}
+
+func TestForwardedSignals_ExcludeInterrupt(t *testing.T) {
+	// The terminal already delivers Ctrl+C to the command; forwarding it would interrupt twice
+	for _, sig := range forwardedSignals {
+		if sig == os.Interrupt {
+			t.Error("Expected os.Interrupt not to be forwarded")
+		}
+	}
+}
+
+func TestHandler_Exec_RequiresCommand(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+
+	err := handler.Exec("prod", "eks-prod", nil)
+	var exitErr *ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		t.Errorf("Expected a usage error without a command, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"tkube/internal/config"
+	"tkube/internal/kubectl"
+)
+
+// KubeconfigPath prints the isolated kubeconfig of a cluster, for use as KUBECONFIG
+func (h *Handler) KubeconfigPath(env, cluster string) error {
+	cfg, err := h.configManager.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error loading configuration: %v\n", err)
+		return err
+	}
+
+	envConfig, exists := cfg.Environments[env]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "❌ Unknown environment '%s'\n", env)
+		fmt.Fprintf(os.Stderr, "Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+		return fmt.Errorf("unknown environment")
+	}
+
+	cluster = envConfig.ResolveCluster(cluster)
+	path := h.teleportClient.KubeconfigPath(env, cluster)
+
+	// Only the path goes to stdout, so it can be used as $(tkube kubeconfig path ...)
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Not connected to %s/%s yet - run: tkube %s %s\n", env, cluster, env, cluster)
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// useKubeconfig merges a freshly written cluster kubeconfig into the global kubeconfig if
+// merge_kubeconfig is set, and otherwise shows how to use it
+func (h *Handler) useKubeconfig(cfg *config.Config, env, cluster string) {
+	path := h.teleportClient.KubeconfigPath(env, cluster)
+	if !cfg.MergeKubeconfig {
+		fmt.Printf("💡 Use it with: export KUBECONFIG=%s\n", path)
+		fmt.Println("💡 To have plain kubectl use it as before, run: tkube config set merge_kubeconfig true")
+		return
+	}
+
+	globalPath, err := kubectl.DefaultKubeconfigPath()
+	if err == nil {
+		err = h.teleportClient.RecordMerge(env, globalPath)
+	}
+	if err == nil {
+		err = kubectl.MergeKubeconfig(path, globalPath)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Failed to merge into the global kubeconfig: %v\n", err)
+		fmt.Printf("💡 Use it with: export KUBECONFIG=%s\n", path)
+		return
+	}
+	fmt.Printf("📄 Merged into %s\n", globalPath)
+}
+
+// removeKubeconfigs deletes the kubeconfigs of an environment after logging out, and the
+// contexts merged from them into a global kubeconfig, even if merge_kubeconfig was turned off since
+func (h *Handler) removeKubeconfigs(cfg *config.Config, env string) {
+	var globalPath string
+	if cfg.MergeKubeconfig {
+		globalPath, _ = kubectl.DefaultKubeconfigPath()
+	}
+	if err := h.teleportClient.RemoveKubeconfigs(env, globalPath); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const clusterKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: teleport.prod.company.com-eks-prod
+  cluster: {server: https://teleport.prod.company.com:443}
+users:
+- name: teleport.prod.company.com-eks-prod
+  user: {token: secret}
+contexts:
+- name: teleport.prod.company.com-eks-prod
+  context: {cluster: teleport.prod.company.com-eks-prod, user: teleport.prod.company.com-eks-prod}
+current-context: teleport.prod.company.com-eks-prod
+`
+
+const globalKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: minikube
+  cluster: {server: https://127.0.0.1:8443}
+users:
+- name: minikube
+  user: {token: local}
+contexts:
+- name: minikube
+  context: {cluster: minikube, user: minikube}
+current-context: minikube
+`
+
+func TestHandler_RemoveKubeconfigs_AfterMergeTurnedOff(t *testing.T) {
+	handler, configManager := newTestHandler(t, "")
+
+	globalPath := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(globalPath, []byte(globalKubeconfig), 0600)
+	t.Setenv("KUBECONFIG", globalPath)
+
+	clusterPath := handler.teleportClient.KubeconfigPath("prod", "eks-prod")
+	os.MkdirAll(filepath.Dir(clusterPath), 0700)
+	os.WriteFile(clusterPath, []byte(clusterKubeconfig), 0600)
+
+	// Connect with merging on
+	if err := configManager.SetValue("merge_kubeconfig", "true"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, _ := configManager.Load()
+	handler.useKubeconfig(cfg, "prod", "eks-prod")
+	if data, _ := os.ReadFile(globalPath); !strings.Contains(string(data), "teleport.prod.company.com-eks-prod") {
+		t.Fatalf("Expected the cluster to be merged, got:\n%s", data)
+	}
+
+	// Turn merging off, then log out
+	if err := configManager.SetValue("merge_kubeconfig", "false"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, _ = configManager.Load()
+	handler.removeKubeconfigs(cfg, "prod")
+
+	data, _ := os.ReadFile(globalPath)
+	if strings.Contains(string(data), "teleport.prod.company.com-eks-prod") {
+		t.Errorf("Expected the merged contexts to be removed, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "name: minikube") {
+		t.Errorf("Expected other contexts to be kept, got:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Dir(clusterPath)); !os.IsNotExist(err) {
+		t.Error("Expected the environment's kubeconfigs to be removed")
+	}
+}
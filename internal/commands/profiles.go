@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"tkube/internal/paths"
+)
+
+// ListProfiles shows the configuration profiles and which one is active
+func (h *Handler) ListProfiles() error {
+	profiles, err := h.configManager.ListProfiles()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Println("👤 Profiles:")
+	fmt.Println()
+	for _, profile := range profiles {
+		marker := "  "
+		if profile.Active {
+			marker = "▶ "
+		}
+
+		fmt.Printf("  %s%-16s %s (%d environments)\n", marker, profile.Name, profile.ConfigPath, profile.Environments)
+	}
+
+	fmt.Println()
+	if profile := os.Getenv(paths.EnvProfile); profile != "" {
+		fmt.Printf("💡 %s=%s overrides the profile selected with 'tkube profile use'\n", paths.EnvProfile, profile)
+	}
+	fmt.Println("💡 Switch profiles: tkube profile use <name>, or --profile <name> for a single command")
+	return nil
+}
+
+// ShowProfile prints the name of the active profile
+func (h *Handler) ShowProfile() {
+	fmt.Println(h.configManager.ActiveProfile())
+}
+
+// CreateProfile creates a named profile, optionally with a default Teleport user
+func (h *Handler) CreateProfile(name, defaultUser string, use bool) error {
+	if err := h.configManager.CreateProfile(name, defaultUser); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	fmt.Printf("✅ Created profile %s\n", name)
+
+	if use {
+		return h.UseProfile(name)
+	}
+
+	fmt.Printf("💡 Switch to it: tkube profile use %s\n", name)
+	return nil
+}
+
+// UseProfile selects the profile used by later commands
+func (h *Handler) UseProfile(name string) error {
+	if err := h.configManager.UseProfile(name); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Println("💡 List profiles with 'tkube profile list' or create one with 'tkube profile create <name>'")
+		return err
+	}
+
+	fmt.Printf("✅ Now using profile %s\n", name)
+	if profile := os.Getenv(paths.EnvProfile); profile != "" && profile != name {
+		fmt.Printf("⚠️  %s=%s is set and still takes precedence in this shell\n", paths.EnvProfile, profile)
+	}
+	return nil
+}
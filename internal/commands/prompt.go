@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prompt asks for a value and returns defaultValue when the answer is empty or input ends
+func (h *Handler) prompt(label, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", label, defaultValue)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	line, err := h.input.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil && line == "" {
+		fmt.Println()
+	}
+
+	if line == "" {
+		return defaultValue
+	}
+	return line
+}
+
+// confirm asks a yes/no question; an empty answer selects defaultYes
+func (h *Handler) confirm(question string, defaultYes bool) bool {
+	hint := "y/N"
+	if defaultYes {
+		hint = "Y/n"
+	}
+
+	response := strings.ToLower(h.prompt(fmt.Sprintf("%s (%s)", question, hint), ""))
+	if response == "" {
+		return defaultYes
+	}
+	return response == "y" || response == "yes"
+}
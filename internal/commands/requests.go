@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+	"tkube/internal/config"
+	"tkube/internal/teleport"
+)
+
+// requestPollInterval is how often a pending access request is checked
+const requestPollInterval = 5 * time.Second
+
+// requestStateIcons marks access request states in listings
+var requestStateIcons = map[string]string{
+	teleport.RequestPending:  "⏳",
+	teleport.RequestApproved: "✅",
+	teleport.RequestDenied:   "❌",
+	teleport.RequestPromoted: "✅",
+}
+
+// requestEnvironment returns the configuration of an environment and makes sure it has a session,
+// since access requests are filed with the user's Teleport identity
+func (h *Handler) requestEnvironment(env string) (*config.Config, config.Environment, error) {
+	cfg, err := h.configManager.Load()
+	if err != nil {
+		fmt.Printf("❌ Error loading configuration: %v\n", err)
+		return nil, config.Environment{}, err
+	}
+
+	envConfig, exists := cfg.Environments[env]
+	if !exists {
+		fmt.Printf("❌ Unknown environment '%s'\n", env)
+		fmt.Printf("Available environments: %s\n", strings.Join(h.getEnvironments(), ", "))
+		return nil, config.Environment{}, fmt.Errorf("unknown environment")
+	}
+
+	if !h.teleportClient.IsAuthenticatedWithEnv(env, envConfig.Proxy) {
+		if !cfg.AutoLogin {
+			fmt.Printf("❌ Not authenticated to %s\n", envConfig.Proxy)
+			fmt.Printf("💡 Run: tkube login %s\n", env)
+			return nil, config.Environment{}, fmt.Errorf("authentication required")
+		}
+
+		fmt.Printf("🔐 Authenticating to %s...\n", envConfig.Proxy)
+		if err := h.teleportClient.LoginWithEnv(env, envConfig.Proxy, os.Stdout); err != nil {
+			fmt.Printf("❌ Authentication failed\n")
+			return nil, config.Environment{}, err
+		}
+	}
+
+	return cfg, envConfig, nil
+}
+
+// CreateAccessRequest files an access request for roles in an environment, optionally waiting for it
+func (h *Handler) CreateAccessRequest(env string, roles []string, reason string, wait bool, timeout time.Duration) error {
+	if len(roles) == 0 {
+		fmt.Println("❌ Specify the roles to request with --roles")
+		return fmt.Errorf("no roles specified")
+	}
+
+	_, envConfig, err := h.requestEnvironment(env)
+	if err != nil {
+		return err
+	}
+
+	request, err := h.teleportClient.CreateAccessRequest(env, envConfig.Proxy, roles, reason)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("📨 Access request %s created for roles: %s\n", request.ID, strings.Join(request.Roles, ", "))
+	if !wait {
+		fmt.Printf("💡 Reviewers approve it with: tsh request review --approve %s\n", request.ID)
+		fmt.Printf("💡 Use the roles once approved: tkube request wait %s %s\n", env, request.ID)
+		return nil
+	}
+
+	return h.waitAndAssumeRequest(env, envConfig.Proxy, request.ID, timeout)
+}
+
+// ListAccessRequests shows the access requests of an environment
+func (h *Handler) ListAccessRequests(env string) error {
+	_, envConfig, err := h.requestEnvironment(env)
+	if err != nil {
+		return err
+	}
+
+	requests, err := h.teleportClient.ListAccessRequests(env, envConfig.Proxy)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if len(requests) == 0 {
+		fmt.Printf("ℹ️  No access requests in %s\n", env)
+		fmt.Printf("💡 Request roles with: tkube request create %s --roles <role> --reason <reason>\n", env)
+		return nil
+	}
+
+	// Newest first
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Created.After(requests[j].Created)
+	})
+
+	fmt.Printf("📨 Access requests in %s:\n", env)
+	fmt.Println()
+	for _, request := range requests {
+		icon := requestStateIcons[request.State]
+		if icon == "" {
+			icon = "•"
+		}
+
+		fmt.Printf("  %s %-36s %-9s %s\n", icon, request.ID, request.State, strings.Join(request.Roles, ", "))
+		details := []string{"by " + request.User}
+		if !request.Created.IsZero() {
+			details = append(details, "created "+request.Created.Local().Format("2006-01-02 15:04"))
+		}
+		if request.Reason != "" {
+			details = append(details, fmt.Sprintf("reason: %q", request.Reason))
+		}
+		if request.ResolveReason != "" {
+			details = append(details, fmt.Sprintf("review: %q", request.ResolveReason))
+		}
+		fmt.Printf("      %s\n", strings.Join(details, ", "))
+	}
+	return nil
+}
+
+// WaitAccessRequest waits for an access request to be reviewed and assumes its roles once approved.
+// Without an ID, the user's newest pending request is used.
+func (h *Handler) WaitAccessRequest(env, id string, timeout time.Duration) error {
+	_, envConfig, err := h.requestEnvironment(env)
+	if err != nil {
+		return err
+	}
+
+	if id == "" {
+		requests, err := h.teleportClient.ListAccessRequests(env, envConfig.Proxy)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+
+		session := h.teleportClient.GetSessionInfo(env, envConfig.Proxy)
+		request := newestPendingRequest(requests, session.Username)
+		if request == nil {
+			fmt.Printf("ℹ️  No pending access request in %s\n", env)
+			return fmt.Errorf("no pending access request")
+		}
+		id = request.ID
+	}
+
+	return h.waitAndAssumeRequest(env, envConfig.Proxy, id, timeout)
+}
+
+// newestPendingRequest returns the most recently created pending request of user; any user if empty
+func newestPendingRequest(requests []teleport.AccessRequest, user string) *teleport.AccessRequest {
+	var newest *teleport.AccessRequest
+	for i := range requests {
+		request := &requests[i]
+		if !request.IsPending() || (user != "" && request.User != user) {
+			continue
+		}
+		if newest == nil || request.Created.After(newest.Created) {
+			newest = request
+		}
+	}
+	return newest
+}
+
+// waitAndAssumeRequest waits for a request to be reviewed, then re-issues the session with its roles
+func (h *Handler) waitAndAssumeRequest(env, proxy, id string, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	fmt.Printf("⏳ Waiting for access request %s to be reviewed (Ctrl+C to stop)...\n", id)
+	request, err := h.teleportClient.WaitForAccessRequest(ctx, env, proxy, id, requestPollInterval)
+	if err != nil {
+		if request != nil && request.IsPending() {
+			fmt.Printf("⏰ Access request %s is still pending\n", id)
+			fmt.Printf("💡 Keep waiting with: tkube request wait %s %s\n", env, id)
+			return fmt.Errorf("access request %s is still pending", id)
+		}
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if !request.IsApproved() {
+		fmt.Printf("❌ Access request %s was %s\n", id, strings.ToLower(request.State))
+		if request.ResolveReason != "" {
+			fmt.Printf("   Reason: %s\n", request.ResolveReason)
+		}
+		return fmt.Errorf("access request %s was %s", id, strings.ToLower(request.State))
+	}
+
+	fmt.Printf("✅ Access request %s approved\n", id)
+	fmt.Printf("🔐 Assuming roles %s in %s...\n", strings.Join(request.Roles, ", "), env)
+	if err := h.teleportClient.LoginWithRequest(env, proxy, id); err != nil {
+		fmt.Printf("❌ Failed to assume the requested roles: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Session in %s now includes roles: %s\n", env, strings.Join(request.Roles, ", "))
+	return nil
+}
+
+// requestKubeAccess offers to request access to a cluster the user was denied, waits for the
+// request to be approved and then retries the kube login
+func (h *Handler) requestKubeAccess(env, proxy, cluster string) error {
+	fmt.Printf("🚫 Access to %s/%s was denied\n", env, cluster)
+
+	access, err := h.teleportClient.FindKubeAccess(env, proxy, cluster)
+	if err != nil {
+		fmt.Printf("⚠️  Could not look up requestable access: %v\n", err)
+	}
+	if access == nil {
+		fmt.Printf("💡 Request roles granting the cluster with: tkube request create %s --roles <role> --reason <reason>\n", env)
+		return teleport.ErrKubeAccessDenied
+	}
+
+	if !h.confirm(fmt.Sprintf("📨 %s can be requested. Request access now?", cluster), true) {
+		fmt.Printf("💡 Request it later with: tsh request create --resource=%s\n", access.ResourceID)
+		return teleport.ErrKubeAccessDenied
+	}
+	reason := h.prompt("Reason", "")
+
+	request, err := h.teleportClient.RequestKubeAccess(env, proxy, access, reason)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	if len(request.Roles) > 0 {
+		fmt.Printf("📨 Access request %s created for roles: %s\n", request.ID, strings.Join(request.Roles, ", "))
+	} else {
+		fmt.Printf("📨 Access request %s created for %s\n", request.ID, cluster)
+	}
+
+	if err := h.waitAndAssumeRequest(env, proxy, request.ID, 0); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Connecting to %s/%s...\n", env, cluster)
+	if err := h.teleportClient.KubeLoginWithEnv(env, proxy, cluster, os.Stdout); err != nil {
+		fmt.Printf("❌ Connection failed\n")
+		return err
+	}
+	return nil
+}
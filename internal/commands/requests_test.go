@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"testing"
+	"time"
+	"tkube/internal/teleport"
+)
+
+func TestNewestPendingRequest(t *testing.T) {
+	now := time.Now()
+	requests := []teleport.AccessRequest{
+		{ID: "old", User: "alice", State: teleport.RequestPending, Created: now.Add(-2 * time.Hour)},
+		{ID: "new", User: "alice", State: teleport.RequestPending, Created: now.Add(-time.Hour)},
+		{ID: "approved", User: "alice", State: teleport.RequestApproved, Created: now},
+		{ID: "bob", User: "bob", State: teleport.RequestPending, Created: now},
+	}
+
+	if request := newestPendingRequest(requests, "alice"); request == nil || request.ID != "new" {
+		t.Errorf("Expected alice's newest pending request, got %+v", request)
+	}
+	if request := newestPendingRequest(requests, ""); request == nil || request.ID != "bob" {
+		t.Errorf("Expected the newest pending request of any user, got %+v", request)
+	}
+	if request := newestPendingRequest(requests, "carol"); request != nil {
+		t.Errorf("Expected no request for carol, got %+v", request)
+	}
+}
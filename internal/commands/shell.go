@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"tkube/internal/teleport"
+)
+
+// Variables marking a tkube shell, e.g. for shell prompts
+const (
+	EnvShellEnv     = "TKUBE_ENV"
+	EnvShellCluster = "TKUBE_CLUSTER"
+)
+
+// userShell returns the user's login shell, falling back to the platform's default shell
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// clusterEnviron returns the environment for commands run against a cluster: its isolated
+// credentials plus the TKUBE_ENV and TKUBE_CLUSTER markers
+func (h *Handler) clusterEnviron(env, cluster string) ([]string, error) {
+	environ, err := h.teleportClient.KubeEnviron(env, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return teleport.SetEnviron(environ, EnvShellEnv+"="+env, EnvShellCluster+"="+cluster), nil
+}
+
+// Shell connects to a cluster and starts the user's shell with the cluster's isolated kubeconfig,
+// session directory and tsh, leaving the global kubeconfig untouched
+func (h *Handler) Shell(env, cluster string) error {
+	if current := os.Getenv(EnvShellEnv); current != "" {
+		fmt.Printf("⚠️  Already in a tkube shell for %s/%s - starting a nested shell\n", current, os.Getenv(EnvShellCluster))
+	}
+
+	_, cluster, err := h.connect(env, cluster, true, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	environ, err := h.clusterEnviron(env, cluster)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	shell := userShell()
+	fmt.Printf("🐚 Starting %s for %s/%s (exit to leave)\n", shell, env, cluster)
+
+	cmd := exec.Command(shell)
+	cmd.Env = environ
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl+C belongs to the shell; tkube only waits for it to exit
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	err = cmd.Run()
+	// The shell reports the status of the last command it ran, which is not a tkube failure
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		fmt.Printf("❌ Failed to start %s: %v\n", shell, err)
+		return err
+	}
+
+	fmt.Printf("👋 Left the %s/%s shell\n", env, cluster)
+	return nil
+}
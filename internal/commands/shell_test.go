@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// installFakeTSH installs a tsh stand-in for the prod environment of a test handler
+func installFakeTSH(t *testing.T, handler *Handler) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tsh is a POSIX shell script")
+	}
+
+	if err := handler.configManager.UpdateEnvironmentTSHVersion("prod", "17.7.1"); err != nil {
+		t.Fatalf("Failed to pin tsh version: %v", err)
+	}
+	tshPath := filepath.Join(handler.installer.GetBaseDir(), "17.7.1", "tsh")
+	os.MkdirAll(filepath.Dir(tshPath), 0755)
+	os.WriteFile(tshPath, []byte("#!/bin/sh\nexit 0\n"), 0755)
+	return tshPath
+}
+
+// lookupEnv returns the value of key in environ, or "<duplicate>" if key is set more than once
+func lookupEnv(environ []string, key string) string {
+	var values []string
+	for _, entry := range environ {
+		if strings.HasPrefix(entry, key+"=") {
+			values = append(values, strings.TrimPrefix(entry, key+"="))
+		}
+	}
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0]
+	}
+	return "<duplicate>"
+}
+
+func TestHandler_ClusterEnviron(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+	tshPath := installFakeTSH(t, handler)
+	t.Setenv("KUBECONFIG", "/home/alice/.kube/config")
+	t.Setenv("TELEPORT_HOME", "/home/alice/.tsh")
+	t.Setenv(EnvShellEnv, "staging")
+
+	environ, err := handler.clusterEnviron("prod", "eks-prod")
+	if err != nil {
+		t.Fatalf("Expected an environment, got %v", err)
+	}
+
+	if kubeconfig := lookupEnv(environ, "KUBECONFIG"); kubeconfig != handler.teleportClient.KubeconfigPath("prod", "eks-prod") {
+		t.Errorf("Expected the isolated kubeconfig, got %s", kubeconfig)
+	}
+	if home := lookupEnv(environ, "TELEPORT_HOME"); !strings.HasSuffix(home, filepath.Join("sessions", "prod")) {
+		t.Errorf("Expected the isolated session directory, got %s", home)
+	}
+	if path := lookupEnv(environ, "PATH"); !strings.HasPrefix(path, filepath.Dir(tshPath)+string(os.PathListSeparator)) {
+		t.Errorf("Expected the pinned tsh first on PATH, got %s", path)
+	}
+	if lookupEnv(environ, EnvShellEnv) != "prod" || lookupEnv(environ, EnvShellCluster) != "eks-prod" {
+		t.Errorf("Expected the tkube shell markers, got %v", environ)
+	}
+}
+
+func TestHandler_ClusterEnviron_RequiresTSH(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+
+	if _, err := handler.clusterEnviron("prod", "eks-prod"); err == nil {
+		t.Error("Expected an error without an installed tsh")
+	}
+}
+
+func TestUserShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	if shell := userShell(); shell != "/usr/bin/zsh" {
+		t.Errorf("Expected $SHELL, got %s", shell)
+	}
+
+	t.Setenv("SHELL", "")
+	if shell := userShell(); runtime.GOOS != "windows" && shell != "/bin/sh" {
+		t.Errorf("Expected /bin/sh without $SHELL, got %s", shell)
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults of the session agent started with 'tkube agent'
+const (
+	DefaultAgentInterval         = time.Minute
+	DefaultAgentWarnBefore       = 15 * time.Minute
+	DefaultAgentClustersInterval = 10 * time.Minute
+)
+
+// AgentOptions configure the background session agent
+type AgentOptions struct {
+	// Interval is how often sessions are checked, e.g. "1m"
+	Interval string `json:"interval,omitempty"`
+	// WarnBefore is how long before expiry a warning is raised, e.g. "15m"
+	WarnBefore string `json:"warn_before,omitempty"`
+	// ClustersInterval is how often the cluster lists of logged-in environments are refreshed
+	ClustersInterval string `json:"clusters_interval,omitempty"`
+	// Bell rings the terminal bell the agent runs in when warning
+	Bell bool `json:"bell,omitempty"`
+	// NotifyCommand is run with a title and a message as arguments, e.g. "notify-send"
+	NotifyCommand string `json:"notify_command,omitempty"`
+	// Hook is a shell command run with TKUBE_EVENT, TKUBE_ENV and TKUBE_VALID_UNTIL set
+	Hook string `json:"hook,omitempty"`
+}
+
+// Validate checks the agent options
+func (o *AgentOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	for _, option := range []struct{ name, value string }{
+		{"interval", o.Interval},
+		{"warn_before", o.WarnBefore},
+		{"clusters_interval", o.ClustersInterval},
+	} {
+		if option.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(option.value)
+		if err != nil {
+			return fmt.Errorf("invalid agent %s '%s': use a duration such as 1m or 15m", option.name, option.value)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("invalid agent %s '%s': must be positive", option.name, option.value)
+		}
+	}
+	return nil
+}
+
+// IntervalDuration returns how often the agent checks sessions
+func (o *AgentOptions) IntervalDuration() time.Duration {
+	return agentDuration(o, func(o *AgentOptions) string { return o.Interval }, DefaultAgentInterval)
+}
+
+// WarnBeforeDuration returns how long before expiry the agent warns
+func (o *AgentOptions) WarnBeforeDuration() time.Duration {
+	return agentDuration(o, func(o *AgentOptions) string { return o.WarnBefore }, DefaultAgentWarnBefore)
+}
+
+// ClustersIntervalDuration returns how often the agent refreshes cluster lists
+func (o *AgentOptions) ClustersIntervalDuration() time.Duration {
+	return agentDuration(o, func(o *AgentOptions) string { return o.ClustersInterval }, DefaultAgentClustersInterval)
+}
+
+// agentDuration parses an agent option, falling back to the default if it is unset or invalid
+func agentDuration(o *AgentOptions, value func(*AgentOptions) string, defaultValue time.Duration) time.Duration {
+	if o == nil || value(o) == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value(o))
+	if err != nil || duration <= 0 {
+		return defaultValue
+	}
+	return duration
+}
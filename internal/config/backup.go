@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBackups is the number of previous configuration versions kept in the backup directory
+const MaxBackups = 20
+
+// backupIDFormat names backups after the time they were taken, so IDs sort chronologically
+const backupIDFormat = "20060102-150405.000"
+
+// Backup is a previous version of the user configuration file
+type Backup struct {
+	ID   string
+	Path string
+	Time time.Time
+	Size int64
+}
+
+// RestorePlan describes the change restoring a backup would make to the user configuration
+type RestorePlan struct {
+	Backup Backup
+	Path   string
+	Before []byte
+	After  []byte
+}
+
+// Diff returns a unified diff from the current configuration to the backup
+func (p *RestorePlan) Diff() string {
+	return UnifiedDiff(p.Path, p.Backup.Path, p.Before, p.After)
+}
+
+// GetBackupDir returns the directory holding previous configuration versions
+func (m *Manager) GetBackupDir() string {
+	if m.backupDir != "" {
+		return m.backupDir
+	}
+	return filepath.Join(filepath.Dir(m.configPath), "backups")
+}
+
+// backupUserLayer copies the current user layer into the backup directory and prunes old backups.
+// A reason such as "schema-v0" is appended to the backup ID to tell why it was taken.
+// It returns the backup path; nothing is written if the file does not exist yet or next would not change it.
+// Callers must hold the config lock.
+func (m *Manager) backupUserLayer(next []byte, reason string) (string, error) {
+	current, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if string(current) == string(next) {
+		return "", nil
+	}
+
+	backupDir := m.GetBackupDir()
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+
+	// Dots separate the extension, so the millisecond part uses a dash in the ID
+	base := strings.Replace(time.Now().Format(backupIDFormat), ".", "-", 1)
+	if reason != "" {
+		base += "-" + reason
+	}
+	ext := filepath.Ext(m.configPath)
+	id := base
+	var path string
+	for i := 1; ; i++ {
+		path = filepath.Join(backupDir, id+ext)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			id = fmt.Sprintf("%s-%d", base, i)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(current); err != nil {
+			file.Close()
+			return "", err
+		}
+		if err := file.Close(); err != nil {
+			return "", err
+		}
+		break
+	}
+
+	return path, m.pruneBackups()
+}
+
+// pruneBackups removes the oldest backups beyond MaxBackups
+func (m *Manager) pruneBackups() error {
+	backups, err := m.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups[min(len(backups), MaxBackups):] {
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBackups returns the available configuration backups, newest first
+func (m *Manager) ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(m.GetBackupDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !isConfigExtension(ext) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			ID:   strings.TrimSuffix(entry.Name(), ext),
+			Path: filepath.Join(m.GetBackupDir(), entry.Name()),
+			Time: info.ModTime(),
+			Size: info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+	return backups, nil
+}
+
+// findBackup returns the backup with the given ID
+func (m *Manager) findBackup(id string) (*Backup, error) {
+	backups, err := m.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.ID == id {
+			return &backup, nil
+		}
+	}
+	return nil, fmt.Errorf("backup '%s' not found", id)
+}
+
+// PlanRestore computes the change restoring a backup would make without writing anything
+func (m *Manager) PlanRestore(id string) (*RestorePlan, error) {
+	backup, err := m.findBackup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if formatForPath(backup.Path) != formatForPath(m.configPath) {
+		return nil, fmt.Errorf("backup '%s' is in a different format than %s", id, m.configPath)
+	}
+
+	after, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if _, err := parseDocument(backup.Path, after); err != nil {
+		return nil, fmt.Errorf("backup '%s' is not a valid config file: %w", id, err)
+	}
+
+	before, err := os.ReadFile(m.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return &RestorePlan{
+		Backup: *backup,
+		Path:   m.configPath,
+		Before: before,
+		After:  after,
+	}, nil
+}
+
+// RestoreBackup replaces the user configuration with a backup.
+// The configuration being replaced is itself backed up, so a restore can be undone.
+func (m *Manager) RestoreBackup(id string) error {
+	return withFileLock(m.configPath, func() error {
+		plan, err := m.PlanRestore(id)
+		if err != nil {
+			return err
+		}
+
+		return m.writeUserLayer(plan.After)
+	})
+}
+
+// isConfigExtension reports whether ext is a supported configuration file extension
+func isConfigExtension(ext string) bool {
+	for _, candidate := range configExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
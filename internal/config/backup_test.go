@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManager_SaveCreatesBackup(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	original := "{\n  \"environments\": {},\n  \"auto_login\": true\n}\n"
+	os.WriteFile(configPath, []byte(original), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	if err := manager.UpdateAutoLogin(false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	backups, err := manager.ListBackups()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+
+	data, _ := os.ReadFile(backups[0].Path)
+	if string(data) != original {
+		t.Errorf("Expected backup to hold the previous version, got:\n%s", data)
+	}
+
+	// Writing identical contents does not add a backup
+	if err := manager.UpdateAutoLogin(false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if backups, _ := manager.ListBackups(); len(backups) != 1 {
+		t.Errorf("Expected unchanged write to skip the backup, got %d backups", len(backups))
+	}
+}
+
+func TestManager_BackupRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte("{\n  \"environments\": {}\n}\n"), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	for i := 0; i < MaxBackups+5; i++ {
+		env := Environment{Proxy: fmt.Sprintf("teleport%d.company.com:443", i)}
+		if err := manager.AddEnvironment(fmt.Sprintf("env%d", i), env); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	backups, err := manager.ListBackups()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(backups) != MaxBackups {
+		t.Fatalf("Expected %d backups, got %d", MaxBackups, len(backups))
+	}
+
+	// The newest backup is the version before the last change
+	data, _ := os.ReadFile(backups[0].Path)
+	if !strings.Contains(string(data), "env23") || strings.Contains(string(data), "env24") {
+		t.Errorf("Expected newest backup first, got:\n%s", data)
+	}
+}
+
+func TestManager_RestoreBackup(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	original := "{\n  // keep me\n  \"auto_login\": true\n}\n"
+	os.WriteFile(configPath, []byte(original), 0644)
+
+	manager := &Manager{configPath: configPath}
+	manager.UpdateAutoLogin(false)
+
+	backups, _ := manager.ListBackups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+
+	plan, err := manager.PlanRestore(backups[0].ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	diff := plan.Diff()
+	if !strings.Contains(diff, `-  "auto_login": false`) || !strings.Contains(diff, `+  "auto_login": true`) {
+		t.Errorf("Expected diff to show the restored value, got:\n%s", diff)
+	}
+
+	if err := manager.RestoreBackup(backups[0].ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if string(data) != original {
+		t.Errorf("Expected original config to be restored, got:\n%s", data)
+	}
+
+	// The replaced version was backed up as well
+	if backups, _ := manager.ListBackups(); len(backups) != 2 {
+		t.Errorf("Expected 2 backups after restore, got %d", len(backups))
+	}
+
+	if _, err := manager.PlanRestore("does-not-exist"); err == nil {
+		t.Error("Expected error for unknown backup")
+	}
+}
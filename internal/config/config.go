@@ -3,15 +3,32 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"tkube/internal/paths"
 )
 
 // Environment represents a Teleport environment configuration
 type Environment struct {
-	Proxy      string `json:"proxy"`
-	TSHVersion string `json:"tsh_version,omitempty"`
-	User       string `json:"user,omitempty"`
+	Proxy       string            `json:"proxy"`
+	TSHVersion  string            `json:"tsh_version,omitempty"`
+	User        string            `json:"user,omitempty"`
+	Aliases     map[string]string `json:"aliases,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Login       *LoginOptions     `json:"login,omitempty"`
+	RenewBefore string            `json:"renew_before,omitempty"`
+}
+
+// ResolveCluster returns the Teleport cluster name for a cluster name or alias
+func (e Environment) ResolveCluster(name string) string {
+	if cluster, ok := e.Aliases[name]; ok && cluster != "" {
+		return cluster
+	}
+	return name
 }
 
 // VersionDetector interface for detecting tsh versions
@@ -21,25 +38,86 @@ type VersionDetector interface {
 
 // Config represents the main tkube configuration
 type Config struct {
-	Environments map[string]Environment `json:"environments"`
-	AutoLogin    bool                   `json:"auto_login"`
-	DefaultUser  string                 `json:"default_user,omitempty"`
+	Schema          string                 `json:"$schema,omitempty"`
+	SchemaVersion   int                    `json:"schema_version,omitempty"`
+	Environments    map[string]Environment `json:"environments"`
+	AutoLogin       bool                   `json:"auto_login"`
+	DefaultUser     string                 `json:"default_user,omitempty"`
+	RenewBefore     string                 `json:"renew_before,omitempty"`
+	MergeKubeconfig bool                   `json:"merge_kubeconfig,omitempty"`
+	Agent           *AgentOptions          `json:"agent,omitempty"`
+	Team            *TeamSource            `json:"team,omitempty"`
 }
 
-// Manager handles configuration operations
+// Manager handles configuration operations.
+// The effective configuration is merged from the system, user and project layers,
+// while all modifications are written to the user layer only.
 type Manager struct {
 	configPath string
+	systemPath string
+	workDir    string
+	backupDir  string
+	teamDir    string
+	httpClient *http.Client
+
+	// profile is the active named profile, empty for the default profile
+	profile     string
+	profilesDir string
+	profileFile string
+
+	// warned holds the notices already shown, as Load runs many times per command
+	warned sync.Map
+}
+
+// ProfileNotFoundError is returned by NewManager if the selected profile does not exist
+type ProfileNotFoundError struct {
+	Profile string
+}
+
+func (e *ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("profile '%s' not found (create it with 'tkube --profile default profile create %s')", e.Profile, e.Profile)
 }
 
 // NewManager creates a new configuration manager
 func NewManager() (*Manager, error) {
-	homeDir, err := os.UserHomeDir()
+	return NewManagerForProfile("")
+}
+
+// NewManagerForProfile creates a configuration manager for the named profile, or for the
+// selected profile if name is empty
+func NewManagerForProfile(name string) (*Manager, error) {
+	p, err := paths.ResolveProfile(name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
+	}
+
+	// Selecting a misspelled profile must not silently start an empty one
+	if p.Profile != "" {
+		if info, err := os.Stat(p.ProfileConfigDir(p.Profile)); err != nil || !info.IsDir() {
+			return nil, &ProfileNotFoundError{Profile: p.Profile}
+		}
+	}
+
+	// TKUBE_CONFIG names the file explicitly; otherwise any supported format in the config directory is used
+	configPath := p.ConfigFile
+	if configPath == "" {
+		configPath = findConfigFile(p.ConfigDir, "config")
 	}
 
-	configPath := filepath.Join(homeDir, ".tkube", "config.json")
-	return &Manager{configPath: configPath}, nil
+	// The project layer is optional, so a missing working directory only disables it
+	workDir, _ := os.Getwd()
+
+	return &Manager{
+		configPath: configPath,
+		systemPath: findConfigFile(SystemConfigDir, "config"),
+		workDir:    workDir,
+		backupDir:  p.BackupsDir,
+		teamDir:    p.TeamDir,
+
+		profile:     p.Profile,
+		profilesDir: p.ProfilesDir,
+		profileFile: p.ProfileFile,
+	}, nil
 }
 
 // GetPath returns the configuration file path
@@ -47,50 +125,207 @@ func (m *Manager) GetPath() string {
 	return m.configPath
 }
 
-// Load loads the configuration from file
+// warnOnce prints a notice to stderr the first time it is raised by this manager
+func (m *Manager) warnOnce(message string) {
+	if _, seen := m.warned.LoadOrStore(message, true); !seen {
+		fmt.Fprintln(os.Stderr, message)
+	}
+}
+
+// Load loads the merged configuration from all layers
 func (m *Manager) Load() (*Config, error) {
-	// Create default config if it doesn't exist
-	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
+	config, _, err := m.LoadWithSources()
+	return config, err
+}
+
+// LoadWithSources loads the merged configuration along with the layer that supplied each value
+func (m *Manager) LoadWithSources() (*Config, Sources, error) {
+	layers := m.Layers()
+
+	// Create default config if no layer exists yet
+	if !anyLayerExists(layers) {
 		if err := m.createDefault(); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		layers = m.Layers()
+	}
+
+	// Upgrade an outdated user config before reading it
+	if plan, backupPath, err := m.Migrate(); err != nil {
+		return nil, nil, err
+	} else if backupPath != "" {
+		fmt.Fprintf(os.Stderr, "🔄 Migrated %s to schema version %d (backup: %s)\n", plan.Path, plan.To, backupPath)
+	}
+
+	// Only the cached team configuration is read; fetching it is left to 'tkube config sync' and 'tkube login'
+	if !m.TeamCached() {
+		m.warnOnce("💡 The team config has not been fetched yet; run 'tkube config sync'")
+	}
+
+	// Misspelled keys would otherwise be ignored silently
+	if err := checkKnownKeys(layers); err != nil {
+		return nil, nil, err
+	}
+	for _, ignored := range ignoredLayerCommands(layers) {
+		m.warnOnce(fmt.Sprintf("⚠️  Ignoring %s: commands are only run from your own config", ignored))
 	}
 
-	data, err := os.ReadFile(m.configPath)
+	return mergeLayers(layers)
+}
+
+// mergeLayers reads and deep-merges the existing layers into a configuration
+func mergeLayers(layers []Layer) (*Config, Sources, error) {
+	return mergeLayersWithUser(layers, nil)
+}
+
+// mergeLayersWithUser merges the layers like mergeLayers, using user as the contents
+// of the user layer instead of reading its file if it is not nil
+func mergeLayersWithUser(layers []Layer, user map[string]interface{}) (*Config, Sources, error) {
+	merged := make(map[string]interface{})
+	sources := make(Sources)
+	for _, layer := range layers {
+		if layer.Name == LayerUser && user != nil {
+			mergeLayer(merged, user, layer.Name, "", sources)
+			continue
+		}
+		if !layer.Exists {
+			continue
+		}
+
+		raw, err := readLayer(layer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !allowsCommands(layer.Name) {
+			raw = withoutCommandKeys(raw)
+		}
+		mergeLayer(merged, raw, layer.Name, "", sources)
+	}
+
+	data, err := json.Marshal(merged)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to merge config layers: %w", err)
 	}
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return &config, nil
+	if config.Environments == nil {
+		config.Environments = make(map[string]Environment)
+	}
+
+	return &config, sources, nil
 }
 
-// Save saves the configuration to file
+// anyLayerExists reports whether at least one layer file is present
+func anyLayerExists(layers []Layer) bool {
+	for _, layer := range layers {
+		if layer.Exists {
+			return true
+		}
+	}
+	return false
+}
+
+// loadUserDocument reads the user layer, returning an empty document if it does not exist
+func (m *Manager) loadUserDocument() (document, error) {
+	doc, err := readDocument(m.configPath)
+	if os.IsNotExist(err) {
+		return newDocument(m.configPath), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user config file %s: %w", m.configPath, err)
+	}
+	return doc, nil
+}
+
+// updateUserLayer applies a modification to the user layer and writes it back.
+// Only the edited values change; comments and key order are preserved.
+// The file is re-read under the config lock, so concurrent updates are never lost.
+func (m *Manager) updateUserLayer(update func(doc document) error) error {
+	return withFileLock(m.configPath, func() error {
+		doc, err := m.loadUserDocument()
+		if err != nil {
+			return err
+		}
+
+		if err := update(doc); err != nil {
+			return err
+		}
+
+		data, err := doc.Bytes()
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		return m.writeUserLayer(data)
+	})
+}
+
+// Save saves the configuration to the user layer, replacing its previous contents.
+// Values that did not change keep their comments and position in the file.
 func (m *Manager) Save(config *Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	value, err := toGeneric(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
+	return withFileLock(m.configPath, func() error {
+		// An unreadable file is left alone rather than replaced, so hand edits are never lost
+		doc, err := m.loadUserDocument()
+		if err != nil {
+			return err
+		}
+
+		if err := syncDocument(doc, value.(map[string]interface{})); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		data, err := doc.Bytes()
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		return m.writeUserLayer(data)
+	})
+}
+
+// writeUserLayer atomically replaces the user layer file with data,
+// keeping the previous contents in the backup history.
+// Callers must hold the config lock.
+func (m *Manager) writeUserLayer(data []byte) error {
+	_, err := m.writeUserLayerWithReason(data, "")
+	return err
+}
+
+// writeUserLayerWithReason is writeUserLayer tagging the backup with a reason.
+// It returns the path of the backup, empty if none was needed.
+// Callers must hold the config lock.
+func (m *Manager) writeUserLayerWithReason(data []byte, reason string) (string, error) {
 	configDir := filepath.Dir(m.configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+		return "", fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	backupPath, err := m.backupUserLayer(data, reason)
+	if err != nil {
+		return "", fmt.Errorf("failed to back up config file: %w", err)
 	}
 
-	return nil
+	if err := atomicWriteFile(m.configPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return backupPath, nil
 }
 
 // createDefault creates a default configuration file
 func (m *Manager) createDefault() error {
 	defaultConfig := Config{
+		Schema:        SchemaURL,
+		SchemaVersion: CurrentSchemaVersion,
 		Environments: map[string]Environment{
 			"prod": {Proxy: "teleport.prod.env:443"},
 			"test": {Proxy: "teleport.test.env:443"},
@@ -130,55 +365,156 @@ func (m *Manager) GetEnvironment(name string) (*Environment, error) {
 	return &env, nil
 }
 
-// AddEnvironment adds a new environment to the configuration
+// AddEnvironment adds a new environment to the user configuration
 func (m *Manager) AddEnvironment(name string, env Environment) error {
-	config, err := m.Load()
+	value, err := toGeneric(env)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to marshal environment: %w", err)
 	}
 
-	config.Environments[name] = env
-	return m.Save(config)
+	return m.updateUserLayer(func(doc document) error {
+		return doc.Set([]string{"environments", name}, value)
+	})
 }
 
-// RemoveEnvironment removes an environment from the configuration
-func (m *Manager) RemoveEnvironment(name string) error {
-	config, err := m.Load()
+// UpdateEnvironment changes an existing environment to env, writing only the fields that differ
+// from the effective environment to the user configuration. Fields supplied by other layers and
+// left unchanged stay out of the user file, so later upstream changes still apply.
+func (m *Manager) UpdateEnvironment(name string, env Environment) error {
+	current, err := m.GetEnvironment(name)
 	if err != nil {
 		return err
 	}
 
-	delete(config.Environments, name)
-	return m.Save(config)
+	before, err := toGeneric(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment: %w", err)
+	}
+	after, err := toGeneric(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment: %w", err)
+	}
+	beforeFields, _ := before.(map[string]interface{})
+	afterFields, _ := after.(map[string]interface{})
+
+	var changed []string
+	for key := range jsonFields(reflect.TypeOf(Environment{})) {
+		if !reflect.DeepEqual(beforeFields[key], afterFields[key]) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// Cleared fields are deleted from the user file, or blanked there if another layer still sets them
+	inherited := map[string]bool{}
+	for _, key := range changed {
+		if _, set := afterFields[key]; set {
+			continue
+		}
+		layers, err := m.definingLayers("environments", name, key)
+		if err != nil {
+			return err
+		}
+		for _, layer := range layers {
+			if layer != LayerUser {
+				inherited[key] = true
+			}
+		}
+	}
+
+	return m.updateUserLayer(func(doc document) error {
+		for _, key := range changed {
+			path := []string{"environments", name, key}
+			value, set := afterFields[key]
+			switch {
+			case set:
+				if err := doc.Set(path, value); err != nil {
+					return err
+				}
+			case inherited[key]:
+				if err := doc.Set(path, zeroValue(beforeFields[key])); err != nil {
+					return err
+				}
+			default:
+				if err := doc.Delete(path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 }
 
-// UpdateAutoLogin updates the auto-login setting
-func (m *Manager) UpdateAutoLogin(autoLogin bool) error {
-	config, err := m.Load()
+// zeroValue returns the empty value of the JSON type of value
+func zeroValue(value interface{}) interface{} {
+	switch value.(type) {
+	case string:
+		return ""
+	case map[string]interface{}:
+		return map[string]interface{}{}
+	case []interface{}:
+		return []interface{}{}
+	case bool:
+		return false
+	case float64:
+		return 0
+	}
+	return nil
+}
+
+// CanRemoveEnvironment checks that an environment is defined only in the user configuration.
+// Environments defined by the system or project layers cannot be removed by tkube.
+func (m *Manager) CanRemoveEnvironment(name string) error {
+	layers, err := m.definingLayers("environments", name)
 	if err != nil {
 		return err
 	}
 
-	config.AutoLogin = autoLogin
-	return m.Save(config)
+	for _, layer := range layers {
+		if layer != LayerUser {
+			return fmt.Errorf("environment '%s' is defined in the %s configuration and cannot be removed from the user configuration", name, layer)
+		}
+	}
+	return nil
+}
+
+// RemoveEnvironment removes an environment from the user configuration.
+// Environments defined by the system or project layers cannot be removed this way.
+func (m *Manager) RemoveEnvironment(name string) error {
+	if err := m.CanRemoveEnvironment(name); err != nil {
+		return err
+	}
+
+	return m.updateUserLayer(func(doc document) error {
+		return doc.Delete([]string{"environments", name})
+	})
 }
 
-// UpdateEnvironmentTSHVersion updates the tsh version for a specific environment
+// UpdateAutoLogin updates the auto-login setting
+func (m *Manager) UpdateAutoLogin(autoLogin bool) error {
+	return m.updateUserLayer(func(doc document) error {
+		return doc.Set([]string{"auto_login"}, autoLogin)
+	})
+}
+
+// UpdateEnvironmentTSHVersion updates the tsh version for a specific environment.
+// The version is written to the user layer, overriding any pin from the other layers.
 func (m *Manager) UpdateEnvironmentTSHVersion(envName, tshVersion string) error {
 	config, err := m.Load()
 	if err != nil {
 		return err
 	}
 
-	env, exists := config.Environments[envName]
-	if !exists {
+	if _, exists := config.Environments[envName]; !exists {
 		return fmt.Errorf("environment '%s' not found", envName)
 	}
 
-	env.TSHVersion = tshVersion
-	config.Environments[envName] = env
-
-	return m.Save(config)
+	return m.updateUserLayer(func(doc document) error {
+		return doc.Set([]string{"environments", envName, "tsh_version"}, tshVersion)
+	})
 }
 
 // AutoDetectAndUpdateTSHVersions automatically detects and updates tsh versions for all environments
@@ -189,6 +525,7 @@ func (m *Manager) AutoDetectAndUpdateTSHVersions(detector VersionDetector) (map[
 	}
 
 	detectedVersions := make(map[string]string)
+	updates := make(map[string]string)
 	updated := false
 
 	for envName, env := range config.Environments {
@@ -208,9 +545,8 @@ func (m *Manager) AutoDetectAndUpdateTSHVersions(detector VersionDetector) (map[
 
 		if version != "" {
 			// Update environment with detected version
-			env.TSHVersion = version
-			config.Environments[envName] = env
 			detectedVersions[envName] = version
+			updates[envName] = version
 			updated = true
 			fmt.Printf("🔍 Auto-detected tsh version %s for environment %s (%s)\n", version, envName, env.Proxy)
 		}
@@ -218,7 +554,15 @@ func (m *Manager) AutoDetectAndUpdateTSHVersions(detector VersionDetector) (map[
 
 	// Save configuration if any updates were made
 	if updated {
-		if err := m.Save(config); err != nil {
+		err := m.updateUserLayer(func(doc document) error {
+			for envName, version := range updates {
+				if err := doc.Set([]string{"environments", envName, "tsh_version"}, version); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to save updated configuration: %w", err)
 		}
 		fmt.Println("✅ Configuration updated with auto-detected tsh versions")
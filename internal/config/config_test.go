@@ -559,4 +559,276 @@ func TestManager_AutoDetectAndUpdateTSHVersions_EmptyVersion(t *testing.T) {
 	if len(detectedVersions) != 0 {
 		t.Errorf("Expected 0 detected versions, got %d", len(detectedVersions))
 	}
-}
\ No newline at end of file
+}
+
+func TestManager_Load_MergesLayers(t *testing.T) {
+	tempDir := t.TempDir()
+	systemPath := filepath.Join(tempDir, "etc", "config.json")
+	userPath := filepath.Join(tempDir, "home", "config.json")
+	projectDir := filepath.Join(tempDir, "repo")
+	workDir := filepath.Join(projectDir, "sub", "dir")
+
+	os.MkdirAll(filepath.Dir(systemPath), 0755)
+	os.MkdirAll(filepath.Dir(userPath), 0755)
+	os.MkdirAll(workDir, 0755)
+
+	// System layer provides the company baseline
+	os.WriteFile(systemPath, []byte(`{
+		"environments": {
+			"prod": {"proxy": "teleport.prod.company.com:443", "tsh_version": "16.4.0"},
+			"test": {"proxy": "teleport.test.company.com:443"}
+		},
+		"auto_login": true
+	}`), 0644)
+
+	// User layer overrides a single field and disables auto-login
+	os.WriteFile(userPath, []byte(`{
+		"environments": {
+			"prod": {"user": "alice"}
+		},
+		"auto_login": false
+	}`), 0644)
+
+	// Project layer is discovered from a parent of the working directory
+	os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(`{
+		"environments": {
+			"payments": {"proxy": "teleport.payments.company.com:443"},
+			"prod": {"tsh_version": "17.7.1"}
+		}
+	}`), 0644)
+
+	manager := &Manager{configPath: userPath, systemPath: systemPath, workDir: workDir}
+
+	config, sources, err := manager.LoadWithSources()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(config.Environments) != 3 {
+		t.Errorf("Expected 3 environments, got %d", len(config.Environments))
+	}
+
+	prod := config.Environments["prod"]
+	if prod.Proxy != "teleport.prod.company.com:443" {
+		t.Errorf("Expected proxy from system layer, got '%s'", prod.Proxy)
+	}
+	if prod.User != "alice" {
+		t.Errorf("Expected user from user layer, got '%s'", prod.User)
+	}
+	if prod.TSHVersion != "17.7.1" {
+		t.Errorf("Expected tsh version from project layer, got '%s'", prod.TSHVersion)
+	}
+	if config.AutoLogin {
+		t.Error("Expected user layer to disable auto-login")
+	}
+
+	expectedSources := map[string]string{
+		"environments.prod.proxy":       LayerSystem,
+		"environments.prod.user":        LayerUser,
+		"environments.prod.tsh_version": LayerProject,
+		"environments.payments.proxy":   LayerProject,
+		"auto_login":                    LayerUser,
+		"default_user":                  LayerDefault,
+	}
+	for path, expected := range expectedSources {
+		if got := sources.Lookup(path); got != expected {
+			t.Errorf("Expected source of %s to be %s, got %s", path, expected, got)
+		}
+	}
+}
+
+func TestManager_Load_NoDefaultWhenSystemLayerExists(t *testing.T) {
+	tempDir := t.TempDir()
+	systemPath := filepath.Join(tempDir, "system.json")
+	userPath := filepath.Join(tempDir, "home", "config.json")
+
+	os.WriteFile(systemPath, []byte(`{"environments": {"prod": {"proxy": "prod.proxy.com:443"}}}`), 0644)
+
+	manager := &Manager{configPath: userPath, systemPath: systemPath}
+
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(config.Environments) != 1 {
+		t.Errorf("Expected only the system environment, got %d environments", len(config.Environments))
+	}
+
+	// The example user config must not be created on top of a system baseline
+	if _, err := os.Stat(userPath); !os.IsNotExist(err) {
+		t.Error("Expected user config not to be created")
+	}
+}
+
+func TestManager_UpdateEnvironmentTSHVersion_SystemEnvironment(t *testing.T) {
+	tempDir := t.TempDir()
+	systemPath := filepath.Join(tempDir, "system.json")
+	userPath := filepath.Join(tempDir, "config.json")
+
+	os.WriteFile(systemPath, []byte(`{"environments": {"prod": {"proxy": "prod.proxy.com:443", "tsh_version": "16.4.0"}}}`), 0644)
+
+	manager := &Manager{configPath: userPath, systemPath: systemPath}
+
+	if err := manager.UpdateEnvironmentTSHVersion("prod", "17.7.1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Only the override should be written to the user layer
+	data, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatalf("Expected user config to be written, got %v", err)
+	}
+
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	prod := raw["environments"].(map[string]interface{})["prod"].(map[string]interface{})
+	if _, exists := prod["proxy"]; exists {
+		t.Error("Expected proxy not to be copied into the user layer")
+	}
+
+	env, err := manager.GetEnvironment("prod")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if env.Proxy != "prod.proxy.com:443" || env.TSHVersion != "17.7.1" {
+		t.Errorf("Expected merged environment, got %+v", env)
+	}
+}
+
+func TestManager_RemoveEnvironment_SystemEnvironment(t *testing.T) {
+	tempDir := t.TempDir()
+	systemPath := filepath.Join(tempDir, "system.json")
+	userPath := filepath.Join(tempDir, "config.json")
+
+	os.WriteFile(systemPath, []byte(`{"environments": {"prod": {"proxy": "prod.proxy.com:443"}}}`), 0644)
+
+	manager := &Manager{configPath: userPath, systemPath: systemPath}
+
+	if err := manager.RemoveEnvironment("prod"); err == nil {
+		t.Error("Expected error when removing an environment defined in the system layer")
+	}
+}
+
+func TestNormalizeProxy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"teleport.company.com:443", "teleport.company.com:443", false},
+		{"teleport.company.com", "teleport.company.com:443", false},
+		{"https://teleport.company.com:3080/", "teleport.company.com:3080", false},
+		{"", "", true},
+		{"teleport.company.com:https", "", true},
+		{"teleport.company.com:70000", "", true},
+		{"a:b:c", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeProxy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeProxy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeProxy(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEnvironmentName(t *testing.T) {
+	for _, name := range []string{"prod", "prod-eu_1", "2024"} {
+		if err := ValidateEnvironmentName(name); err != nil {
+			t.Errorf("Expected '%s' to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-prod", "prod eu", "prod/eu"} {
+		if err := ValidateEnvironmentName(name); err == nil {
+			t.Errorf("Expected '%s' to be invalid", name)
+		}
+	}
+}
+
+func TestManager_UpdateEnvironment(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{"environments": {"prod": {"proxy": "prod.proxy.com:443", "user": "alice"}}}`), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	if err := manager.UpdateEnvironment("prod", Environment{Proxy: "new.proxy.com:443"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	env, _ := manager.GetEnvironment("prod")
+	if env.Proxy != "new.proxy.com:443" || env.User != "" {
+		t.Errorf("Expected environment to be replaced, got %+v", env)
+	}
+
+	if err := manager.UpdateEnvironment("missing", Environment{Proxy: "x:443"}); err == nil {
+		t.Error("Expected error for unknown environment")
+	}
+}
+
+func TestManager_UpdateEnvironment_KeepsOtherLayers(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "home", "config.json")
+	projectDir := filepath.Join(tempDir, "repo")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.MkdirAll(projectDir, 0755)
+
+	os.WriteFile(configPath, []byte(`{"environments": {"prod": {"proxy": "prod.proxy.com:443"}}}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(`{
+		"environments": {"prod": {"tsh_version": "17.7.1"}}
+	}`), 0644)
+
+	manager := &Manager{configPath: configPath, systemPath: filepath.Join(tempDir, "none.json"), workDir: projectDir}
+
+	env, err := manager.GetEnvironment("prod")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	env.Proxy = "new.proxy.com:443"
+	if err := manager.UpdateEnvironment("prod", *env); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	var raw map[string]map[string]map[string]interface{}
+	json.Unmarshal(data, &raw)
+	prod := raw["environments"]["prod"]
+	if prod["proxy"] != "new.proxy.com:443" {
+		t.Errorf("Expected the changed proxy in the user file, got %v", prod)
+	}
+	if _, ok := prod["tsh_version"]; ok {
+		t.Errorf("Expected the project tsh_version not to be copied into the user file, got %v", prod)
+	}
+
+	env, _ = manager.GetEnvironment("prod")
+	if env.Proxy != "new.proxy.com:443" || env.TSHVersion != "17.7.1" {
+		t.Errorf("Expected the new proxy and the project tsh_version, got %+v", env)
+	}
+}
+
+func TestEnvironment_ResolveCluster(t *testing.T) {
+	env := Environment{
+		Proxy: "teleport.prod.company.com:443",
+		Aliases: map[string]string{
+			"payments": "eks-prod-eu-west-1-payments-blue",
+			"broken":   "",
+		},
+	}
+
+	tests := map[string]string{
+		"payments":                         "eks-prod-eu-west-1-payments-blue",
+		"eks-prod-eu-west-1-payments-blue": "eks-prod-eu-west-1-payments-blue",
+		"other":                            "other",
+		"broken":                           "broken",
+	}
+
+	for name, want := range tests {
+		if got := env.ResolveCluster(name); got != want {
+			t.Errorf("ResolveCluster(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
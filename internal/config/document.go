@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Supported configuration file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// configExtensions lists the recognised config file extensions in lookup order
+var configExtensions = []string{".yaml", ".yml", ".jsonc", ".json"}
+
+// document is an editable configuration file that preserves comments and key order
+type document interface {
+	// Decode returns the document contents as a generic map
+	Decode() (map[string]interface{}, error)
+	// Set stores a value at the given path, creating intermediate objects as needed
+	Set(path []string, value interface{}) error
+	// Delete removes the value stored at the given path
+	Delete(path []string) error
+	// Bytes renders the document back to its file representation
+	Bytes() ([]byte, error)
+	// Position returns the 1-based line and column of the key at the given path
+	Position(path []string) (line, column int, ok bool)
+}
+
+// formatForPath detects the configuration format from a file extension
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		// .json files are parsed as JSONC so that comments never make them unreadable
+		return FormatJSON
+	}
+}
+
+// parseDocument parses file contents according to the format of the given path
+func parseDocument(path string, data []byte) (document, error) {
+	if formatForPath(path) == FormatYAML {
+		return parseYAMLDocument(data)
+	}
+	return parseJSONCDocument(data)
+}
+
+// newDocument creates an empty document in the format of the given path
+func newDocument(path string) document {
+	if formatForPath(path) == FormatYAML {
+		return newYAMLDocument()
+	}
+	return newJSONCDocument()
+}
+
+// readDocument reads and parses a configuration file
+func readDocument(path string) (document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(path, data)
+}
+
+// findConfigFile returns the first existing file named base with a recognised extension in dir,
+// or the .json variant if none exists
+func findConfigFile(dir, base string) string {
+	for _, ext := range configExtensions {
+		candidate := filepath.Join(dir, base+ext)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return filepath.Join(dir, base+".json")
+}
+
+// syncDocument edits doc so that its contents match desired, touching only the values that differ.
+// Unchanged keys keep their comments and position.
+func syncDocument(doc document, desired map[string]interface{}) error {
+	decoded, err := doc.Decode()
+	if err != nil {
+		return err
+	}
+
+	current, err := toGeneric(decoded)
+	if err != nil {
+		return err
+	}
+
+	currentMap, _ := current.(map[string]interface{})
+	return syncObject(doc, nil, currentMap, desired)
+}
+
+// syncObject applies the differences between two generic objects at path
+func syncObject(doc document, path []string, current, desired map[string]interface{}) error {
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := append(append([]string{}, path...), key)
+		want := desired[key]
+		have, exists := current[key]
+
+		wantMap, wantIsMap := want.(map[string]interface{})
+		haveMap, haveIsMap := have.(map[string]interface{})
+		if exists && wantIsMap && haveIsMap {
+			if err := syncObject(doc, childPath, haveMap, wantMap); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if exists && reflect.DeepEqual(have, want) {
+			continue
+		}
+
+		if err := doc.Set(childPath, want); err != nil {
+			return err
+		}
+	}
+
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			if err := doc.Delete(append(append([]string{}, path...), key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// marshalIndent renders a value as indented JSON without HTML escaping
+func marshalIndent(value interface{}, prefix, indent string) (string, error) {
+	var out strings.Builder
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, indent)
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
@@ -0,0 +1,340 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsoncFixture = `{
+  // Shared proxies
+  "environments": {
+    "prod": {
+      "proxy": "teleport.prod.company.com:443",
+      // Pinned until the 17.x rollout finishes
+      "tsh_version": "16.4.0" // see INC-1234
+    },
+    "test": {
+      "proxy": "teleport.test.company.com:443",
+    },
+  },
+  "auto_login": true
+}
+`
+
+const yamlFixture = `# Shared proxies
+environments:
+  prod:
+    proxy: teleport.prod.company.com:443
+    # Pinned until the 17.x rollout finishes
+    tsh_version: 16.4.0 # see INC-1234
+  test:
+    proxy: teleport.test.company.com:443
+auto_login: true
+`
+
+func TestParseJSONCDocument_Decode(t *testing.T) {
+	doc, err := parseJSONCDocument([]byte(jsoncFixture))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	raw, err := doc.Decode()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	envs := raw["environments"].(map[string]interface{})
+	if len(envs) != 2 {
+		t.Errorf("Expected 2 environments, got %d", len(envs))
+	}
+	if raw["auto_login"] != true {
+		t.Error("Expected auto_login to be true")
+	}
+}
+
+func TestParseJSONCDocument_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"not an object", `[1, 2]`, "must be a JSON object"},
+		{"missing colon", "{\n  \"a\" 1\n}", "line 2, column 7"},
+		{"unterminated comment", `{ /* comment }`, "unterminated comment"},
+		{"invalid literal", `{"a": yes}`, "invalid value"},
+		{"trailing content", `{} {}`, "unexpected content"},
+		{"duplicate key", "{\n  \"a\": 1,\n  \"a\": 2\n}", "line 3, column 3: duplicate key \"a\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseJSONCDocument([]byte(tt.input))
+			if err == nil {
+				t.Fatal("Expected parse error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestJSONCDocument_SetPreservesComments(t *testing.T) {
+	doc, _ := parseJSONCDocument([]byte(jsoncFixture))
+
+	if err := doc.Set([]string{"environments", "prod", "tsh_version"}, "17.7.1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := doc.Bytes()
+	expected := strings.Replace(jsoncFixture, `"16.4.0"`, `"17.7.1"`, 1)
+	if string(data) != expected {
+		t.Errorf("Expected only the version to change, got:\n%s", data)
+	}
+}
+
+func TestJSONCDocument_SetInsertsMembers(t *testing.T) {
+	doc, _ := parseJSONCDocument([]byte(jsoncFixture))
+
+	// New key in an object without trailing comma after its last member
+	if err := doc.Set([]string{"environments", "prod", "user"}, "alice"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// New key in an object using trailing commas
+	if err := doc.Set([]string{"environments", "test", "tsh_version"}, "17.7.1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// New nested object
+	if err := doc.Set([]string{"environments", "dev", "proxy"}, "teleport.dev.company.com:443"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := doc.Bytes()
+	text := string(data)
+
+	for _, fragment := range []string{
+		`"tsh_version": "16.4.0", // see INC-1234` + "\n" + `      "user": "alice"`,
+		`"proxy": "teleport.test.company.com:443",` + "\n" + `      "tsh_version": "17.7.1",`,
+		`"dev": {` + "\n" + `      "proxy": "teleport.dev.company.com:443"` + "\n" + `    },`,
+		"// Pinned until the 17.x rollout finishes",
+	} {
+		if !strings.Contains(text, fragment) {
+			t.Errorf("Expected output to contain %q, got:\n%s", fragment, text)
+		}
+	}
+
+	// The result must still parse
+	if _, err := parseJSONCDocument(data); err != nil {
+		t.Errorf("Expected edited document to parse, got %v", err)
+	}
+}
+
+func TestJSONCDocument_Delete(t *testing.T) {
+	doc, _ := parseJSONCDocument([]byte(jsoncFixture))
+
+	if err := doc.Delete([]string{"environments", "prod", "tsh_version"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := doc.Delete([]string{"auto_login"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := doc.Bytes()
+	text := string(data)
+
+	if strings.Contains(text, "tsh_version") || strings.Contains(text, "Pinned until") {
+		t.Errorf("Expected tsh_version and its comment to be removed, got:\n%s", text)
+	}
+	if strings.Contains(text, "auto_login") {
+		t.Errorf("Expected auto_login to be removed, got:\n%s", text)
+	}
+
+	// Removing the last member must not leave a dangling comma in strict JSON
+	if !strings.Contains(text, `"proxy": "teleport.prod.company.com:443"`+"\n") {
+		t.Errorf("Expected separator after the remaining member to be dropped, got:\n%s", text)
+	}
+	if _, err := parseJSONCDocument(data); err != nil {
+		t.Errorf("Expected edited document to parse, got %v", err)
+	}
+}
+
+func TestJSONCDocument_DeleteCommentBeforeComma(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		key      string
+		expected map[string]interface{}
+	}{
+		{"first member", `{"a": 1 /* one */, "b": 2}`, "a", map[string]interface{}{"b": 2.0}},
+		{"last member", `{"a": 1 /* one */, "b": 2}`, "b", map[string]interface{}{"a": 1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseJSONCDocument([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := doc.Delete([]string{tt.key}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			data, _ := doc.Bytes()
+			edited, err := parseJSONCDocument(data)
+			if err != nil {
+				t.Fatalf("Expected edited document to parse, got %v:\n%s", err, data)
+			}
+			raw, _ := edited.Decode()
+			if !reflect.DeepEqual(raw, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, raw)
+			}
+		})
+	}
+}
+
+func TestJSONCDocument_SetEmptyDocument(t *testing.T) {
+	doc := newJSONCDocument()
+
+	if err := doc.Set([]string{"environments", "prod", "proxy"}, "prod.proxy.com:443"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := doc.Bytes()
+	expected := "{\n  \"environments\": {\n    \"prod\": {\n      \"proxy\": \"prod.proxy.com:443\"\n    }\n  }\n}\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestYAMLDocument_SetPreservesComments(t *testing.T) {
+	doc, err := parseYAMLDocument([]byte(yamlFixture))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := doc.Set([]string{"environments", "prod", "tsh_version"}, "17.7.1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := doc.Set([]string{"environments", "test", "user"}, "alice"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := doc.Bytes()
+	text := string(data)
+
+	for _, fragment := range []string{
+		"# Shared proxies",
+		"# Pinned until the 17.x rollout finishes",
+		"tsh_version: 17.7.1 # see INC-1234",
+		"  test:\n    proxy: teleport.test.company.com:443\n    user: alice\n",
+	} {
+		if !strings.Contains(text, fragment) {
+			t.Errorf("Expected output to contain %q, got:\n%s", fragment, text)
+		}
+	}
+
+	// Key order is preserved
+	if strings.Index(text, "environments:") > strings.Index(text, "auto_login:") {
+		t.Errorf("Expected key order to be preserved, got:\n%s", text)
+	}
+}
+
+func TestYAMLDocument_Delete(t *testing.T) {
+	doc, _ := parseYAMLDocument([]byte(yamlFixture))
+
+	if err := doc.Delete([]string{"environments", "prod", "tsh_version"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	raw, _ := doc.Decode()
+	prod := raw["environments"].(map[string]interface{})["prod"].(map[string]interface{})
+	if _, exists := prod["tsh_version"]; exists {
+		t.Error("Expected tsh_version to be removed")
+	}
+}
+
+func TestManager_YAMLConfigRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	os.WriteFile(configPath, []byte(yamlFixture), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	env, err := manager.GetEnvironment("prod")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if env.TSHVersion != "16.4.0" {
+		t.Errorf("Expected TSH version '16.4.0', got '%s'", env.TSHVersion)
+	}
+
+	if err := manager.UpdateEnvironmentTSHVersion("prod", "17.7.1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "# Pinned until the 17.x rollout finishes") {
+		t.Errorf("Expected comments to survive the update, got:\n%s", data)
+	}
+}
+
+func TestManager_SaveKeepsUnchangedValues(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.jsonc")
+	os.WriteFile(configPath, []byte(jsoncFixture), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config.AutoLogin = false
+	if err := manager.Save(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	text := string(data)
+	if !strings.Contains(text, "// Pinned until the 17.x rollout finishes") {
+		t.Errorf("Expected comments to survive Save, got:\n%s", text)
+	}
+	if !strings.Contains(text, `"auto_login": false`) {
+		t.Errorf("Expected auto_login to be updated, got:\n%s", text)
+	}
+}
+
+func TestManager_SaveKeepsUnreadableFile(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.jsonc")
+	broken := "{\n  // Half-finished edit\n  \"auto_login\": true,,\n}\n"
+	os.WriteFile(configPath, []byte(broken), 0644)
+
+	manager := &Manager{configPath: configPath, backupDir: filepath.Join(tempDir, "backups")}
+
+	if err := manager.Save(&Config{AutoLogin: false}); err == nil {
+		t.Fatal("Expected Save to fail for an unreadable file")
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if string(data) != broken {
+		t.Errorf("Expected the unreadable file to be left alone, got:\n%s", data)
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if got := findConfigFile(tempDir, "config"); got != filepath.Join(tempDir, "config.json") {
+		t.Errorf("Expected config.json default, got %s", got)
+	}
+
+	os.WriteFile(filepath.Join(tempDir, "config.yaml"), []byte("auto_login: true\n"), 0644)
+	if got := findConfigFile(tempDir, "config"); got != filepath.Join(tempDir, "config.yaml") {
+		t.Errorf("Expected config.yaml, got %s", got)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// environmentNamePattern matches names usable as command arguments and session directory names
+var environmentNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// tshVersionPattern matches full tsh release versions such as 17.7.1
+var tshVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// defaultProxyPort is the port tsh assumes when a proxy address has none
+const defaultProxyPort = "443"
+
+// ValidateEnvironmentName checks that name can be used as an environment name
+func ValidateEnvironmentName(name string) error {
+	if name == "" {
+		return fmt.Errorf("environment name cannot be empty")
+	}
+	if !environmentNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment name '%s': use letters, digits, '-' and '_' and start with a letter or digit", name)
+	}
+	return nil
+}
+
+// NormalizeProxy validates a Teleport proxy address and returns it in host:port form.
+// A missing port defaults to 443, like tsh does.
+func NormalizeProxy(proxy string) (string, error) {
+	proxy = strings.TrimSpace(proxy)
+	proxy = strings.TrimPrefix(proxy, "https://")
+	proxy = strings.TrimSuffix(proxy, "/")
+	if proxy == "" {
+		return "", fmt.Errorf("proxy address cannot be empty")
+	}
+
+	host, port, err := net.SplitHostPort(proxy)
+	if err != nil {
+		if strings.Contains(proxy, ":") {
+			return "", fmt.Errorf("invalid proxy address '%s': expected host:port", proxy)
+		}
+		host, port = proxy, defaultProxyPort
+	}
+
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return "", fmt.Errorf("invalid proxy host in '%s'", proxy)
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		return "", fmt.Errorf("invalid proxy port '%s'", port)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// NormalizeTSHVersion validates a tsh version and strips a leading 'v'
+func NormalizeTSHVersion(version string) (string, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !tshVersionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid tsh version '%s': expected MAJOR.MINOR.PATCH, e.g. 17.7.1", version)
+	}
+	return version, nil
+}
@@ -0,0 +1,590 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsoncNode is a parsed JSON value with its byte span in the source
+type jsoncNode struct {
+	kind    byte // '{' for objects, '[' for arrays, 0 for scalars
+	start   int
+	end     int
+	members []*jsoncMember
+	items   []*jsoncNode
+}
+
+// jsoncMember is a key/value pair of a parsed JSON object
+type jsoncMember struct {
+	key      string
+	keyStart int
+	value    *jsoncNode
+}
+
+// member returns the object member with the given key, or nil
+func (n *jsoncNode) member(key string) (*jsoncMember, int) {
+	for i, member := range n.members {
+		if member.key == key {
+			return member, i
+		}
+	}
+	return nil, -1
+}
+
+// jsoncDocument is a JSON document that tolerates comments and trailing commas.
+// Edits are spliced into the original text so everything else is preserved byte for byte.
+type jsoncDocument struct {
+	src  []byte
+	root *jsoncNode
+}
+
+// newJSONCDocument creates an empty JSON document
+func newJSONCDocument() *jsoncDocument {
+	doc, _ := parseJSONCDocument([]byte("{}\n"))
+	return doc
+}
+
+// parseJSONCDocument parses JSON with comments
+func parseJSONCDocument(data []byte) (*jsoncDocument, error) {
+	parser := &jsoncParser{src: data}
+	parser.skipSpace()
+	if parser.err != nil {
+		return nil, parser.err
+	}
+
+	root := parser.parseValue()
+	if parser.err != nil {
+		return nil, parser.err
+	}
+
+	parser.skipSpace()
+	if parser.err == nil && parser.pos < len(data) {
+		parser.fail("unexpected content after top-level value")
+	}
+	if parser.err != nil {
+		return nil, parser.err
+	}
+
+	if root.kind != '{' {
+		return nil, fmt.Errorf("configuration must be a JSON object")
+	}
+
+	return &jsoncDocument{src: data, root: root}, nil
+}
+
+// Decode returns the document contents as a generic map
+func (d *jsoncDocument) Decode() (map[string]interface{}, error) {
+	value, err := d.decodeNode(d.root)
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]interface{}), nil
+}
+
+// decodeNode converts a parsed node into a generic value
+func (d *jsoncDocument) decodeNode(node *jsoncNode) (interface{}, error) {
+	switch node.kind {
+	case '{':
+		object := make(map[string]interface{}, len(node.members))
+		for _, member := range node.members {
+			value, err := d.decodeNode(member.value)
+			if err != nil {
+				return nil, err
+			}
+			object[member.key] = value
+		}
+		return object, nil
+	case '[':
+		array := make([]interface{}, 0, len(node.items))
+		for _, item := range node.items {
+			value, err := d.decodeNode(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	default:
+		var value interface{}
+		if err := json.Unmarshal(d.src[node.start:node.end], &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// Set stores a value at the given path, creating intermediate objects as needed
+func (d *jsoncDocument) Set(path []string, value interface{}) error {
+	node := d.root
+	for i, key := range path {
+		if node.kind != '{' {
+			return fmt.Errorf("cannot set %s: %s is not an object", strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+
+		member, _ := node.member(key)
+		if member == nil {
+			// Wrap the value in the objects that are still missing
+			nested := value
+			for j := len(path) - 1; j > i; j-- {
+				nested = map[string]interface{}{path[j]: nested}
+			}
+			return d.insertMember(node, key, nested)
+		}
+
+		if i == len(path)-1 {
+			text, err := marshalIndent(value, d.lineIndent(member.keyStart), d.indentUnit())
+			if err != nil {
+				return err
+			}
+			return d.splice(member.value.start, member.value.end, text)
+		}
+
+		node = member.value
+	}
+	return nil
+}
+
+// insertMember appends a new key/value pair to an object, following the style of its last member
+func (d *jsoncDocument) insertMember(object *jsoncNode, key string, value interface{}) error {
+	keyText, err := marshalIndent(key, "", "")
+	if err != nil {
+		return err
+	}
+
+	if len(object.members) == 0 {
+		outer := d.lineIndent(object.start)
+		inner := outer + d.indentUnit()
+		valueText, err := marshalIndent(value, inner, d.indentUnit())
+		if err != nil {
+			return err
+		}
+		text := "\n" + inner + keyText + ": " + valueText + "\n" + outer
+		return d.splice(object.start+1, object.end-1, strings.TrimRight(string(d.src[object.start+1:object.end-1]), " \t\r\n")+text)
+	}
+
+	last := object.members[len(object.members)-1]
+	indent := d.lineIndent(last.keyStart)
+	valueText, err := marshalIndent(value, indent, d.indentUnit())
+	if err != nil {
+		return err
+	}
+
+	// Insert after the last member's trailing comma and same-line comment
+	insertAt := last.value.end
+	next := d.skipToSeparator(insertAt)
+	hasComma := next < len(d.src) && d.src[next] == ','
+	if hasComma {
+		insertAt = next + 1
+		next = d.skipInline(insertAt)
+	}
+	if bytes.HasPrefix(d.src[next:], []byte("//")) {
+		insertAt = d.lineEnd(next)
+	}
+
+	text := "\n" + indent + keyText + ": " + valueText
+	if hasComma {
+		// Keep the file's trailing-comma style
+		return d.splice(insertAt, insertAt, text+",")
+	}
+
+	src := make([]byte, 0, len(d.src)+len(text)+1)
+	src = append(src, d.src[:last.value.end]...)
+	src = append(src, ',')
+	src = append(src, d.src[last.value.end:insertAt]...)
+	src = append(src, text...)
+	src = append(src, d.src[insertAt:]...)
+	return d.reparse(src)
+}
+
+// Delete removes the value stored at the given path along with its comments
+func (d *jsoncDocument) Delete(path []string) error {
+	node := d.root
+	for i, key := range path {
+		if node.kind != '{' {
+			return nil
+		}
+
+		member, index := node.member(key)
+		if member == nil {
+			return nil
+		}
+
+		if i < len(path)-1 {
+			node = member.value
+			continue
+		}
+
+		start := member.keyStart
+		lineStart := d.lineStart(start)
+		atLineStart := strings.TrimSpace(string(d.src[lineStart:start])) == ""
+		if atLineStart {
+			start = lineStart
+			// Comment lines directly above a member describe it
+			for start > 0 {
+				prevStart := d.lineStart(start - 1)
+				if !strings.HasPrefix(strings.TrimSpace(string(d.src[prevStart:start])), "//") {
+					break
+				}
+				start = prevStart
+			}
+		}
+
+		end := member.value.end
+		next := d.skipToSeparator(end)
+		hasComma := next < len(d.src) && d.src[next] == ','
+		if hasComma {
+			end = next + 1
+			next = d.skipInline(end)
+		}
+		if bytes.HasPrefix(d.src[next:], []byte("//")) {
+			end = d.lineEnd(next)
+		}
+		if atLineStart && end < len(d.src) && d.src[end] == '\n' {
+			end++
+		}
+
+		src := make([]byte, 0, len(d.src))
+		src = append(src, d.src[:start]...)
+		src = append(src, d.src[end:]...)
+
+		// The new last member must not keep a separator that JSON does not allow
+		if !hasComma && index == len(node.members)-1 && index > 0 {
+			prev := node.members[index-1]
+			comma := d.skipToSeparator(prev.value.end)
+			if comma < start && d.src[comma] == ',' {
+				src = append(src[:comma:comma], src[comma+1:]...)
+			}
+		}
+
+		return d.reparse(src)
+	}
+	return nil
+}
+
+// Bytes renders the document back to its file representation
+func (d *jsoncDocument) Bytes() ([]byte, error) {
+	return d.src, nil
+}
+
+// splice replaces the source between start and end and re-parses the document
+func (d *jsoncDocument) splice(start, end int, text string) error {
+	src := make([]byte, 0, len(d.src)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	return d.reparse(src)
+}
+
+// reparse replaces the document source and refreshes the parse tree
+func (d *jsoncDocument) reparse(src []byte) error {
+	doc, err := parseJSONCDocument(src)
+	if err != nil {
+		return fmt.Errorf("failed to edit config: %w", err)
+	}
+	*d = *doc
+	return nil
+}
+
+// Position returns the line and column of the key at the given path
+func (d *jsoncDocument) Position(path []string) (int, int, bool) {
+	node := d.root
+	var member *jsoncMember
+	for _, key := range path {
+		if node == nil || node.kind != '{' {
+			return 0, 0, false
+		}
+		if member, _ = node.member(key); member == nil {
+			return 0, 0, false
+		}
+		node = member.value
+	}
+	if member == nil {
+		return 0, 0, false
+	}
+
+	line, column := lineColumn(d.src, member.keyStart)
+	return line, column, true
+}
+
+// lineColumn converts a byte offset into a 1-based line and column
+func lineColumn(src []byte, pos int) (int, int) {
+	line, column := 1, 1
+	for _, c := range src[:pos] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+// lineStart returns the offset of the first byte of the line containing pos
+func (d *jsoncDocument) lineStart(pos int) int {
+	return bytes.LastIndexByte(d.src[:pos], '\n') + 1
+}
+
+// lineEnd returns the offset of the newline terminating the line containing pos
+func (d *jsoncDocument) lineEnd(pos int) int {
+	if idx := bytes.IndexByte(d.src[pos:], '\n'); idx >= 0 {
+		return pos + idx
+	}
+	return len(d.src)
+}
+
+// lineIndent returns the leading whitespace of the line containing pos
+func (d *jsoncDocument) lineIndent(pos int) string {
+	start := d.lineStart(pos)
+	end := start
+	for end < len(d.src) && (d.src[end] == ' ' || d.src[end] == '\t') {
+		end++
+	}
+	return string(d.src[start:end])
+}
+
+// skipInline skips spaces and tabs without crossing a line break
+func (d *jsoncDocument) skipInline(pos int) int {
+	for pos < len(d.src) && (d.src[pos] == ' ' || d.src[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// skipToSeparator skips spaces, tabs and block comments, so a separator after a commented value is found
+func (d *jsoncDocument) skipToSeparator(pos int) int {
+	for {
+		pos = d.skipInline(pos)
+		if !bytes.HasPrefix(d.src[pos:], []byte("/*")) {
+			return pos
+		}
+		idx := bytes.Index(d.src[pos+2:], []byte("*/"))
+		if idx < 0 {
+			return pos
+		}
+		pos += idx + 4
+	}
+}
+
+// indentUnit detects the indentation used by the document, defaulting to two spaces
+func (d *jsoncDocument) indentUnit() string {
+	if len(d.root.members) > 0 {
+		first := d.root.members[0]
+		rootIndent := d.lineIndent(d.root.start)
+		indent := d.lineIndent(first.keyStart)
+		if d.lineStart(first.keyStart) != d.lineStart(d.root.start) && len(indent) > len(rootIndent) {
+			return indent[len(rootIndent):]
+		}
+	}
+	return "  "
+}
+
+// jsoncParser is a recursive descent parser for JSON with comments and trailing commas
+type jsoncParser struct {
+	src []byte
+	pos int
+	err error
+}
+
+// fail records a parse error at the current position
+func (p *jsoncParser) fail(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	line, column := lineColumn(p.src, p.pos)
+	p.err = fmt.Errorf("line %d, column %d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments
+func (p *jsoncParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch {
+		case p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r':
+			p.pos++
+		case bytes.HasPrefix(p.src[p.pos:], []byte("//")):
+			if idx := bytes.IndexByte(p.src[p.pos:], '\n'); idx >= 0 {
+				p.pos += idx + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case bytes.HasPrefix(p.src[p.pos:], []byte("/*")):
+			idx := bytes.Index(p.src[p.pos+2:], []byte("*/"))
+			if idx < 0 {
+				p.fail("unterminated comment")
+				return
+			}
+			p.pos += idx + 4
+		default:
+			return
+		}
+	}
+}
+
+// parseValue parses any JSON value at the current position
+func (p *jsoncParser) parseValue() *jsoncNode {
+	if p.pos >= len(p.src) {
+		p.fail("unexpected end of input")
+		return nil
+	}
+
+	switch p.src[p.pos] {
+	case '{':
+		return p.parseObject()
+	case '[':
+		return p.parseArray()
+	case '"':
+		start := p.pos
+		p.parseString()
+		return &jsoncNode{start: start, end: p.pos}
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && !strings.ContainsRune(",:}] \t\r\n/", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		if !json.Valid(p.src[start:p.pos]) {
+			p.pos = start
+			p.fail("invalid value")
+			return nil
+		}
+		return &jsoncNode{start: start, end: p.pos}
+	}
+}
+
+// parseString parses a quoted string and returns its decoded value
+func (p *jsoncParser) parseString() string {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			var value string
+			if err := json.Unmarshal(p.src[start:p.pos], &value); err != nil {
+				p.pos = start
+				p.fail("invalid string")
+			}
+			return value
+		case '\n':
+			p.fail("unterminated string")
+			return ""
+		default:
+			p.pos++
+		}
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+// parseObject parses an object, allowing a trailing comma before the closing brace
+func (p *jsoncParser) parseObject() *jsoncNode {
+	node := &jsoncNode{kind: '{', start: p.pos}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos >= len(p.src) {
+			p.fail("unterminated object")
+			return nil
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			node.end = p.pos
+			return node
+		}
+		if p.src[p.pos] != '"' {
+			p.fail("expected string key")
+			return nil
+		}
+
+		member := &jsoncMember{keyStart: p.pos}
+		member.key = p.parseString()
+		if p.err != nil {
+			return nil
+		}
+		// Edits and decoding would otherwise disagree on which of the values counts
+		if existing, _ := node.member(member.key); existing != nil {
+			p.pos = member.keyStart
+			p.fail("duplicate key %q", member.key)
+			return nil
+		}
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			p.fail("expected ':' after key %q", member.key)
+			return nil
+		}
+		p.pos++
+		p.skipSpace()
+
+		member.value = p.parseValue()
+		if p.err != nil {
+			return nil
+		}
+		node.members = append(node.members, member)
+
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			continue
+		}
+		p.fail("expected ',' or '}' after object member")
+		return nil
+	}
+}
+
+// parseArray parses an array, allowing a trailing comma before the closing bracket
+func (p *jsoncParser) parseArray() *jsoncNode {
+	node := &jsoncNode{kind: '[', start: p.pos}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos >= len(p.src) {
+			p.fail("unterminated array")
+			return nil
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			node.end = p.pos
+			return node
+		}
+
+		item := p.parseValue()
+		if p.err != nil {
+			return nil
+		}
+		node.items = append(node.items, item)
+
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			continue
+		}
+		p.fail("expected ',' or ']' after array element")
+		return nil
+	}
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyCompletion is a configuration path offered by shell completion
+type KeyCompletion struct {
+	Path string
+	// Type describes the value, e.g. "string", "bool" or "object"
+	Type string
+	// Leaf is set for paths holding a single value rather than an object
+	Leaf bool
+}
+
+// ResolveKey checks a dotted configuration path against the Config struct and returns
+// the type of the value stored there
+func ResolveKey(key string) (reflect.Type, []string, error) {
+	if key == "" {
+		return nil, nil, fmt.Errorf("configuration key cannot be empty")
+	}
+
+	path := strings.Split(key, ".")
+	t := reflect.TypeOf(Config{})
+	for i, part := range path {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if part == "" {
+			return nil, nil, fmt.Errorf("invalid configuration key '%s'", key)
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonFields(t)[part]
+			if !ok {
+				if suggestion := suggestKey(strings.Join(path[:i+1], ".")); suggestion != "" {
+					return nil, nil, fmt.Errorf("unknown configuration key '%s' (did you mean '%s'?)", strings.Join(path[:i+1], "."), suggestion)
+				}
+				return nil, nil, fmt.Errorf("unknown configuration key '%s'", strings.Join(path[:i+1], "."))
+			}
+			t = field
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("'%s' is a %s and has no keys", strings.Join(path[:i], "."), describeType(t))
+		}
+	}
+
+	return t, path, nil
+}
+
+// ParseValue converts a command line value into the generic representation of type t.
+// Lists are comma-separated or JSON arrays; objects must be given as JSON.
+func ParseValue(t reflect.Type, value string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool '%s': use true or false", value)
+		}
+		return parsed, nil
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", value)
+		}
+		return parsed, nil
+	case reflect.Slice:
+		if !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []interface{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			return items, nil
+		}
+	}
+
+	// Objects and JSON lists are decoded strictly into the target type
+	target := reflect.New(t)
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid %s value: %w", describeType(t), err)
+	}
+
+	// Round-trip through JSON so that only set fields are stored
+	var generic interface{}
+	if err := json.Unmarshal([]byte(value), &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// GetValue returns the effective value at a configuration key and the layer that supplied it
+func (m *Manager) GetValue(key string) (interface{}, string, error) {
+	if _, _, err := ResolveKey(key); err != nil {
+		return nil, "", err
+	}
+
+	config, sources, err := m.LoadWithSources()
+	if err != nil {
+		return nil, "", err
+	}
+
+	generic, err := toGeneric(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	value, ok := lookupPath(generic.(map[string]interface{}), strings.Split(key, "."))
+	if !ok {
+		return nil, "", fmt.Errorf("'%s' is not set", key)
+	}
+	return value, sources.Lookup(key), nil
+}
+
+// SetValue type-checks value against the key and stores it in the user layer.
+// The change is rejected if it would introduce validation errors.
+func (m *Manager) SetValue(key, value string) error {
+	t, path, err := ResolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := ParseValue(t, value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	return m.updateUserLayerChecked(func(doc document) error {
+		return doc.Set(path, parsed)
+	})
+}
+
+// UnsetValue removes a key from the user layer, so lower layers or defaults apply again
+func (m *Manager) UnsetValue(key string) error {
+	_, path, err := ResolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	return m.updateUserLayerChecked(func(doc document) error {
+		raw, err := doc.Decode()
+		if err != nil {
+			return err
+		}
+		if _, ok := lookupPath(raw, path); !ok {
+			layers, _ := m.definingLayers(path...)
+			if len(layers) > 0 {
+				return fmt.Errorf("'%s' is not set in the user config (it comes from the %s layer)", key, strings.Join(layers, ", "))
+			}
+			return fmt.Errorf("'%s' is not set", key)
+		}
+		return doc.Delete(path)
+	})
+}
+
+// updateUserLayerChecked applies a modification to the user layer after checking that the
+// resulting configuration parses and has no validation errors the current one does not have
+func (m *Manager) updateUserLayerChecked(update func(doc document) error) error {
+	layers := m.Layers()
+	current, _, err := mergeLayers(layers)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]bool)
+	for _, diagnostic := range m.checkEnvironments(current, func(string) string { return "" }, ValidationOptions{}) {
+		before[diagnostic.Code+" "+diagnostic.Path] = true
+	}
+
+	return m.updateUserLayer(func(doc document) error {
+		if err := update(doc); err != nil {
+			return err
+		}
+
+		user, err := doc.Decode()
+		if err != nil {
+			return err
+		}
+		candidate, _, err := mergeLayersWithUser(layers, user)
+		if err != nil {
+			return err
+		}
+
+		var problems []string
+		for _, diagnostic := range m.checkEnvironments(candidate, func(string) string { return "" }, ValidationOptions{}) {
+			if diagnostic.Severity == SeverityError && !before[diagnostic.Code+" "+diagnostic.Path] {
+				problems = append(problems, diagnostic.Message)
+			}
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("the change would make the configuration invalid: %s", strings.Join(problems, "; "))
+		}
+		return nil
+	})
+}
+
+// CompleteKeys returns the configuration keys one level below the given prefix.
+// Map keys such as environment names are taken from the effective configuration.
+func (m *Manager) CompleteKeys(prefix string) []KeyCompletion {
+	parent := ""
+	if i := strings.LastIndex(prefix, "."); i >= 0 {
+		parent = prefix[:i]
+	}
+
+	t := reflect.TypeOf(Config{})
+	var path []string
+	if parent != "" {
+		var err error
+		if t, path, err = ResolveKey(parent); err != nil {
+			return nil
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	children := make(map[string]reflect.Type)
+	switch t.Kind() {
+	case reflect.Struct:
+		children = jsonFields(t)
+	case reflect.Map:
+		config, err := m.Load()
+		if err != nil {
+			return nil
+		}
+		generic, err := toGeneric(config)
+		if err != nil {
+			return nil
+		}
+		value, _ := lookupPath(generic.(map[string]interface{}), path)
+		if object, ok := value.(map[string]interface{}); ok {
+			for key := range object {
+				children[key] = t.Elem()
+			}
+		}
+	default:
+		return nil
+	}
+
+	var completions []KeyCompletion
+	for name, childType := range children {
+		key := joinPath(parent, name)
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		completions = append(completions, KeyCompletion{
+			Path: key,
+			Type: describeType(childType),
+			Leaf: isLeafType(childType),
+		})
+	}
+
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].Path < completions[j].Path
+	})
+	return completions
+}
+
+// describeType names a configuration value type for users
+func describeType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int64:
+		return "number"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+// isLeafType reports whether values of type t have no nested keys
+func isLeafType(t reflect.Type) bool {
+	return describeType(t) != "object"
+}
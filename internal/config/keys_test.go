@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newKeysTestManager creates a manager with a user config holding one environment
+func newKeysTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{
+  // Personal settings
+  "schema_version": 1,
+  "environments": {
+    "prod": {"proxy": "teleport.prod.company.com:443", "user": "alice"}
+  },
+  "auto_login": true
+}
+`), 0600)
+
+	return &Manager{configPath: configPath}
+}
+
+func TestResolveKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		wantType string
+		wantErr  string
+	}{
+		{"auto_login", "bool", ""},
+		{"environments.prod", "object", ""},
+		{"environments.prod.user", "string", ""},
+		{"environments.prod.login.roles", "list", ""},
+		{"team.url", "string", ""},
+		{"auto_logn", "", "did you mean 'auto_login'"},
+		{"environments.prod.proxy.port", "", "has no keys"},
+		{"environments..user", "", "invalid configuration key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			keyType, _, err := ResolveKey(tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := describeType(keyType); got != tt.wantType {
+				t.Errorf("Expected type %s, got %s", tt.wantType, got)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	boolType, _, _ := ResolveKey("auto_login")
+	if value, err := ParseValue(boolType, "false"); err != nil || value != false {
+		t.Errorf("Expected false, got %v (%v)", value, err)
+	}
+	if _, err := ParseValue(boolType, "maybe"); err == nil {
+		t.Error("Expected error for an invalid bool")
+	}
+
+	listType, _, _ := ResolveKey("environments.prod.login.roles")
+	for _, input := range []string{"dba, auditor", `["dba", "auditor"]`} {
+		value, err := ParseValue(listType, input)
+		if err != nil || !reflect.DeepEqual(value, []interface{}{"dba", "auditor"}) {
+			t.Errorf("Expected list from '%s', got %v (%v)", input, value, err)
+		}
+	}
+
+	envType, _, _ := ResolveKey("environments.dev")
+	if _, err := ParseValue(envType, `{"proxy": "dev:443", "usr": "bob"}`); err == nil {
+		t.Error("Expected error for an unknown field in an object")
+	}
+}
+
+func TestManager_SetValue(t *testing.T) {
+	manager := newKeysTestManager(t)
+
+	if err := manager.SetValue("auto_login", "false"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := manager.SetValue("environments.prod.login.ttl", "4h"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	value, layer, err := manager.GetValue("environments.prod.login.ttl")
+	if err != nil || value != "4h" || layer != LayerUser {
+		t.Errorf("Expected 4h from the user layer, got %v from %s (%v)", value, layer, err)
+	}
+
+	data, _ := os.ReadFile(manager.configPath)
+	if !strings.Contains(string(data), "// Personal settings") {
+		t.Error("Expected comments to be preserved")
+	}
+
+	// Changes that make the configuration invalid are rejected and not written
+	if err := manager.SetValue("environments.prod.proxy", "teleport:port"); err == nil {
+		t.Error("Expected error for an invalid proxy")
+	}
+	if err := manager.SetValue("environments.prod.login.ttl", "forever"); err == nil {
+		t.Error("Expected error for an invalid ttl")
+	}
+	if err := manager.SetValue("environments.staging.user", "bob"); err == nil {
+		t.Error("Expected error for an environment without proxy")
+	}
+	if value, _, _ := manager.GetValue("environments.prod.proxy"); value != "teleport.prod.company.com:443" {
+		t.Errorf("Expected the proxy to be unchanged, got %v", value)
+	}
+}
+
+func TestManager_UnsetValue(t *testing.T) {
+	manager := newKeysTestManager(t)
+
+	if err := manager.UnsetValue("environments.prod.user"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := manager.GetValue("environments.prod.user"); err == nil {
+		t.Error("Expected the user to be unset")
+	}
+
+	if err := manager.UnsetValue("environments.prod.user"); err == nil {
+		t.Error("Expected error when unsetting a key that is not set")
+	}
+	if err := manager.UnsetValue("environments.prod.proxy"); err == nil {
+		t.Error("Expected error when unsetting a required value")
+	}
+}
+
+func TestManager_CompleteKeys(t *testing.T) {
+	manager := newKeysTestManager(t)
+
+	paths := func(completions []KeyCompletion) []string {
+		var result []string
+		for _, completion := range completions {
+			result = append(result, completion.Path)
+		}
+		return result
+	}
+
+	if got := paths(manager.CompleteKeys("auto")); !reflect.DeepEqual(got, []string{"auto_login"}) {
+		t.Errorf("Unexpected completions %v", got)
+	}
+	if got := paths(manager.CompleteKeys("environments.")); !reflect.DeepEqual(got, []string{"environments.prod"}) {
+		t.Errorf("Unexpected completions %v", got)
+	}
+
+	completions := manager.CompleteKeys("environments.prod.l")
+	if len(completions) != 1 || completions[0].Path != "environments.prod.login" || completions[0].Leaf {
+		t.Errorf("Unexpected completions %+v", completions)
+	}
+}
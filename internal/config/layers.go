@@ -0,0 +1,325 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Layer names in increasing order of precedence
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerTeam    = "team"
+	LayerUser    = "user"
+	LayerProject = "project"
+)
+
+// SystemConfigDir holds the machine-wide baseline configuration shipped by platform teams
+const SystemConfigDir = "/etc/tkube"
+
+// DefaultSystemConfigPath is the default system configuration file
+const DefaultSystemConfigPath = SystemConfigDir + "/config.json"
+
+// ProjectConfigName is the file looked up in the working directory and its parents.
+// .tkube.yaml, .tkube.yml and .tkube.jsonc are recognised as well.
+const ProjectConfigName = ProjectConfigBase + ".json"
+
+// ProjectConfigBase is the project config file name without its extension
+const ProjectConfigBase = ".tkube"
+
+// Layer describes a single configuration file contributing to the merged configuration
+type Layer struct {
+	Name   string
+	Path   string
+	Exists bool
+}
+
+// Sources maps dotted configuration paths (e.g. "environments.prod.proxy") to the layer that supplied them
+type Sources map[string]string
+
+// Lookup returns the layer that supplied a path, or LayerDefault if no layer set it
+func (s Sources) Lookup(path string) string {
+	if layer, ok := s[path]; ok {
+		return layer
+	}
+	return LayerDefault
+}
+
+// Layers returns the configuration layers in increasing order of precedence
+func (m *Manager) Layers() []Layer {
+	var layers []Layer
+
+	if m.systemPath != "" {
+		layers = append(layers, newLayer(LayerSystem, m.systemPath))
+	}
+
+	if team := m.teamLayer(); team != nil {
+		layers = append(layers, *team)
+	}
+
+	layers = append(layers, newLayer(LayerUser, m.configPath))
+
+	if projectPath := m.findProjectConfig(); projectPath != "" {
+		layers = append(layers, newLayer(LayerProject, projectPath))
+	}
+
+	return layers
+}
+
+// newLayer creates a layer and records whether its file is present
+func newLayer(name, path string) Layer {
+	_, err := os.Stat(path)
+	return Layer{Name: name, Path: path, Exists: err == nil}
+}
+
+// findProjectConfig walks up from the working directory looking for a project config file
+func (m *Manager) findProjectConfig() string {
+	if m.workDir == "" {
+		return ""
+	}
+
+	dir := m.workDir
+	for {
+		candidate := findConfigFile(dir, ProjectConfigBase)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readLayer reads a layer file into a generic map
+func readLayer(layer Layer) (map[string]interface{}, error) {
+	_, raw, err := readLayerDocument(layer)
+	return raw, err
+}
+
+// readLayerDocument reads a layer file, returning the parsed document along with its contents
+func readLayerDocument(layer Layer) (document, map[string]interface{}, error) {
+	doc, err := readDocument(layer.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("failed to read %s config file %s: %w", layer.Name, layer.Path, err)
+		}
+		return nil, nil, fmt.Errorf("failed to parse %s config file %s: %w", layer.Name, layer.Path, err)
+	}
+
+	original, err := doc.Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s config file %s: %w", layer.Name, layer.Path, err)
+	}
+
+	// Layers tkube does not own are upgraded in memory only
+	if _, _, err := migrateDocument(doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to migrate %s config file %s: %w", layer.Name, layer.Path, err)
+	}
+
+	raw, err := doc.Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s config file %s: %w", layer.Name, layer.Path, err)
+	}
+	// The version the migration records is not a value the file sets, so it is not attributed to the layer
+	if _, ok := original["schema_version"]; !ok {
+		delete(raw, "schema_version")
+	}
+
+	return doc, raw, nil
+}
+
+// checkKnownKeys returns an error pointing at the first key of any layer that is not part of the configuration
+func checkKnownKeys(layers []Layer) error {
+	for _, layer := range layers {
+		if !layer.Exists {
+			continue
+		}
+
+		doc, raw, err := readLayerDocument(layer)
+		if err != nil {
+			return err
+		}
+
+		unknown := unknownKeys(raw, reflect.TypeOf(Config{}), "")
+		if len(unknown) == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("unknown key '%s' in %s config file %s", unknown[0], layer.Name, layer.Path)
+		if line, column, ok := doc.Position(strings.Split(unknown[0], ".")); ok {
+			message += fmt.Sprintf(" at line %d, column %d", line, column)
+		}
+		if suggestion := suggestKey(unknown[0]); suggestion != "" {
+			message += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+		}
+		if len(unknown) > 1 {
+			message += fmt.Sprintf(" and %d more; run 'tkube config validate' to list them", len(unknown)-1)
+		}
+		return fmt.Errorf("%s", message)
+	}
+	return nil
+}
+
+// commandKeys are the options that run commands on the user's machine. Team and project files
+// are written by other people, so these keys are only honoured from the system and user layers.
+var commandKeys = [][]string{{"agent", "notify_command"}, {"agent", "hook"}}
+
+// allowsCommands reports whether a layer may set the commandKeys
+func allowsCommands(layer string) bool {
+	return layer == LayerSystem || layer == LayerUser
+}
+
+// ignoredCommandKeys returns the commandKeys a layer sets but is not allowed to
+func ignoredCommandKeys(layer string, raw map[string]interface{}) []string {
+	if allowsCommands(layer) {
+		return nil
+	}
+
+	var keys []string
+	for _, path := range commandKeys {
+		if _, ok := lookupPath(raw, path); ok {
+			keys = append(keys, strings.Join(path, "."))
+		}
+	}
+	return keys
+}
+
+// ignoredLayerCommands returns a description of every commandKeys entry the layers set but may not
+func ignoredLayerCommands(layers []Layer) []string {
+	var ignored []string
+	for _, layer := range layers {
+		if !layer.Exists || allowsCommands(layer.Name) {
+			continue
+		}
+		raw, err := readLayer(layer)
+		if err != nil {
+			continue
+		}
+		for _, key := range ignoredCommandKeys(layer.Name, raw) {
+			ignored = append(ignored, fmt.Sprintf("'%s' in %s config file %s", key, layer.Name, layer.Path))
+		}
+	}
+	return ignored
+}
+
+// withoutCommandKeys returns raw without the commandKeys, leaving raw itself untouched
+func withoutCommandKeys(raw map[string]interface{}) map[string]interface{} {
+	stripped := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		stripped[key] = value
+	}
+
+	for _, path := range commandKeys {
+		parent, ok := stripped[path[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := parent[path[1]]; !ok {
+			continue
+		}
+		copied := make(map[string]interface{}, len(parent))
+		for key, value := range parent {
+			if key != path[1] {
+				copied[key] = value
+			}
+		}
+		stripped[path[0]] = copied
+	}
+	return stripped
+}
+
+// mergeLayer deep-merges src into dst, recording which layer supplied each leaf value.
+// Objects are merged key by key; any other value (including arrays) replaces the previous one.
+func mergeLayer(dst, src map[string]interface{}, layer, prefix string, sources Sources) {
+	for key, value := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		if srcIsMap {
+			if !dstIsMap {
+				clearSources(sources, path)
+				dstMap = make(map[string]interface{})
+				dst[key] = dstMap
+			}
+			sources[path] = layer
+			mergeLayer(dstMap, srcMap, layer, path, sources)
+			continue
+		}
+
+		clearSources(sources, path)
+		dst[key] = value
+		sources[path] = layer
+	}
+}
+
+// clearSources forgets the recorded sources of a path and everything below it
+func clearSources(sources Sources, path string) {
+	delete(sources, path)
+	for key := range sources {
+		if strings.HasPrefix(key, path+".") {
+			delete(sources, key)
+		}
+	}
+}
+
+// definingLayers returns the names of the layers whose files set the given path
+func (m *Manager) definingLayers(path ...string) ([]string, error) {
+	var names []string
+	for _, layer := range m.Layers() {
+		if !layer.Exists {
+			continue
+		}
+
+		raw, err := readLayer(layer)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := lookupPath(raw, path); ok {
+			names = append(names, layer.Name)
+		}
+	}
+	return names, nil
+}
+
+// lookupPath returns the value stored at path in a generic map
+func lookupPath(raw map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = raw
+	for _, key := range path {
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = node[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// toGeneric converts a typed value into its generic JSON representation
+func toGeneric(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// tryLockFile is a no-op on platforms without flock; writes remain atomic through rename
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile is a no-op on platforms without flock
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestManager_ConcurrentAddEnvironment(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte("{\n  \"environments\": {}\n}\n"), 0644)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate managers mimic separate tkube processes
+			manager := &Manager{configPath: configPath}
+			env := Environment{Proxy: fmt.Sprintf("teleport%d.company.com:443", i)}
+			if err := manager.AddEnvironment(fmt.Sprintf("env%d", i), env); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config, err := (&Manager{configPath: configPath}).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(config.Environments) != workers {
+		t.Errorf("Expected %d environments, got %d", workers, len(config.Environments))
+	}
+}
+
+func TestAtomicWriteFile_KeepsPermissions(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.json")
+	os.WriteFile(path, []byte("{}"), 0600)
+
+	if err := atomicWriteFile(path, []byte(`{"auto_login": true}`), 0644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %o", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Expected no temporary files to remain, found %s", entry.Name())
+		}
+	}
+}
+
+func TestManager_WriteKeepsSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+
+	tempDir := t.TempDir()
+	dotfiles := filepath.Join(tempDir, "dotfiles", "tkube.json")
+	os.MkdirAll(filepath.Dir(dotfiles), 0755)
+	os.WriteFile(dotfiles, []byte(`{"schema_version": 1, "environments": {}}`), 0644)
+
+	configPath := filepath.Join(tempDir, "home", "config.json")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	if err := os.Symlink("../dotfiles/tkube.json", configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &Manager{configPath: configPath, backupDir: filepath.Join(tempDir, "backups")}
+	if err := manager.AddEnvironment("prod", Environment{Proxy: "teleport.prod.company.com:443"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	info, err := os.Lstat(configPath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Expected the config to stay a symlink, got %v (%v)", info, err)
+	}
+	data, _ := os.ReadFile(dotfiles)
+	if !strings.Contains(string(data), "teleport.prod.company.com:443") {
+		t.Errorf("Expected the change to reach the link target, got %s", data)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginOptions are the tsh login settings of an environment beyond proxy and user
+type LoginOptions struct {
+	// AuthConnector selects the authentication connector, e.g. "okta", "github" or "local"
+	AuthConnector string `json:"auth_connector,omitempty"`
+	// TTL is the requested session length as a duration, e.g. "8h" or "90m"
+	TTL string `json:"ttl,omitempty"`
+	// Roles are requested through an access request at login
+	Roles []string `json:"roles,omitempty"`
+	// RequestReason explains the role request to reviewers
+	RequestReason string `json:"request_reason,omitempty"`
+	// MFAMode selects the second factor, e.g. "auto", "platform", "cross-platform", "otp" or "sso"
+	MFAMode string `json:"mfa_mode,omitempty"`
+}
+
+// loginFlagSupport is the first tsh release supporting a login option
+type loginFlagSupport struct {
+	option  string
+	flag    string
+	version string
+}
+
+// loginFlagVersions lists login options that older tsh releases reject
+var loginFlagVersions = []loginFlagSupport{
+	{option: "roles", flag: "--request-roles", version: "5.0.0"},
+	{option: "request_reason", flag: "--request-reason", version: "5.0.0"},
+	{option: "mfa_mode", flag: "--mfa-mode", version: "10.0.0"},
+}
+
+// mfaModes maps the accepted MFA modes to the first tsh release supporting them
+var mfaModes = map[string]string{
+	"auto":           "10.0.0",
+	"cross-platform": "10.0.0",
+	"platform":       "10.0.0",
+	"otp":            "10.0.0",
+	"sso":            "17.0.0",
+}
+
+// IsEmpty reports whether no login option is set
+func (o *LoginOptions) IsEmpty() bool {
+	return o == nil || (o.AuthConnector == "" && o.TTL == "" && len(o.Roles) == 0 && o.RequestReason == "" && o.MFAMode == "")
+}
+
+// Validate checks the login options, and that tshVersion supports them unless it is empty
+func (o *LoginOptions) Validate(tshVersion string) error {
+	if o.IsEmpty() {
+		return nil
+	}
+
+	if strings.ContainsAny(o.AuthConnector, " \t") {
+		return fmt.Errorf("invalid auth connector '%s'", o.AuthConnector)
+	}
+	if o.TTL != "" {
+		if _, err := ttlMinutes(o.TTL); err != nil {
+			return err
+		}
+	}
+	for _, role := range o.Roles {
+		if role == "" || strings.ContainsAny(role, ", \t") {
+			return fmt.Errorf("invalid role '%s'", role)
+		}
+	}
+	if o.RequestReason != "" && len(o.Roles) == 0 {
+		return fmt.Errorf("request_reason needs roles to request")
+	}
+	if o.MFAMode != "" {
+		if _, ok := mfaModes[o.MFAMode]; !ok {
+			return fmt.Errorf("invalid mfa_mode '%s': use auto, cross-platform, platform, otp or sso", o.MFAMode)
+		}
+	}
+
+	if tshVersion == "" {
+		return nil
+	}
+	for _, support := range loginFlagVersions {
+		if o.isSet(support.option) && CompareVersions(tshVersion, support.version) < 0 {
+			return fmt.Errorf("%s (%s) needs tsh %s or newer, but tsh %s is configured", support.option, support.flag, support.version, tshVersion)
+		}
+	}
+	if o.MFAMode != "" && CompareVersions(tshVersion, mfaModes[o.MFAMode]) < 0 {
+		return fmt.Errorf("mfa_mode '%s' needs tsh %s or newer, but tsh %s is configured", o.MFAMode, mfaModes[o.MFAMode], tshVersion)
+	}
+	return nil
+}
+
+// Args translates the login options into tsh login flags
+func (o *LoginOptions) Args() []string {
+	if o.IsEmpty() {
+		return nil
+	}
+
+	var args []string
+	if o.AuthConnector != "" {
+		args = append(args, "--auth="+o.AuthConnector)
+	}
+	if minutes, err := ttlMinutes(o.TTL); err == nil {
+		args = append(args, "--ttl="+strconv.Itoa(minutes))
+	}
+	if len(o.Roles) > 0 {
+		args = append(args, "--request-roles="+strings.Join(o.Roles, ","))
+	}
+	if o.RequestReason != "" {
+		args = append(args, "--request-reason="+o.RequestReason)
+	}
+	if o.MFAMode != "" {
+		args = append(args, "--mfa-mode="+o.MFAMode)
+	}
+	return args
+}
+
+// isSet reports whether the option with the given JSON name has a value
+func (o *LoginOptions) isSet(option string) bool {
+	switch option {
+	case "roles":
+		return len(o.Roles) > 0
+	case "request_reason":
+		return o.RequestReason != ""
+	case "mfa_mode":
+		return o.MFAMode != ""
+	}
+	return false
+}
+
+// ttlMinutes converts a TTL duration into the whole minutes tsh expects
+func ttlMinutes(ttl string) (int, error) {
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl '%s': use a duration such as 8h or 90m", ttl)
+	}
+	if duration < time.Minute || duration%time.Minute != 0 {
+		return 0, fmt.Errorf("invalid ttl '%s': must be a whole number of minutes", ttl)
+	}
+	return int(duration / time.Minute), nil
+}
+
+// CompareVersions compares two MAJOR.MINOR.PATCH versions, returning -1, 0 or 1
+func CompareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < 3; i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(partsB[i])
+		}
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
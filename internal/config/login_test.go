@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoginOptions_Args(t *testing.T) {
+	opts := &LoginOptions{
+		AuthConnector: "okta",
+		TTL:           "2h30m",
+		Roles:         []string{"prod-admin", "dba"},
+		RequestReason: "incident 42",
+		MFAMode:       "platform",
+	}
+
+	want := []string{"--auth=okta", "--ttl=150", "--request-roles=prod-admin,dba", "--request-reason=incident 42", "--mfa-mode=platform"}
+	if got := opts.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	var empty *LoginOptions
+	if args := empty.Args(); args != nil {
+		t.Errorf("Expected no flags without login options, got %v", args)
+	}
+}
+
+func TestLoginOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       LoginOptions
+		tshVersion string
+		wantErr    string
+	}{
+		{"valid", LoginOptions{AuthConnector: "github", TTL: "8h", MFAMode: "otp"}, "17.7.1", ""},
+		{"no version check", LoginOptions{MFAMode: "sso"}, "", ""},
+		{"bad ttl", LoginOptions{TTL: "eight hours"}, "", "invalid ttl"},
+		{"partial minute", LoginOptions{TTL: "90s"}, "", "whole number of minutes"},
+		{"bad role", LoginOptions{Roles: []string{"a,b"}}, "", "invalid role"},
+		{"reason without roles", LoginOptions{RequestReason: "why"}, "", "needs roles"},
+		{"bad mfa mode", LoginOptions{MFAMode: "yubikey"}, "", "invalid mfa_mode"},
+		{"old tsh for mfa", LoginOptions{MFAMode: "auto"}, "9.3.4", "needs tsh 10.0.0"},
+		{"old tsh for sso", LoginOptions{MFAMode: "sso"}, "16.4.0", "needs tsh 17.0.0"},
+		{"old tsh for roles", LoginOptions{Roles: []string{"dba"}}, "4.4.0", "needs tsh 5.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate(tt.tshVersion)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"17.7.1", "17.7.1", 0},
+		{"9.3.4", "10.0.0", -1},
+		{"v17.0.0", "16.4.10", 1},
+		{"16.4.2", "16.4.10", -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
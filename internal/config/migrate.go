@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CurrentSchemaVersion is the configuration schema version written by this release
+const CurrentSchemaVersion = 1
+
+// migration upgrades a configuration document from one schema version to the next
+type migration struct {
+	from        int
+	description string
+	apply       func(doc document, raw map[string]interface{}) error
+}
+
+// migrations is the ordered chain applied to configuration files with an older schema version
+var migrations = []migration{
+	{
+		from:        0,
+		description: "normalize tsh_version values with a leading 'v' and record schema_version",
+		apply: func(doc document, raw map[string]interface{}) error {
+			envs, _ := raw["environments"].(map[string]interface{})
+			for name, value := range envs {
+				env, _ := value.(map[string]interface{})
+				version, ok := env["tsh_version"].(string)
+				if ok && strings.HasPrefix(version, "v") {
+					if err := doc.Set([]string{"environments", name, "tsh_version"}, strings.TrimPrefix(version, "v")); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// MigrationPlan describes the migrations pending for the user configuration file
+type MigrationPlan struct {
+	Path   string
+	From   int
+	To     int
+	Steps  []string
+	Before []byte
+	After  []byte
+}
+
+// NeedsMigration reports whether the plan changes anything
+func (p *MigrationPlan) NeedsMigration() bool {
+	return p.From < p.To
+}
+
+// Diff returns a unified diff of the changes the plan applies
+func (p *MigrationPlan) Diff() string {
+	return UnifiedDiff(p.Path, p.Path+" (migrated)", p.Before, p.After)
+}
+
+// schemaVersionOf returns the schema version recorded in a decoded document
+func schemaVersionOf(raw map[string]interface{}) (int, error) {
+	switch version := raw["schema_version"].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(version), nil
+	case int:
+		return version, nil
+	default:
+		return 0, fmt.Errorf("invalid schema_version %v", version)
+	}
+}
+
+// migrateDocument applies all pending migrations to doc and returns the original version and applied steps
+func migrateDocument(doc document) (int, []string, error) {
+	raw, err := doc.Decode()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	from, err := schemaVersionOf(raw)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if from > CurrentSchemaVersion {
+		return from, nil, fmt.Errorf("config schema version %d is newer than the supported version %d; please upgrade tkube", from, CurrentSchemaVersion)
+	}
+
+	var steps []string
+	for _, step := range migrations {
+		if step.from < from {
+			continue
+		}
+
+		if err := step.apply(doc, raw); err != nil {
+			return from, nil, fmt.Errorf("migration from schema version %d failed: %w", step.from, err)
+		}
+		if err := doc.Set([]string{"schema_version"}, step.from+1); err != nil {
+			return from, nil, err
+		}
+		steps = append(steps, fmt.Sprintf("v%d → v%d: %s", step.from, step.from+1, step.description))
+
+		if raw, err = doc.Decode(); err != nil {
+			return from, nil, err
+		}
+	}
+
+	return from, steps, nil
+}
+
+// PlanMigration computes the migrations pending for the user configuration without writing anything
+func (m *Manager) PlanMigration() (*MigrationPlan, error) {
+	plan := &MigrationPlan{Path: m.configPath, To: CurrentSchemaVersion}
+
+	before, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		plan.From = CurrentSchemaVersion
+		return plan, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	doc, err := parseDocument(m.configPath, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user config file %s: %w", m.configPath, err)
+	}
+
+	from, steps, err := migrateDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := doc.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	plan.From = from
+	plan.Steps = steps
+	plan.Before = before
+	plan.After = after
+	return plan, nil
+}
+
+// Migrate applies pending migrations to the user configuration, backing up the old file in the backup directory.
+// It returns the executed plan and the backup path (empty if nothing was migrated).
+func (m *Manager) Migrate() (*MigrationPlan, string, error) {
+	// Most loads find nothing to do, so only take the lock when a migration is pending
+	plan, err := m.PlanMigration()
+	if err != nil || !plan.NeedsMigration() {
+		return plan, "", err
+	}
+
+	var backupPath string
+	err = withFileLock(m.configPath, func() error {
+		var err error
+		plan, err = m.PlanMigration()
+		if err != nil || !plan.NeedsMigration() {
+			return err
+		}
+
+		// The regular backup keeps the old file where 'tkube config restore' finds it
+		backupPath, err = m.writeUserLayerWithReason(plan.After, fmt.Sprintf("schema-v%d", plan.From))
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return plan, backupPath, nil
+}
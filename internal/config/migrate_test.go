@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyConfig = `{
+  "environments": {
+    "prod": {
+      "proxy": "prod.proxy.com:443",
+      "tsh_version": "v16.4.0"
+    }
+  },
+  "auto_login": true
+}
+`
+
+func TestManager_PlanMigration_DryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(legacyConfig), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	plan, err := manager.PlanMigration()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !plan.NeedsMigration() {
+		t.Fatal("Expected legacy config to need migration")
+	}
+	if plan.From != 0 || plan.To != CurrentSchemaVersion {
+		t.Errorf("Expected migration from 0 to %d, got %d to %d", CurrentSchemaVersion, plan.From, plan.To)
+	}
+	if len(plan.Steps) != 1 {
+		t.Errorf("Expected 1 migration step, got %d", len(plan.Steps))
+	}
+
+	diff := plan.Diff()
+	if !strings.Contains(diff, `-      "tsh_version": "v16.4.0"`) || !strings.Contains(diff, `+      "tsh_version": "16.4.0"`) {
+		t.Errorf("Expected diff to show the normalized version, got:\n%s", diff)
+	}
+
+	// Planning must not touch the file
+	data, _ := os.ReadFile(configPath)
+	if string(data) != legacyConfig {
+		t.Error("Expected config file to be unchanged by PlanMigration")
+	}
+}
+
+func TestManager_Load_MigratesUserConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(legacyConfig), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", CurrentSchemaVersion, config.SchemaVersion)
+	}
+	if config.Environments["prod"].TSHVersion != "16.4.0" {
+		t.Errorf("Expected normalized tsh version, got '%s'", config.Environments["prod"].TSHVersion)
+	}
+
+	// A single backup of the original file must be written, restorable like any other
+	backups, _ := manager.ListBackups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+	if !strings.HasSuffix(backups[0].ID, "-schema-v0") {
+		t.Errorf("Expected the backup to be tagged with the migration, got %s", backups[0].ID)
+	}
+	data, _ := os.ReadFile(backups[0].Path)
+	if string(data) != legacyConfig {
+		t.Error("Expected backup to contain the original config")
+	}
+	if stray, _ := filepath.Glob(configPath + ".*.bak"); len(stray) != 0 {
+		t.Errorf("Expected no backups next to the config, got %v", stray)
+	}
+
+	// Migrating again is a no-op
+	plan, err := manager.PlanMigration()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if plan.NeedsMigration() {
+		t.Error("Expected no further migration to be needed")
+	}
+}
+
+func TestManager_Load_NewerSchemaVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{"schema_version": 99, "environments": {}}`), 0644)
+
+	manager := &Manager{configPath: configPath}
+
+	if _, err := manager.Load(); err == nil {
+		t.Error("Expected error for a config written by a newer tkube")
+	}
+}
+
+func TestManager_LoadWithSources_SchemaVersionSource(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "home", "config.json")
+	projectDir := filepath.Join(tempDir, "repo")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.MkdirAll(projectDir, 0755)
+
+	os.WriteFile(configPath, []byte(`{"schema_version": 1, "environments": {}}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(`{"auto_login": false}`), 0644)
+
+	manager := &Manager{configPath: configPath, systemPath: filepath.Join(tempDir, "none.json"), workDir: projectDir}
+	config, sources, err := manager.LoadWithSources()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The project file is migrated in memory, but never set schema_version itself
+	if source := sources.Lookup("schema_version"); source != LayerUser {
+		t.Errorf("Expected schema_version to come from the user layer, got %s", source)
+	}
+	if config.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", CurrentSchemaVersion, config.SchemaVersion)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := []byte("a\nb\nc\n")
+	to := []byte("a\nB\nc\nd\n")
+
+	diff := UnifiedDiff("old", "new", from, to)
+	expected := "--- old\n+++ new\n@@ -1,3 +1,4 @@\n a\n-b\n+B\n c\n+d\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, diff)
+	}
+
+	if UnifiedDiff("old", "new", from, from) != "" {
+		t.Error("Expected empty diff for identical input")
+	}
+}
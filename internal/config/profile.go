@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"tkube/internal/paths"
+)
+
+// Profile describes a named set of environments with its own default user and session store
+type Profile struct {
+	Name string
+	// ConfigPath is the user configuration file of the profile
+	ConfigPath string
+	// Active is set for the profile the current configuration was loaded from
+	Active bool
+	// Environments is the number of environments defined in the profile's own file
+	Environments int
+}
+
+// ActiveProfile returns the name of the profile in use
+func (m *Manager) ActiveProfile() string {
+	if m.profile == "" {
+		return paths.DefaultProfile
+	}
+	return m.profile
+}
+
+// ListProfiles returns the default profile followed by the named profiles in sorted order
+func (m *Manager) ListProfiles() ([]Profile, error) {
+	profiles := []Profile{{
+		Name:       paths.DefaultProfile,
+		ConfigPath: m.profileConfigPath(paths.DefaultProfile),
+		Active:     m.profile == "",
+	}}
+
+	if m.profilesDir == "" {
+		return profiles, nil
+	}
+
+	entries, err := os.ReadDir(m.profilesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && paths.ValidateProfileName(entry.Name()) == nil && entry.Name() != paths.DefaultProfile {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profiles = append(profiles, Profile{
+			Name:       name,
+			ConfigPath: m.profileConfigPath(name),
+			Active:     m.profile == name,
+		})
+	}
+
+	for i := range profiles {
+		profiles[i].Environments = countEnvironments(profiles[i].ConfigPath)
+	}
+	return profiles, nil
+}
+
+// countEnvironments returns the number of environments in a user config file, or 0 if it cannot be read
+func countEnvironments(path string) int {
+	layer := newLayer(LayerUser, path)
+	if !layer.Exists {
+		return 0
+	}
+	raw, err := readLayer(layer)
+	if err != nil {
+		return 0
+	}
+	envs, _ := raw["environments"].(map[string]interface{})
+	return len(envs)
+}
+
+// ProfileExists reports whether a profile can be selected
+func (m *Manager) ProfileExists(name string) bool {
+	if name == paths.DefaultProfile {
+		return true
+	}
+	if m.profilesDir == "" || paths.ValidateProfileName(name) != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(m.profilesDir, name))
+	return err == nil && info.IsDir()
+}
+
+// CreateProfile creates a named profile with no environments.
+// defaultUser is stored as the profile's default Teleport user if it is set.
+func (m *Manager) CreateProfile(name, defaultUser string) error {
+	if err := paths.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if name == paths.DefaultProfile {
+		return fmt.Errorf("profile '%s' always exists", name)
+	}
+	if m.profilesDir == "" {
+		return fmt.Errorf("profiles are not available with this configuration")
+	}
+	if m.ProfileExists(name) {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+
+	if err := os.MkdirAll(filepath.Join(m.profilesDir, name), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	profileManager := &Manager{configPath: m.profileConfigPath(name)}
+	return profileManager.Save(&Config{
+		Schema:        SchemaURL,
+		SchemaVersion: CurrentSchemaVersion,
+		Environments:  map[string]Environment{},
+		AutoLogin:     true,
+		DefaultUser:   defaultUser,
+	})
+}
+
+// UseProfile makes a profile the one used when neither --profile nor TKUBE_PROFILE is given
+func (m *Manager) UseProfile(name string) error {
+	if !m.ProfileExists(name) {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+	if m.profileFile == "" {
+		return fmt.Errorf("profiles are not available with this configuration")
+	}
+
+	if name == paths.DefaultProfile {
+		if err := os.Remove(m.profileFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset profile selection: %w", err)
+		}
+		return nil
+	}
+
+	if err := atomicWriteFile(m.profileFile, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to store profile selection: %w", err)
+	}
+	return nil
+}
+
+// profileConfigPath returns the user configuration file of a profile
+func (m *Manager) profileConfigPath(name string) string {
+	if name == m.ActiveProfile() {
+		return m.configPath
+	}
+	if name == paths.DefaultProfile {
+		return findConfigFile(filepath.Dir(m.profilesDir), "config")
+	}
+	return findConfigFile(filepath.Join(m.profilesDir, name), "config")
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tkube/internal/paths"
+)
+
+// newProfileManager creates a manager for the default profile with its profiles below tempDir
+func newProfileManager(tempDir string) *Manager {
+	return &Manager{
+		configPath:  filepath.Join(tempDir, "config.json"),
+		profilesDir: filepath.Join(tempDir, "profiles"),
+		profileFile: filepath.Join(tempDir, "profile"),
+	}
+}
+
+func TestManager_CreateProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := newProfileManager(tempDir)
+
+	if err := manager.CreateProfile("work", "alice"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	profileManager := &Manager{configPath: filepath.Join(tempDir, "profiles", "work", "config.json")}
+	config, err := profileManager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(config.Environments) != 0 {
+		t.Errorf("Expected a new profile without environments, got %v", config.Environments)
+	}
+	if config.DefaultUser != "alice" {
+		t.Errorf("Expected default user alice, got %q", config.DefaultUser)
+	}
+
+	if err := manager.CreateProfile("work", ""); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an error for an existing profile, got %v", err)
+	}
+	if err := manager.CreateProfile("default", ""); err == nil {
+		t.Error("Expected an error for the default profile")
+	}
+	if err := manager.CreateProfile("../escape", ""); err == nil {
+		t.Error("Expected an error for an invalid profile name")
+	}
+}
+
+func TestManager_ListProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := newProfileManager(tempDir)
+	manager.CreateProfile("personal", "")
+	manager.CreateProfile("customer", "")
+
+	profiles, err := manager.ListProfiles()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var names []string
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	if strings.Join(names, ",") != "default,customer,personal" {
+		t.Errorf("Unexpected profiles %v", names)
+	}
+	if !profiles[0].Active || profiles[1].Active {
+		t.Errorf("Expected only the default profile to be active, got %+v", profiles)
+	}
+	if profiles[1].ConfigPath != filepath.Join(tempDir, "profiles", "customer", "config.json") {
+		t.Errorf("Unexpected config path %s", profiles[1].ConfigPath)
+	}
+}
+
+func TestManager_UseProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := newProfileManager(tempDir)
+	manager.CreateProfile("work", "")
+
+	if err := manager.UseProfile("missing"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+
+	if err := manager.UseProfile("work"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(manager.profileFile)
+	if strings.TrimSpace(string(data)) != "work" {
+		t.Errorf("Expected the selection to be stored, got %q", data)
+	}
+
+	if err := manager.UseProfile("default"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(manager.profileFile); !os.IsNotExist(err) {
+		t.Error("Expected the selection to be removed for the default profile")
+	}
+}
+
+func TestNewManager_MissingProfile(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	for _, name := range []string{paths.EnvHome, paths.EnvConfig, paths.EnvProfile, "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(name, "")
+	}
+	os.MkdirAll(filepath.Join(homeDir, ".tkube"), 0755)
+	os.WriteFile(filepath.Join(homeDir, ".tkube", "profile"), []byte("gone\n"), 0644)
+
+	_, err := NewManager()
+	var notFound *ProfileNotFoundError
+	if !errors.As(err, &notFound) || notFound.Profile != "gone" {
+		t.Fatalf("Expected a ProfileNotFoundError, got %v", err)
+	}
+
+	// The default profile stays usable, e.g. to select another profile
+	manager, err := NewManagerForProfile(paths.DefaultProfile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if manager.ActiveProfile() != paths.DefaultProfile {
+		t.Errorf("Expected the default profile, got %s", manager.ActiveProfile())
+	}
+	if err := manager.UseProfile(paths.DefaultProfile); err != nil {
+		t.Errorf("Expected resetting the selection to work, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRenewBefore is the renewal threshold used when neither the environment nor the configuration sets one
+const DefaultRenewBefore = 15 * time.Minute
+
+// ParseRenewBefore parses a renewal threshold such as "30m" or "1h"; "0" disables proactive renewal
+func ParseRenewBefore(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid renew_before '%s': use a duration such as 15m or 1h, or 0 to disable", value)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("invalid renew_before '%s': must not be negative", value)
+	}
+	return duration, nil
+}
+
+// RenewThreshold returns how long before expiry the session of an environment is renewed on connect.
+// The environment's renew_before takes precedence over the global one; invalid values fall back to the default.
+func (c *Config) RenewThreshold(env string) time.Duration {
+	value := c.RenewBefore
+	if envConfig, ok := c.Environments[env]; ok && envConfig.RenewBefore != "" {
+		value = envConfig.RenewBefore
+	}
+	if value == "" {
+		return DefaultRenewBefore
+	}
+
+	threshold, err := ParseRenewBefore(value)
+	if err != nil {
+		return DefaultRenewBefore
+	}
+	return threshold
+}
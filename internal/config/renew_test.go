@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRenewBefore(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"15m", 15 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"0", 0, false},
+		{"-5m", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		threshold, err := ParseRenewBefore(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRenewBefore(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if threshold != tt.expected {
+			t.Errorf("ParseRenewBefore(%q) = %v, expected %v", tt.value, threshold, tt.expected)
+		}
+	}
+}
+
+func TestConfig_RenewThreshold(t *testing.T) {
+	config := &Config{
+		RenewBefore: "30m",
+		Environments: map[string]Environment{
+			"prod":    {Proxy: "teleport.prod.company.com:443", RenewBefore: "1h"},
+			"test":    {Proxy: "teleport.test.company.com:443"},
+			"dev":     {Proxy: "teleport.dev.company.com:443", RenewBefore: "0"},
+			"invalid": {Proxy: "teleport.invalid.company.com:443", RenewBefore: "soon"},
+		},
+	}
+
+	tests := map[string]time.Duration{
+		"prod":    time.Hour,
+		"test":    30 * time.Minute,
+		"dev":     0,
+		"invalid": DefaultRenewBefore,
+		"missing": 30 * time.Minute,
+	}
+	for env, expected := range tests {
+		if threshold := config.RenewThreshold(env); threshold != expected {
+			t.Errorf("RenewThreshold(%s) = %v, expected %v", env, threshold, expected)
+		}
+	}
+
+	config.RenewBefore = ""
+	if threshold := config.RenewThreshold("test"); threshold != DefaultRenewBefore {
+		t.Errorf("Expected default threshold, got %v", threshold)
+	}
+}
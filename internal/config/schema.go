@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// SchemaURL is where the published JSON Schema of the configuration file is served.
+// It is written to the $schema key of new configuration files so editors can validate and complete them.
+const SchemaURL = "https://raw.githubusercontent.com/lidin10/tkube/main/schema/config.schema.json"
+
+// schemaDialect is the JSON Schema draft the generated schema follows
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaDescriptions documents the configuration keys; "*" stands for any map key
+var schemaDescriptions = map[string]string{
+	"":                                    "tkube configuration",
+	"$schema":                             "JSON Schema of this file, used by editors",
+	"schema_version":                      "Configuration schema version; outdated files are migrated automatically",
+	"environments":                        "Teleport environments by name",
+	"environments.*":                      "A Teleport environment",
+	"environments.*.proxy":                "Teleport proxy address in host:port form",
+	"environments.*.tsh_version":          "tsh version used for this environment, e.g. 17.7.1",
+	"environments.*.user":                 "Teleport user for this environment (default: default_user)",
+	"environments.*.aliases":              "Short names for Teleport cluster names",
+	"environments.*.aliases.*":            "Teleport cluster the alias stands for",
+	"environments.*.tags":                 "Labels matched by --selector, e.g. tier=prod",
+	"environments.*.tags.*":               "Tag value",
+	"environments.*.login":                "Extra tsh login options",
+	"environments.*.login.auth_connector": "Authentication connector, e.g. okta, github or local",
+	"environments.*.login.ttl":            "Requested session length, e.g. 8h or 90m",
+	"environments.*.login.roles":          "Roles requested through an access request at login",
+	"environments.*.login.request_reason": "Reason shown to reviewers of the role request",
+	"environments.*.login.mfa_mode":       "Second factor used at login",
+	"environments.*.renew_before":         "Renewal threshold for this environment (default: renew_before)",
+	"auto_login":                          "Log in automatically when a session is missing",
+	"default_user":                        "Teleport user for environments without their own user",
+	"renew_before":                        "Log in again on connect when less session time is left, e.g. 15m; 0 disables",
+	"merge_kubeconfig":                    "Also add connected clusters to the global kubeconfig (KUBECONFIG or ~/.kube/config)",
+	"agent":                               "Background session agent started with 'tkube agent'",
+	"agent.interval":                      "How often sessions are checked (default: 1m)",
+	"agent.warn_before":                   "Warn this long before a session expires (default: 15m)",
+	"agent.clusters_interval":             "How often cluster lists are refreshed (default: 10m)",
+	"agent.bell":                          "Ring the terminal bell when warning",
+	"agent.notify_command":                "Desktop notification command run with a title and a message, e.g. notify-send",
+	"agent.hook":                          "Shell command run on session events with TKUBE_EVENT, TKUBE_ENV and TKUBE_VALID_UNTIL set",
+	"team":                                "Shared team configuration merged below this file",
+	"team.url":                            "HTTPS URL of the team configuration",
+	"team.path":                           "Team configuration file inside a git checkout",
+	"team.sha256":                         "Expected SHA-256 checksum of the team configuration",
+}
+
+// durationPattern matches Go durations such as 90m or 1h30m
+const durationPattern = `^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+$`
+
+// schemaPatterns constrains string values by their path
+var schemaPatterns = map[string]string{
+	"environments.*.tsh_version":  tshVersionPattern.String(),
+	"environments.*.login.ttl":    durationPattern,
+	"environments.*.renew_before": durationPattern + `|^0$`,
+	"renew_before":                durationPattern + `|^0$`,
+	"agent.interval":              durationPattern,
+	"agent.warn_before":           durationPattern,
+	"agent.clusters_interval":     durationPattern,
+	"team.sha256":                 `^[0-9a-fA-F]{64}$`,
+}
+
+// schemaKeyPatterns constrains the keys of maps by their path
+var schemaKeyPatterns = map[string]string{
+	"environments": environmentNamePattern.String(),
+}
+
+// JSONSchema returns the JSON Schema of the configuration file, generated from the Config type.
+// Every layer is a partial configuration, so no key is required.
+func JSONSchema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = schemaDialect
+	schema["$id"] = SchemaURL
+	schema["title"] = "tkube configuration"
+	return schema
+}
+
+// JSONSchemaBytes returns the JSON Schema of the configuration file as indented JSON
+func JSONSchemaBytes() ([]byte, error) {
+	data, err := json.MarshalIndent(JSONSchema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema builds the schema of a Go type stored at the dotted path
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := make(map[string]interface{})
+	if description, ok := schemaDescriptions[path]; ok {
+		schema["description"] = description
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		properties := make(map[string]interface{}, len(fields))
+		for name, field := range fields {
+			properties[name] = typeSchema(field, joinPath(path, name))
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	case reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = typeSchema(t.Elem(), joinPath(path, "*"))
+		if pattern, ok := schemaKeyPatterns[path]; ok {
+			schema["propertyNames"] = map[string]interface{}{"pattern": pattern}
+		}
+	case reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), joinPath(path, "*"))
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int64:
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	default:
+		schema["type"] = "string"
+		if pattern, ok := schemaPatterns[path]; ok {
+			schema["pattern"] = pattern
+		}
+	}
+
+	if path == "environments.*.login.mfa_mode" {
+		schema["enum"] = sortedModeNames()
+	}
+	if path == "schema_version" {
+		schema["maximum"] = CurrentSchemaVersion
+	}
+	return schema
+}
+
+// sortedModeNames returns the accepted MFA modes in sorted order
+func sortedModeNames() []string {
+	modes := make([]string, 0, len(mfaModes))
+	for mode := range mfaModes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
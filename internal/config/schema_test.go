@@ -0,0 +1,109 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+
+	if schema["$id"] != SchemaURL {
+		t.Errorf("Expected $id %s, got %v", SchemaURL, schema["$id"])
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("Expected unknown top-level keys to be rejected")
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, key := range []string{"$schema", "schema_version", "environments", "auto_login", "default_user", "team"} {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("Expected property %s in the schema", key)
+		}
+	}
+
+	environments := properties["environments"].(map[string]interface{})
+	if names := environments["propertyNames"].(map[string]interface{}); names["pattern"] != environmentNamePattern.String() {
+		t.Errorf("Expected environment names to be constrained, got %v", names)
+	}
+
+	environment := environments["additionalProperties"].(map[string]interface{})
+	envProperties := environment["properties"].(map[string]interface{})
+	if proxy := envProperties["proxy"].(map[string]interface{}); proxy["type"] != "string" || proxy["description"] == nil {
+		t.Errorf("Expected a described string proxy, got %v", proxy)
+	}
+	login := envProperties["login"].(map[string]interface{})
+	roles := login["properties"].(map[string]interface{})["roles"].(map[string]interface{})
+	if roles["type"] != "array" {
+		t.Errorf("Expected roles to be an array, got %v", roles)
+	}
+}
+
+func TestJSONSchema_Published(t *testing.T) {
+	published, err := os.ReadFile(filepath.Join("..", "..", "schema", "config.schema.json"))
+	if err != nil {
+		t.Fatalf("Failed to read published schema: %v", err)
+	}
+
+	generated, err := JSONSchemaBytes()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(published, generated) {
+		t.Error("schema/config.schema.json is outdated; regenerate it with 'tkube config schema > schema/config.schema.json'")
+	}
+}
+
+func TestManager_Load_RejectsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		file     string
+		content  string
+		expected string
+		position string
+	}{
+		{
+			file:     "config.json",
+			content:  "{\n  \"environments\": {\n    \"prod\": {\n      \"proxy\": \"teleport.prod.company.com:443\",\n      \"tsh_vesion\": \"17.7.1\"\n    }\n  }\n}\n",
+			expected: "unknown key 'environments.prod.tsh_vesion' in user config file",
+			position: "at line 5, column 7 (did you mean 'tsh_version'?)",
+		},
+		{
+			file:     "config.yaml",
+			content:  "environments:\n  prod:\n    proxy: teleport.prod.company.com:443\nauto_logn: true\n",
+			expected: "unknown key 'auto_logn' in user config file",
+			position: "at line 4, column 1 (did you mean 'auto_login'?)",
+		},
+	}
+
+	for _, tt := range tests {
+		configPath := filepath.Join(t.TempDir(), tt.file)
+		os.WriteFile(configPath, []byte(tt.content), 0644)
+
+		manager := &Manager{configPath: configPath}
+		_, err := manager.Load()
+		if err == nil {
+			t.Errorf("Expected an error for %s", tt.file)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) || !strings.Contains(err.Error(), tt.position) {
+			t.Errorf("Unexpected error for %s: %v", tt.file, err)
+		}
+	}
+}
+
+func TestManager_Load_AcceptsSchemaKey(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	manager := &Manager{configPath: configPath}
+
+	// The default configuration references the schema
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Schema != SchemaURL {
+		t.Errorf("Expected $schema %s, got %q", SchemaURL, config.Schema)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// selectorOperator is the comparison of a single selector requirement
+type selectorOperator int
+
+const (
+	selectorEquals selectorOperator = iota
+	selectorNotEquals
+	selectorExists
+	selectorNotExists
+)
+
+// selectorRequirement is one comma-separated term of a selector
+type selectorRequirement struct {
+	key      string
+	operator selectorOperator
+	value    string
+}
+
+// Selector matches environments by their tags, using the label selector
+// syntax known from kubectl: "tier=prod,region!=us,critical,!legacy"
+type Selector struct {
+	requirements []selectorRequirement
+}
+
+// ParseSelector parses a comma-separated list of key=value, key!=value, key and !key terms.
+// An empty selector matches every environment.
+func ParseSelector(selector string) (*Selector, error) {
+	parsed := &Selector{}
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var requirement selectorRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement = selectorRequirement{key: parts[0], operator: selectorNotEquals, value: parts[1]}
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			requirement = selectorRequirement{key: parts[0], operator: selectorEquals, value: parts[1]}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			requirement = selectorRequirement{key: parts[0], operator: selectorEquals, value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			requirement = selectorRequirement{key: term[1:], operator: selectorNotExists}
+		default:
+			requirement = selectorRequirement{key: term, operator: selectorExists}
+		}
+
+		requirement.key = strings.TrimSpace(requirement.key)
+		requirement.value = strings.TrimSpace(requirement.value)
+		if requirement.key == "" || strings.ContainsAny(requirement.key, "=! ") {
+			return nil, fmt.Errorf("invalid selector term '%s'", term)
+		}
+
+		parsed.requirements = append(parsed.requirements, requirement)
+	}
+
+	return parsed, nil
+}
+
+// Empty reports whether the selector has no requirements and so matches everything
+func (s *Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether tags satisfy every requirement of the selector
+func (s *Selector) Matches(tags map[string]string) bool {
+	for _, requirement := range s.requirements {
+		value, exists := tags[requirement.key]
+
+		switch requirement.operator {
+		case selectorEquals:
+			if !exists || value != requirement.value {
+				return false
+			}
+		case selectorNotEquals:
+			if exists && value == requirement.value {
+				return false
+			}
+		case selectorExists:
+			if !exists {
+				return false
+			}
+		case selectorNotExists:
+			if exists {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String returns the selector in its textual form
+func (s *Selector) String() string {
+	terms := make([]string, 0, len(s.requirements))
+	for _, requirement := range s.requirements {
+		switch requirement.operator {
+		case selectorEquals:
+			terms = append(terms, requirement.key+"="+requirement.value)
+		case selectorNotEquals:
+			terms = append(terms, requirement.key+"!="+requirement.value)
+		case selectorExists:
+			terms = append(terms, requirement.key)
+		case selectorNotExists:
+			terms = append(terms, "!"+requirement.key)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// SelectEnvironments returns the sorted names of the environments matching the selector
+func (c *Config) SelectEnvironments(selector *Selector) []string {
+	var names []string
+	for name, env := range c.Environments {
+		if selector == nil || selector.Matches(env.Tags) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"tier=prod", "tier=prod", false},
+		{" tier == prod , region=eu ", "tier=prod,region=eu", false},
+		{"region!=us,critical,!legacy", "region!=us,critical,!legacy", false},
+		{"tier=", "tier=", false},
+		{"=prod", "", true},
+		{"!", "", true},
+		{"bad key=x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := ParseSelector(tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for selector '%s'", tt.selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := selector.String(); got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	tags := map[string]string{"tier": "prod", "region": "eu"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"tier=prod", true},
+		{"tier=prod,region=eu", true},
+		{"tier=prod,region=us", false},
+		{"region!=us", true},
+		{"region!=eu", false},
+		{"team!=payments", true},
+		{"tier", true},
+		{"team", false},
+		{"!team", true},
+		{"!tier", false},
+	}
+
+	for _, tt := range tests {
+		selector, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %v", tt.selector, err)
+		}
+		if got := selector.Matches(tags); got != tt.want {
+			t.Errorf("Expected '%s' to match %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+
+	// Environments without tags only match negative requirements
+	selector, _ := ParseSelector("!legacy")
+	if !selector.Matches(nil) {
+		t.Error("Expected '!legacy' to match an environment without tags")
+	}
+}
+
+func TestConfig_SelectEnvironments(t *testing.T) {
+	cfg := &Config{
+		Environments: map[string]Environment{
+			"prod-eu": {Proxy: "eu.prod:443", Tags: map[string]string{"tier": "prod", "region": "eu"}},
+			"prod-us": {Proxy: "us.prod:443", Tags: map[string]string{"tier": "prod", "region": "us"}},
+			"test-eu": {Proxy: "eu.test:443", Tags: map[string]string{"tier": "test", "region": "eu"}},
+			"sandbox": {Proxy: "sandbox:443"},
+		},
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"tier=prod,region=eu", []string{"prod-eu"}},
+		{"region=eu", []string{"prod-eu", "test-eu"}},
+		{"tier!=prod", []string{"sandbox", "test-eu"}},
+		{"", []string{"prod-eu", "prod-us", "sandbox", "test-eu"}},
+		{"team=payments", nil},
+	}
+
+	for _, tt := range tests {
+		selector, _ := ParseSelector(tt.selector)
+		if got := cfg.SelectEnvironments(selector); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Selector '%s': expected %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+}
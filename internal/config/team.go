@@ -0,0 +1,475 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TeamSource references a shared team configuration that is merged below the user's own settings.
+// Exactly one of URL and Path is set.
+type TeamSource struct {
+	// URL serves the team configuration over HTTPS
+	URL string `json:"url,omitempty"`
+	// Path is a file inside a git checkout, which is pulled on sync
+	Path string `json:"path,omitempty"`
+	// SHA256 pins the expected checksum of the team configuration
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Location returns the URL or path the team configuration is read from
+func (t *TeamSource) Location() string {
+	if t.URL != "" {
+		return t.URL
+	}
+	return t.Path
+}
+
+// maxTeamConfigSize limits the size of a downloaded team configuration
+const maxTeamConfigSize = 1 << 20
+
+// teamRefreshInterval is how old the cached team configuration may get before 'tkube login' refreshes it
+const teamRefreshInterval = 24 * time.Hour
+
+// teamCacheMeta records where the cached team configuration came from
+type teamCacheMeta struct {
+	Source    string    `json:"source"`
+	File      string    `json:"file"`
+	ETag      string    `json:"etag,omitempty"`
+	SHA256    string    `json:"sha256"`
+	Revision  string    `json:"revision,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// TeamChange is a value the team configuration added, changed or removed
+type TeamChange struct {
+	Path   string
+	Before string
+	After  string
+	// Overridden is set if the user configuration sets the same value, so the change has no effect
+	Overridden bool
+}
+
+// TeamSyncResult describes the outcome of refreshing the team configuration
+type TeamSyncResult struct {
+	Source      string
+	Path        string
+	Revision    string
+	NotModified bool
+	Changes     []TeamChange
+	Warnings    []string
+}
+
+// TeamSource returns the team configuration referenced by the system or user configuration, or nil.
+// Project configurations cannot subscribe to a team configuration.
+func (m *Manager) TeamSource() (*TeamSource, error) {
+	var source *TeamSource
+	for _, layer := range []Layer{newLayer(LayerSystem, m.systemPath), newLayer(LayerUser, m.configPath)} {
+		if layer.Path == "" || !layer.Exists {
+			continue
+		}
+
+		raw, err := readLayer(layer)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := raw["team"]
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var layerSource TeamSource
+		if err := json.Unmarshal(data, &layerSource); err != nil {
+			return nil, fmt.Errorf("invalid team configuration reference in %s: %w", layer.Path, err)
+		}
+		source = &layerSource
+	}
+
+	if source == nil || source.Location() == "" {
+		return nil, nil
+	}
+	if source.URL != "" && source.Path != "" {
+		return nil, fmt.Errorf("team configuration must set either url or path, not both")
+	}
+	return source, nil
+}
+
+// teamLayer returns the layer of the cached team configuration, or nil if none is configured
+func (m *Manager) teamLayer() *Layer {
+	source, err := m.TeamSource()
+	if err != nil || source == nil {
+		return nil
+	}
+
+	meta, err := m.readTeamMeta()
+	if err != nil || meta.Source != source.Location() {
+		return &Layer{Name: LayerTeam, Path: source.Location(), Exists: false}
+	}
+
+	layer := newLayer(LayerTeam, filepath.Join(m.teamDir, meta.File))
+	return &layer
+}
+
+// TeamCached reports whether the configured team configuration has been fetched; true if none is configured
+func (m *Manager) TeamCached() bool {
+	layer := m.teamLayer()
+	return layer == nil || layer.Exists
+}
+
+// RefreshTeamConfig syncs the team configuration if it has not been fetched yet or the cached copy
+// is older than teamRefreshInterval. It returns nil without syncing if the cache is fresh or no
+// team configuration is set. Loading the configuration never refreshes it, so only commands that
+// go online anyway call this.
+func (m *Manager) RefreshTeamConfig() (*TeamSyncResult, error) {
+	source, err := m.TeamSource()
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	if meta, err := m.readTeamMeta(); err == nil && meta.Source == source.Location() && m.TeamCached() &&
+		time.Since(meta.FetchedAt) < teamRefreshInterval {
+		return nil, nil
+	}
+	return m.SyncTeamConfig()
+}
+
+// SyncTeamConfig fetches the team configuration, verifies it and replaces the cached copy.
+// HTTPS sources are requested with the cached ETag, git checkouts are pulled first.
+func (m *Manager) SyncTeamConfig() (*TeamSyncResult, error) {
+	source, err := m.TeamSource()
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("no team configuration is set (add team.url or team.path to %s)", m.configPath)
+	}
+
+	metaPath := filepath.Join(m.teamDir, "meta.json")
+	if err := os.MkdirAll(m.teamDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create team config cache: %w", err)
+	}
+
+	var result *TeamSyncResult
+	err = withFileLock(metaPath, func() error {
+		result, err = m.syncTeamConfig(source)
+		return err
+	})
+	return result, err
+}
+
+// syncTeamConfig performs the sync; callers must hold the team cache lock
+func (m *Manager) syncTeamConfig(source *TeamSource) (*TeamSyncResult, error) {
+	result := &TeamSyncResult{Source: source.Location()}
+
+	// The previous copy only counts if it came from the same source
+	meta, _ := m.readTeamMeta()
+	if meta != nil && meta.Source != source.Location() {
+		meta = nil
+	}
+	var previous []byte
+	if meta != nil {
+		previous, _ = os.ReadFile(filepath.Join(m.teamDir, meta.File))
+	}
+
+	next := &teamCacheMeta{Source: source.Location(), FetchedAt: time.Now()}
+	var data []byte
+	var err error
+
+	if source.URL != "" {
+		etag := ""
+		if meta != nil && previous != nil {
+			etag = meta.ETag
+		}
+
+		var notModified bool
+		data, next.ETag, notModified, err = m.fetchTeamURL(source.URL, etag)
+		if err != nil {
+			return nil, err
+		}
+		if notModified {
+			data = previous
+			result.NotModified = true
+		}
+		next.File = "team" + teamConfigExtension(source.URL)
+	} else {
+		path := m.resolveTeamPath(source.Path)
+		revision, warning := pullGitCheckout(filepath.Dir(path))
+		if warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+		}
+		next.Revision = revision
+
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read team config: %w", err)
+		}
+		next.File = "team" + teamConfigExtension(path)
+	}
+
+	raw, err := verifyTeamConfig(source, next.File, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	next.SHA256 = hex.EncodeToString(sum[:])
+	result.Path = filepath.Join(m.teamDir, next.File)
+	result.Revision = next.Revision
+
+	var previousRaw map[string]interface{}
+	if previous != nil {
+		if doc, err := parseDocument(filepath.Join(m.teamDir, meta.File), previous); err == nil {
+			previousRaw, _ = doc.Decode()
+		}
+	}
+	result.Changes = m.teamChanges(previousRaw, raw)
+	if len(result.Changes) == 0 && previous != nil {
+		result.NotModified = true
+	}
+
+	if err := atomicWriteFile(result.Path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to cache team config: %w", err)
+	}
+	if meta != nil && meta.File != next.File {
+		os.Remove(filepath.Join(m.teamDir, meta.File))
+	}
+
+	metaData, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicWriteFile(filepath.Join(m.teamDir, "meta.json"), append(metaData, '\n'), 0600); err != nil {
+		return nil, fmt.Errorf("failed to cache team config: %w", err)
+	}
+
+	return result, nil
+}
+
+// readTeamMeta reads the description of the cached team configuration
+func (m *Manager) readTeamMeta() (*teamCacheMeta, error) {
+	if m.teamDir == "" {
+		return nil, fmt.Errorf("no team config cache directory")
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.teamDir, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var meta teamCacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse team config cache: %w", err)
+	}
+	return &meta, nil
+}
+
+// fetchTeamURL downloads a team configuration, returning notModified if it still matches etag
+func (m *Manager) fetchTeamURL(rawURL, etag string) ([]byte, string, bool, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil, "", false, fmt.Errorf("invalid team config URL '%s'", rawURL)
+	}
+	if parsed.Scheme != "https" {
+		return nil, "", false, fmt.Errorf("team config URL must use https, got '%s'", rawURL)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := m.httpClient
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to fetch team config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, true, nil
+	case http.StatusOK:
+	default:
+		return nil, "", false, fmt.Errorf("failed to fetch team config: %s returned %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTeamConfigSize+1))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to fetch team config: %w", err)
+	}
+	if len(data) > maxTeamConfigSize {
+		return nil, "", false, fmt.Errorf("team config at %s is larger than %d bytes", rawURL, maxTeamConfigSize)
+	}
+
+	return data, resp.Header.Get("ETag"), false, nil
+}
+
+// resolveTeamPath expands ~ and resolves a relative path against the user configuration directory
+func (m *Manager) resolveTeamPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(filepath.Dir(m.configPath), path)
+	}
+	return path
+}
+
+// pullGitCheckout fast-forwards the git checkout containing dir and returns its revision.
+// Directories outside a git checkout are read as they are; a failed pull is only a warning.
+func pullGitCheckout(dir string) (revision, warning string) {
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Run(); err != nil {
+		return "", ""
+	}
+
+	// Local-only checkouts have nothing to pull from
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--abbrev-ref", "@{upstream}").Run(); err == nil {
+		if output, err := exec.Command("git", "-C", dir, "pull", "--ff-only", "--quiet").CombinedOutput(); err != nil {
+			reason, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+			warning = fmt.Sprintf("git pull in %s failed, using the checked out version: %s", dir, reason)
+		}
+	}
+
+	if output, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output(); err == nil {
+		revision = strings.TrimSpace(string(output))
+	}
+	return revision, warning
+}
+
+// teamConfigExtension returns the configuration format extension of a team config location
+func teamConfigExtension(location string) string {
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" {
+		location = parsed.Path
+	}
+	if ext := filepath.Ext(location); isConfigExtension(ext) {
+		return ext
+	}
+	return ".json"
+}
+
+// verifyTeamConfig checks a downloaded team configuration before it is cached
+func verifyTeamConfig(source *TeamSource, file string, data []byte) (map[string]interface{}, error) {
+	if source.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, source.SHA256) {
+			return nil, fmt.Errorf("team config checksum mismatch: expected %s, got %s", source.SHA256, actual)
+		}
+	}
+
+	doc, err := parseDocument(file, data)
+	if err != nil {
+		return nil, fmt.Errorf("team config is not valid: %w", err)
+	}
+	if _, _, err := migrateDocument(doc); err != nil {
+		return nil, fmt.Errorf("team config is not valid: %w", err)
+	}
+	raw, err := doc.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("team config is not valid: %w", err)
+	}
+
+	// A team configuration must not redirect users to yet another source
+	if _, ok := raw["team"]; ok {
+		return nil, fmt.Errorf("team config must not reference another team config")
+	}
+	if unknown := unknownKeys(raw, reflect.TypeOf(Config{}), ""); len(unknown) > 0 {
+		return nil, fmt.Errorf("team config has unknown keys: %s", strings.Join(unknown, ", "))
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return nil, fmt.Errorf("team config is not valid: %w", err)
+	}
+	for name, env := range config.Environments {
+		if err := ValidateEnvironmentName(name); err != nil {
+			return nil, fmt.Errorf("team config is not valid: %w", err)
+		}
+		if _, err := NormalizeProxy(env.Proxy); err != nil {
+			return nil, fmt.Errorf("team config environment '%s' is not valid: %w", name, err)
+		}
+	}
+
+	return raw, nil
+}
+
+// teamChanges lists the values that differ between two versions of the team configuration
+func (m *Manager) teamChanges(before, after map[string]interface{}) []TeamChange {
+	beforeLeaves := make(map[string]string)
+	afterLeaves := make(map[string]string)
+	flattenLeaves(before, "", beforeLeaves)
+	flattenLeaves(after, "", afterLeaves)
+
+	paths := make(map[string]bool)
+	for path := range beforeLeaves {
+		paths[path] = true
+	}
+	for path := range afterLeaves {
+		paths[path] = true
+	}
+
+	var user map[string]interface{}
+	if layer := newLayer(LayerUser, m.configPath); layer.Exists {
+		user, _ = readLayer(layer)
+	}
+
+	var changes []TeamChange
+	for path := range paths {
+		// Schema versions are upgraded in memory, so they are not a change worth showing
+		if path == "schema_version" || beforeLeaves[path] == afterLeaves[path] {
+			continue
+		}
+		_, overridden := lookupPath(user, strings.Split(path, "."))
+		changes = append(changes, TeamChange{
+			Path:       path,
+			Before:     beforeLeaves[path],
+			After:      afterLeaves[path],
+			Overridden: overridden,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// flattenLeaves records every non-object value of raw under its dotted path
+func flattenLeaves(raw map[string]interface{}, prefix string, leaves map[string]string) {
+	for key, value := range raw {
+		path := joinPath(prefix, key)
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenLeaves(nested, path, leaves)
+			continue
+		}
+		data, _ := json.Marshal(value)
+		leaves[path] = string(data)
+	}
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTeamTestManager creates a manager whose user config subscribes to the given team source
+func newTeamTestManager(t *testing.T, team string) *Manager {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{
+  "schema_version": 1,
+  "environments": {
+    "prod": {"proxy": "teleport.prod.company.com:443", "user": "alice"}
+  },
+  "team": `+team+`
+}
+`), 0600)
+
+	return &Manager{configPath: configPath, teamDir: filepath.Join(tempDir, "team")}
+}
+
+func TestManager_SyncTeamConfig_URL(t *testing.T) {
+	body := `{"environments": {"prod": {"proxy": "teleport.prod.company.com:443", "tsh_version": "16.4.0"}, "test": {"proxy": "teleport.test.company.com:443"}}}`
+	revision, requests, notModified := 1, 0, 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := fmt.Sprintf(`"v%d"`, revision)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	manager := newTeamTestManager(t, `{"url": "`+server.URL+`/team.json"}`)
+	manager.httpClient = server.Client()
+
+	result, err := manager.SyncTeamConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.NotModified || len(result.Changes) != 3 {
+		t.Errorf("Expected 3 added values on the first sync, got %+v", result.Changes)
+	}
+
+	// The team layer sits below the user layer
+	config, sources, err := manager.LoadWithSources()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Environments["test"].Proxy != "teleport.test.company.com:443" {
+		t.Errorf("Expected the team environment to be merged, got %+v", config.Environments)
+	}
+	if config.Environments["prod"].TSHVersion != "16.4.0" || config.Environments["prod"].User != "alice" {
+		t.Errorf("Expected team and user values to be merged, got %+v", config.Environments["prod"])
+	}
+	if sources.Lookup("environments.prod.tsh_version") != LayerTeam || sources.Lookup("environments.prod.user") != LayerUser {
+		t.Errorf("Unexpected sources %+v", sources)
+	}
+
+	// An unchanged file is answered with 304 thanks to the ETag
+	result, err = manager.SyncTeamConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.NotModified || notModified != 1 {
+		t.Errorf("Expected a not modified response, got %+v after %d requests", result, requests)
+	}
+
+	// Upstream changes a tsh pin and a proxy the user overrides
+	revision++
+	body = `{"environments": {"prod": {"proxy": "teleport-new.prod.company.com:443", "tsh_version": "17.7.1"}, "test": {"proxy": "teleport.test.company.com:443"}}}`
+	result, err = manager.SyncTeamConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	changes := make(map[string]TeamChange)
+	for _, change := range result.Changes {
+		changes[change.Path] = change
+	}
+	if change := changes["environments.prod.tsh_version"]; change.Before != `"16.4.0"` || change.After != `"17.7.1"` || change.Overridden {
+		t.Errorf("Unexpected tsh version change %+v", change)
+	}
+	if change := changes["environments.prod.proxy"]; !change.Overridden {
+		t.Errorf("Expected the proxy change to be overridden by the user config, got %+v", change)
+	}
+	if len(result.Changes) != 2 {
+		t.Errorf("Expected 2 changes, got %+v", result.Changes)
+	}
+}
+
+func TestManager_RefreshTeamConfig(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"environments": {"test": {"proxy": "teleport.test.company.com:443"}}}`))
+	}))
+	defer server.Close()
+
+	manager := newTeamTestManager(t, `{"url": "`+server.URL+`/team.json"}`)
+	manager.httpClient = server.Client()
+
+	// Loading never goes online, even before the first sync
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requests != 0 || manager.TeamCached() {
+		t.Errorf("Expected Load not to fetch the team config, got %d requests", requests)
+	}
+	if _, ok := config.Environments["test"]; ok {
+		t.Error("Expected no team environments before the first sync")
+	}
+
+	result, err := manager.RefreshTeamConfig()
+	if err != nil || result == nil {
+		t.Fatalf("Expected the missing team config to be fetched, got %+v, %v", result, err)
+	}
+	if requests != 1 || !manager.TeamCached() {
+		t.Errorf("Expected one request and a cached team config, got %d requests", requests)
+	}
+
+	// A fresh cache is not fetched again
+	if result, err := manager.RefreshTeamConfig(); err != nil || result != nil || requests != 1 {
+		t.Errorf("Expected no refresh of a fresh cache, got %+v, %v after %d requests", result, err, requests)
+	}
+
+	config, err = manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Environments["test"].Proxy != "teleport.test.company.com:443" {
+		t.Errorf("Expected the cached team environment, got %+v", config.Environments)
+	}
+}
+
+func TestManager_Load_TeamHintOnce(t *testing.T) {
+	manager := newTeamTestManager(t, `{"url": "https://config.company.com/tkube/team.json"}`)
+
+	stderr, _ := os.CreateTemp(t.TempDir(), "stderr")
+	original := os.Stderr
+	os.Stderr = stderr
+	for i := 0; i < 3; i++ {
+		manager.Load()
+	}
+	os.Stderr = original
+
+	data, _ := os.ReadFile(stderr.Name())
+	if count := strings.Count(string(data), "team config has not been fetched"); count != 1 {
+		t.Errorf("Expected the team hint once, got %d times:\n%s", count, data)
+	}
+}
+
+func TestManager_SyncTeamConfig_Path(t *testing.T) {
+	teamDir := t.TempDir()
+	teamPath := filepath.Join(teamDir, "team.yaml")
+	os.WriteFile(teamPath, []byte("environments:\n  staging:\n    proxy: teleport.staging.company.com:443\n"), 0600)
+
+	manager := newTeamTestManager(t, `{"path": "`+teamPath+`"}`)
+
+	if layer := manager.teamLayer(); layer == nil || layer.Exists {
+		t.Fatalf("Expected an unsynced team layer, got %+v", layer)
+	}
+
+	if _, err := manager.SyncTeamConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	layer := manager.teamLayer()
+	if layer == nil || !layer.Exists || filepath.Ext(layer.Path) != ".yaml" {
+		t.Fatalf("Expected the cached YAML team layer, got %+v", layer)
+	}
+
+	config, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := config.Environments["staging"]; !ok {
+		t.Errorf("Expected the team environment to be merged, got %+v", config.Environments)
+	}
+
+	// Team environments cannot be removed from the user config
+	if err := manager.CanRemoveEnvironment("staging"); err == nil {
+		t.Error("Expected error when removing an environment defined by the team config")
+	}
+}
+
+func TestManager_SyncTeamConfig_Verification(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		pin     string
+		wantErr string
+	}{
+		{"parse error", `{"environments": `, "", "not valid"},
+		{"unknown key", `{"environmnets": {}}`, "", "unknown keys"},
+		{"nested team", `{"team": {"url": "https://example.com/other.json"}}`, "", "another team config"},
+		{"invalid proxy", `{"environments": {"prod": {"proxy": "teleport:port"}}}`, "", "prod"},
+		{"checksum", `{"environments": {}}`, "0000", "checksum mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teamPath := filepath.Join(t.TempDir(), "team.json")
+			os.WriteFile(teamPath, []byte(tt.content), 0600)
+
+			team := `{"path": "` + teamPath + `"}`
+			if tt.pin != "" {
+				team = `{"path": "` + teamPath + `", "sha256": "` + tt.pin + `"}`
+			}
+			manager := newTeamTestManager(t, team)
+
+			_, err := manager.SyncTeamConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+			if layer := manager.teamLayer(); layer == nil || layer.Exists {
+				t.Errorf("Expected nothing to be cached, got %+v", layer)
+			}
+		})
+	}
+}
+
+func TestManager_SyncTeamConfig_RequiresHTTPS(t *testing.T) {
+	manager := newTeamTestManager(t, `{"url": "http://config.company.com/team.json"}`)
+
+	if _, err := manager.SyncTeamConfig(); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Errorf("Expected error for a plain HTTP URL, got %v", err)
+	}
+}
+
+func TestManager_SyncTeamConfig_NotConfigured(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{"environments": {}}`), 0600)
+
+	manager := &Manager{configPath: configPath, teamDir: filepath.Join(tempDir, "team")}
+	if _, err := manager.SyncTeamConfig(); err == nil {
+		t.Error("Expected error without a team source")
+	}
+	if layer := manager.teamLayer(); layer != nil {
+		t.Errorf("Expected no team layer, got %+v", layer)
+	}
+}
@@ -0,0 +1,550 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Severity classifies a validation finding
+type Severity string
+
+// Validation severities; only errors make a configuration invalid
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Diagnostic is a single validation finding with a suggested fix
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Path     string   `json:"path,omitempty"`
+	File     string   `json:"file,omitempty"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// ValidationOptions supplies the checks that need tools outside the config package
+type ValidationOptions struct {
+	// IsVersionInstalled reports whether a tsh version is installed; nil skips the check
+	IsVersionInstalled func(version string) bool
+	// ProbeProxy checks that a proxy is reachable; nil skips the live check
+	ProbeProxy func(proxy string) error
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every configuration layer and the merged configuration without modifying any file
+func (m *Manager) Validate(opts ValidationOptions) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+
+	layers := m.Layers()
+	layerPaths := make(map[string]string)
+	parsed := true
+
+	for _, layer := range layers {
+		if !layer.Exists {
+			if layer.Name == LayerTeam {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     "team-not-synced",
+					Path:     "team",
+					File:     layer.Path,
+					Message:  fmt.Sprintf("team config %s has not been fetched yet", layer.Path),
+					Fix:      "Run 'tkube config sync'",
+				})
+			}
+			continue
+		}
+		layerPaths[layer.Name] = layer.Path
+
+		diagnostics = append(diagnostics, checkPermissions(layer)...)
+
+		doc, raw, err := readLayerDocument(layer)
+		if err != nil {
+			parsed = false
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Code:     "parse-error",
+				File:     layer.Path,
+				Message:  err.Error(),
+				Fix:      "Fix the syntax error or restore a previous version with 'tkube config history'",
+			})
+			continue
+		}
+
+		for _, path := range ignoredCommandKeys(layer.Name, raw) {
+			diagnostic := Diagnostic{
+				Severity: SeverityWarning,
+				Code:     "command-not-allowed",
+				Path:     path,
+				File:     layer.Path,
+				Message:  fmt.Sprintf("'%s' is ignored in the %s config; commands are only run from the system and user configs", path, layer.Name),
+				Fix:      fmt.Sprintf("Remove the key, or set it in your own config with 'tkube config set %s <command>'", path),
+			}
+			if line, column, ok := doc.Position(strings.Split(path, ".")); ok {
+				diagnostic.Message += fmt.Sprintf(" at line %d, column %d", line, column)
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+
+		for _, path := range unknownKeys(raw, reflect.TypeOf(Config{}), "") {
+			diagnostic := Diagnostic{
+				Severity: SeverityError,
+				Code:     "unknown-key",
+				Path:     path,
+				File:     layer.Path,
+				Message:  fmt.Sprintf("unknown key '%s'", path),
+				Fix:      "Remove the key",
+			}
+			if line, column, ok := doc.Position(strings.Split(path, ".")); ok {
+				diagnostic.Message += fmt.Sprintf(" at line %d, column %d", line, column)
+			}
+			if suggestion := suggestKey(path); suggestion != "" {
+				diagnostic.Fix = fmt.Sprintf("Did you mean '%s'?", suggestion)
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	if len(layerPaths) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     "missing-config",
+			File:     m.configPath,
+			Message:  "no configuration file found",
+			Fix:      "Run 'tkube config add' to create one",
+		})
+		return diagnostics, nil
+	}
+
+	// The merged configuration is only meaningful if every layer could be read
+	if !parsed {
+		return diagnostics, nil
+	}
+
+	config, sources, err := mergeLayers(layers)
+	if err != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     "invalid-value",
+			Message:  err.Error(),
+			Fix:      "Check that every value has the expected type",
+		})
+		return diagnostics, nil
+	}
+
+	fileOf := func(path string) string {
+		return layerPaths[sources.Lookup(path)]
+	}
+
+	diagnostics = append(diagnostics, m.checkEnvironments(config, fileOf, opts)...)
+	return diagnostics, nil
+}
+
+// checkEnvironments validates the environments of the merged configuration
+func (m *Manager) checkEnvironments(config *Config, fileOf func(path string) string, opts ValidationOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	// The global renewal threshold applies to every environment
+	if config.RenewBefore != "" {
+		if _, err := ParseRenewBefore(config.RenewBefore); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Code:     "invalid-renew-before",
+				Path:     "renew_before",
+				File:     fileOf("renew_before"),
+				Message:  err.Error(),
+				Fix:      "Set a duration, e.g. tkube config set renew_before 15m",
+			})
+		}
+	}
+
+	if err := config.Agent.Validate(); err != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     "invalid-agent-option",
+			Path:     "agent",
+			File:     fileOf("agent"),
+			Message:  err.Error(),
+			Fix:      "Fix the option or remove it from the agent block",
+		})
+	}
+
+	if len(config.Environments) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     "no-environments",
+			Path:     "environments",
+			File:     fileOf("environments"),
+			Message:  "no environments are configured",
+			Fix:      "Run 'tkube config add'",
+		})
+	}
+
+	names := make([]string, 0, len(config.Environments))
+	for name := range config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	proxies := make(map[string][]string)
+	for _, name := range names {
+		env := config.Environments[name]
+		envPath := "environments." + name
+
+		if err := ValidateEnvironmentName(name); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Code:     "invalid-name",
+				Path:     envPath,
+				File:     fileOf(envPath),
+				Message:  err.Error(),
+				Fix:      "Rename the environment",
+			})
+		}
+
+		// Proxy address
+		proxyPath := envPath + ".proxy"
+		proxy, proxyErr := NormalizeProxy(env.Proxy)
+		switch {
+		case proxyErr != nil:
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Code:     "invalid-proxy",
+				Path:     proxyPath,
+				File:     fileOf(proxyPath),
+				Message:  proxyErr.Error(),
+				Fix:      fmt.Sprintf("Set a host:port address, e.g. tkube config edit %s --proxy teleport.example.com:443", name),
+			})
+		case proxy != env.Proxy:
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     "proxy-format",
+				Path:     proxyPath,
+				File:     fileOf(proxyPath),
+				Message:  fmt.Sprintf("proxy '%s' is not in host:port form; '%s' is assumed", env.Proxy, proxy),
+				Fix:      fmt.Sprintf("Set the proxy to '%s'", proxy),
+			})
+		}
+		if proxyErr == nil {
+			proxies[proxy] = append(proxies[proxy], name)
+		}
+
+		// tsh version
+		versionPath := envPath + ".tsh_version"
+		if env.TSHVersion == "" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityInfo,
+				Code:     "tsh-version-unset",
+				Path:     versionPath,
+				File:     fileOf(envPath),
+				Message:  "no tsh version is pinned; it is detected on the first connect",
+				Fix:      "Run 'tkube auto-detect-versions' to pin it now",
+			})
+		} else if version, err := NormalizeTSHVersion(env.TSHVersion); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Code:     "invalid-tsh-version",
+				Path:     versionPath,
+				File:     fileOf(versionPath),
+				Message:  err.Error(),
+				Fix:      fmt.Sprintf("Set a release version, e.g. tkube config edit %s --tsh-version 17.7.1", name),
+			})
+		} else if version != env.TSHVersion {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     "tsh-version-format",
+				Path:     versionPath,
+				File:     fileOf(versionPath),
+				Message:  fmt.Sprintf("tsh version '%s' should be written as '%s'", env.TSHVersion, version),
+				Fix:      fmt.Sprintf("Set the version to '%s'", version),
+			})
+		} else if opts.IsVersionInstalled != nil && !opts.IsVersionInstalled(version) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     "tsh-not-installed",
+				Path:     versionPath,
+				File:     fileOf(versionPath),
+				Message:  fmt.Sprintf("tsh version %s is configured but not installed", version),
+				Fix:      fmt.Sprintf("Run 'tkube install-tsh %s'", version),
+			})
+		}
+
+		// Cluster aliases
+		for _, alias := range sortedStringKeys(env.Aliases) {
+			aliasPath := envPath + ".aliases." + alias
+			if alias == "" || strings.ContainsAny(alias, " \t") || env.Aliases[alias] == "" {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     "invalid-alias",
+					Path:     aliasPath,
+					File:     fileOf(aliasPath),
+					Message:  fmt.Sprintf("cluster alias '%s' needs a name without spaces and a cluster to point to", alias),
+					Fix:      "Map the alias to a Teleport cluster name or remove it",
+				})
+			}
+		}
+
+		// Tags, which selectors must be able to express
+		for _, key := range sortedStringKeys(env.Tags) {
+			tagPath := envPath + ".tags." + key
+			if key == "" || strings.ContainsAny(key, "=!, \t") || strings.ContainsAny(env.Tags[key], ", \t") {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     "invalid-tag",
+					Path:     tagPath,
+					File:     fileOf(tagPath),
+					Message:  fmt.Sprintf("tag '%s=%s' cannot be matched by a selector", key, env.Tags[key]),
+					Fix:      "Use tag keys and values without spaces, commas, '=' or '!'",
+				})
+			}
+		}
+
+		// Login options, checked against the pinned tsh version
+		if env.Login != nil {
+			loginPath := envPath + ".login"
+			if err := env.Login.Validate(""); err != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityError,
+					Code:     "invalid-login-option",
+					Path:     loginPath,
+					File:     fileOf(loginPath),
+					Message:  err.Error(),
+					Fix:      "Fix the option or remove it from the login block",
+				})
+			} else if version, versionErr := NormalizeTSHVersion(env.TSHVersion); versionErr == nil {
+				if err := env.Login.Validate(version); err != nil {
+					diagnostics = append(diagnostics, Diagnostic{
+						Severity: SeverityError,
+						Code:     "login-option-unsupported",
+						Path:     loginPath,
+						File:     fileOf(loginPath),
+						Message:  err.Error(),
+						Fix:      fmt.Sprintf("Pin a newer tsh version with 'tkube config edit %s --tsh-version <version>' or drop the option", name),
+					})
+				}
+			}
+		}
+
+		// Renewal threshold
+		if env.RenewBefore != "" {
+			renewPath := envPath + ".renew_before"
+			if _, err := ParseRenewBefore(env.RenewBefore); err != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityError,
+					Code:     "invalid-renew-before",
+					Path:     renewPath,
+					File:     fileOf(renewPath),
+					Message:  err.Error(),
+					Fix:      fmt.Sprintf("Set a duration, e.g. tkube config set %s 30m", renewPath),
+				})
+			}
+		}
+
+		// Live reachability
+		if opts.ProbeProxy != nil && proxyErr == nil {
+			if probeErr := opts.ProbeProxy(proxy); probeErr != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityError,
+					Code:     "proxy-unreachable",
+					Path:     proxyPath,
+					File:     fileOf(proxyPath),
+					Message:  probeErr.Error(),
+					Fix:      "Check the address and your network or VPN connection",
+				})
+			}
+		}
+	}
+
+	// Duplicate proxies
+	var duplicates []string
+	for proxy, envs := range proxies {
+		if len(envs) > 1 {
+			duplicates = append(duplicates, proxy)
+		}
+	}
+	sort.Strings(duplicates)
+	for _, proxy := range duplicates {
+		envs := proxies[proxy]
+		path := "environments." + envs[1] + ".proxy"
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     "duplicate-proxy",
+			Path:     path,
+			File:     fileOf(path),
+			Message:  fmt.Sprintf("environments %s use the same proxy %s", strings.Join(envs, ", "), proxy),
+			Fix:      "Remove the duplicate with 'tkube config remove <environment>' unless they use different users",
+		})
+	}
+
+	return diagnostics
+}
+
+// checkPermissions reports configuration files other users can modify
+func checkPermissions(layer Layer) []Diagnostic {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	info, err := os.Stat(layer.Path)
+	if err != nil {
+		return nil
+	}
+
+	if info.Mode().Perm()&0022 == 0 {
+		return nil
+	}
+
+	return []Diagnostic{{
+		Severity: SeverityWarning,
+		Code:     "insecure-permissions",
+		File:     layer.Path,
+		Message:  fmt.Sprintf("%s config file is writable by other users (mode %04o)", layer.Name, info.Mode().Perm()),
+		Fix:      fmt.Sprintf("Run 'chmod go-w %s'", layer.Path),
+	}}
+}
+
+// unknownKeys returns the dotted paths of keys in raw that have no matching JSON field in t
+func unknownKeys(raw interface{}, t reflect.Type, path string) []string {
+	var unknown []string
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return unknownKeys(raw, t.Elem(), path)
+
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(object) {
+			childPath := joinPath(path, key)
+			field, known := fields[key]
+			if !known {
+				unknown = append(unknown, childPath)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(object[key], field, childPath)...)
+		}
+
+	case reflect.Map:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		for _, key := range sortedKeys(object) {
+			unknown = append(unknown, unknownKeys(object[key], t.Elem(), joinPath(path, key))...)
+		}
+	}
+
+	return unknown
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// suggestKey returns a known key close to the last element of an unknown key path
+func suggestKey(path string) string {
+	parts := strings.Split(path, ".")
+	key := parts[len(parts)-1]
+
+	candidates := jsonFields(reflect.TypeOf(Config{}))
+	if len(parts) == 3 && parts[0] == "environments" {
+		candidates = jsonFields(reflect.TypeOf(Environment{}))
+	}
+	if len(parts) == 2 && parts[0] == "agent" {
+		candidates = jsonFields(reflect.TypeOf(AgentOptions{}))
+	}
+	if len(parts) == 4 && parts[0] == "environments" && parts[2] == "login" {
+		candidates = jsonFields(reflect.TypeOf(LoginOptions{}))
+	}
+
+	best, bestDistance := "", 3
+	for candidate := range candidates {
+		if distance := editDistance(key, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// sortedStringKeys returns the keys of an alias or tag map in sorted order
+func sortedStringKeys(aliases map[string]string) []string {
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinPath appends a key to a dotted configuration path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// findDiagnostic returns the first diagnostic with the given code and path
+func findDiagnostic(diagnostics []Diagnostic, code, path string) *Diagnostic {
+	for i := range diagnostics {
+		if diagnostics[i].Code == code && diagnostics[i].Path == path {
+			return &diagnostics[i]
+		}
+	}
+	return nil
+}
+
+func TestManager_Validate(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{
+  "environments": {
+    "prod": {"proxy": "teleport.prod.company.com:443", "tsh_version": "17.7.1", "aliases": {"pay": "eks-payments", "empty": ""}, "tags": {"tier": "prod", "team": "a,b"}},
+    "prod-copy": {"proxy": "teleport.prod.company.com", "tsh_vesion": "17.7.1"},
+    "bad name": {"proxy": "teleport.test.company.com:port", "tsh_version": "latest", "login": {"ttl": "forever"}, "renew_before": "-5m"},
+    "legacy": {"proxy": "teleport.legacy.company.com:443", "tsh_version": "9.3.4", "login": {"mfa_mode": "auto", "conector": "okta"}}
+  },
+  "auto_login": true,
+  "renew_before": "soon",
+  "agent": {"interval": "0s"},
+  "colour": "always"
+}
+`), 0666)
+	os.Chmod(configPath, 0666)
+
+	manager := &Manager{configPath: configPath}
+
+	diagnostics, err := manager.Validate(ValidationOptions{
+		IsVersionInstalled: func(version string) bool { return false },
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		code     string
+		path     string
+		severity Severity
+	}{
+		{"invalid-name", "environments.bad name", SeverityError},
+		{"invalid-proxy", "environments.bad name.proxy", SeverityError},
+		{"invalid-tsh-version", "environments.bad name.tsh_version", SeverityError},
+		{"proxy-format", "environments.prod-copy.proxy", SeverityWarning},
+		{"duplicate-proxy", "environments.prod-copy.proxy", SeverityWarning},
+		{"unknown-key", "colour", SeverityError},
+		{"unknown-key", "environments.prod-copy.tsh_vesion", SeverityError},
+		{"tsh-not-installed", "environments.prod.tsh_version", SeverityWarning},
+		{"tsh-version-unset", "environments.prod-copy.tsh_version", SeverityInfo},
+		{"insecure-permissions", "", SeverityWarning},
+		{"invalid-alias", "environments.prod.aliases.empty", SeverityWarning},
+		{"invalid-tag", "environments.prod.tags.team", SeverityWarning},
+		{"invalid-login-option", "environments.bad name.login", SeverityError},
+		{"login-option-unsupported", "environments.legacy.login", SeverityError},
+		{"unknown-key", "environments.legacy.login.conector", SeverityError},
+		{"invalid-renew-before", "renew_before", SeverityError},
+		{"invalid-agent-option", "agent", SeverityError},
+		{"invalid-renew-before", "environments.bad name.renew_before", SeverityError},
+	}
+
+	for _, tt := range tests {
+		diagnostic := findDiagnostic(diagnostics, tt.code, tt.path)
+		if diagnostic == nil {
+			t.Errorf("Expected %s diagnostic for '%s', got %+v", tt.code, tt.path, diagnostics)
+			continue
+		}
+		if diagnostic.Severity != tt.severity {
+			t.Errorf("Expected %s to be %s, got %s", tt.code, tt.severity, diagnostic.Severity)
+		}
+		if diagnostic.Fix == "" {
+			t.Errorf("Expected %s to suggest a fix", tt.code)
+		}
+	}
+
+	if d := findDiagnostic(diagnostics, "unknown-key", "environments.prod-copy.tsh_vesion"); d != nil && d.Fix != "Did you mean 'tsh_version'?" {
+		t.Errorf("Expected a spelling suggestion, got '%s'", d.Fix)
+	}
+
+	if !HasErrors(diagnostics) {
+		t.Error("Expected the configuration to have errors")
+	}
+}
+
+func TestManager_Validate_ValidConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte(`{"environments": {"prod": {"proxy": "teleport.prod.company.com:443", "tsh_version": "17.7.1"}}}`), 0600)
+
+	manager := &Manager{configPath: configPath}
+
+	diagnostics, err := manager.Validate(ValidationOptions{
+		IsVersionInstalled: func(version string) bool { return true },
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", diagnostics)
+	}
+
+	// Unreachable proxies are errors when the live check is enabled
+	diagnostics, _ = manager.Validate(ValidationOptions{
+		ProbeProxy: func(proxy string) error { return fmt.Errorf("connection refused") },
+	})
+	if findDiagnostic(diagnostics, "proxy-unreachable", "environments.prod.proxy") == nil {
+		t.Errorf("Expected proxy-unreachable diagnostic, got %+v", diagnostics)
+	}
+}
+
+func TestManager_Validate_ParseError(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+	os.WriteFile(configPath, []byte("{\n  \"environments\": \n}\n"), 0600)
+
+	manager := &Manager{configPath: configPath}
+
+	diagnostics, err := manager.Validate(ValidationOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Code != "parse-error" || diagnostics[0].File != configPath {
+		t.Errorf("Expected a single parse error, got %+v", diagnostics)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument is a YAML configuration file edited through its node tree,
+// which keeps comments and key order intact
+type yamlDocument struct {
+	root yaml.Node
+}
+
+// newYAMLDocument creates an empty YAML document
+func newYAMLDocument() *yamlDocument {
+	return &yamlDocument{root: yaml.Node{
+		Kind:    yaml.DocumentNode,
+		Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+	}}
+}
+
+// parseYAMLDocument parses a YAML configuration file
+func parseYAMLDocument(data []byte) (*yamlDocument, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return newYAMLDocument(), nil
+	}
+
+	doc := &yamlDocument{}
+	if err := yaml.Unmarshal(data, &doc.root); err != nil {
+		return nil, err
+	}
+
+	if doc.root.Kind != yaml.DocumentNode || len(doc.root.Content) == 0 || doc.root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("configuration must be a YAML mapping")
+	}
+
+	return doc, nil
+}
+
+// mapping returns the top-level mapping node
+func (d *yamlDocument) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// Decode returns the document contents as a generic map
+func (d *yamlDocument) Decode() (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	if err := d.mapping().Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// Set stores a value at the given path, creating intermediate mappings as needed
+func (d *yamlDocument) Set(path []string, value interface{}) error {
+	node := d.mapping()
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not an object", strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+
+		index := yamlKeyIndex(node, key)
+		if index < 0 {
+			// Wrap the value in the mappings that are still missing
+			nested := value
+			for j := len(path) - 1; j > i; j-- {
+				nested = map[string]interface{}{path[j]: nested}
+			}
+
+			valueNode := &yaml.Node{}
+			if err := valueNode.Encode(nested); err != nil {
+				return fmt.Errorf("failed to encode value: %w", err)
+			}
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			node.Content = append(node.Content, keyNode, valueNode)
+			return nil
+		}
+
+		if i == len(path)-1 {
+			old := node.Content[index+1]
+			valueNode := &yaml.Node{}
+			if err := valueNode.Encode(value); err != nil {
+				return fmt.Errorf("failed to encode value: %w", err)
+			}
+			valueNode.HeadComment = old.HeadComment
+			valueNode.LineComment = old.LineComment
+			valueNode.FootComment = old.FootComment
+			node.Content[index+1] = valueNode
+			return nil
+		}
+
+		node = node.Content[index+1]
+	}
+	return nil
+}
+
+// Delete removes the value stored at the given path along with its comments
+func (d *yamlDocument) Delete(path []string) error {
+	node := d.mapping()
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		index := yamlKeyIndex(node, key)
+		if index < 0 {
+			return nil
+		}
+
+		if i == len(path)-1 {
+			node.Content = append(node.Content[:index], node.Content[index+2:]...)
+			return nil
+		}
+
+		node = node.Content[index+1]
+	}
+	return nil
+}
+
+// Bytes renders the document back to YAML
+func (d *yamlDocument) Bytes() ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&d.root); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Position returns the line and column of the key at the given path
+func (d *yamlDocument) Position(path []string) (int, int, bool) {
+	node := d.mapping()
+	var keyNode *yaml.Node
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return 0, 0, false
+		}
+		i := yamlKeyIndex(node, key)
+		if i < 0 {
+			return 0, 0, false
+		}
+		keyNode, node = node.Content[i], node.Content[i+1]
+	}
+	if keyNode == nil {
+		return 0, 0, false
+	}
+	return keyNode.Line, keyNode.Column, true
+}
+
+// yamlKeyIndex returns the index of the key node for key in a mapping, or -1
+func yamlKeyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
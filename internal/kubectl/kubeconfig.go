@@ -0,0 +1,225 @@
+package kubectl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfigSections are the named entry lists of a kubeconfig file
+var kubeconfigSections = []string{"clusters", "users", "contexts"}
+
+// DefaultKubeconfigPath returns the kubeconfig kubectl writes to: the first file in KUBECONFIG or ~/.kube/config
+func DefaultKubeconfigPath() (string, error) {
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if path != "" {
+			return path, nil
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".kube", "config"), nil
+}
+
+// kubeconfig is a kubeconfig file kept as generic YAML, so fields tkube does not know survive a rewrite
+type kubeconfig map[string]interface{}
+
+// readKubeconfig reads a kubeconfig file; a missing file is an empty kubeconfig
+func readKubeconfig(path string) (kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return kubeconfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoding into a plain map makes nested mappings plain maps as well
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+	}
+	return kubeconfig(config), nil
+}
+
+// entries returns the entries of a section such as "contexts"
+func (k kubeconfig) entries(section string) []interface{} {
+	entries, _ := k[section].([]interface{})
+	return entries
+}
+
+// entryName returns the name of a section entry
+func entryName(entry interface{}) string {
+	fields, _ := entry.(map[string]interface{})
+	name, _ := fields["name"].(string)
+	return name
+}
+
+// contextRefs returns the cluster and user names the contexts refer to
+func (k kubeconfig) contextRefs() map[string]map[string]bool {
+	refs := map[string]map[string]bool{"clusters": {}, "users": {}}
+	for _, entry := range k.entries("contexts") {
+		fields, _ := entry.(map[string]interface{})
+		context, _ := fields["context"].(map[string]interface{})
+		if cluster, ok := context["cluster"].(string); ok {
+			refs["clusters"][cluster] = true
+		}
+		if user, ok := context["user"].(string); ok {
+			refs["users"][user] = true
+		}
+	}
+	return refs
+}
+
+// write saves a kubeconfig through a temporary file, so kubectl never reads a partial file
+func (k kubeconfig) write(path string) error {
+	if _, ok := k["apiVersion"]; !ok {
+		k["apiVersion"] = "v1"
+	}
+	if _, ok := k["kind"]; !ok {
+		k["kind"] = "Config"
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}(k))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// MergeKubeconfig copies the clusters, users and contexts of src into dst, replacing entries
+// with the same name, and makes the current context of src the current context of dst
+func MergeKubeconfig(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+
+	source, err := readKubeconfig(src)
+	if err != nil {
+		return err
+	}
+	target, err := readKubeconfig(dst)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range kubeconfigSections {
+		merged := target.entries(section)
+		for _, entry := range source.entries(section) {
+			replaced := false
+			for i, existing := range merged {
+				if entryName(existing) == entryName(entry) {
+					merged[i] = entry
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				merged = append(merged, entry)
+			}
+		}
+		if merged != nil {
+			target[section] = merged
+		}
+	}
+
+	if current, ok := source["current-context"].(string); ok && current != "" {
+		target["current-context"] = current
+	}
+
+	return target.write(dst)
+}
+
+// RemoveKubeconfigEntries deletes the contexts of src from dst, along with their clusters and
+// users once no remaining context refers to them. dst is only rewritten if something was removed.
+func RemoveKubeconfigEntries(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+
+	source, err := readKubeconfig(src)
+	if err != nil {
+		return err
+	}
+	target, err := readKubeconfig(dst)
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]map[string]bool{}
+	for _, section := range kubeconfigSections {
+		removed[section] = map[string]bool{}
+		for _, entry := range source.entries(section) {
+			removed[section][entryName(entry)] = true
+		}
+	}
+
+	changed := false
+	keep := func(section string, inUse map[string]bool) {
+		var kept []interface{}
+		for _, entry := range target.entries(section) {
+			name := entryName(entry)
+			if removed[section][name] && !inUse[name] {
+				changed = true
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		if _, ok := target[section]; ok {
+			target[section] = kept
+		}
+	}
+
+	// Contexts first, so clusters and users still used by other contexts are kept
+	keep("contexts", nil)
+	refs := target.contextRefs()
+	keep("clusters", refs["clusters"])
+	keep("users", refs["users"])
+
+	if current, ok := target["current-context"].(string); ok && removed["contexts"][current] {
+		target["current-context"] = ""
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return target.write(dst)
+}
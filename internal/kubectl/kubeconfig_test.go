@@ -0,0 +1,188 @@
+package kubectl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const isolatedKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: teleport.prod.company.com
+  cluster:
+    server: https://teleport.prod.company.com:443
+users:
+- name: teleport.prod.company.com-eks-prod
+  user:
+    exec:
+      command: tsh
+contexts:
+- name: teleport.prod.company.com-eks-prod
+  context:
+    cluster: teleport.prod.company.com
+    user: teleport.prod.company.com-eks-prod
+current-context: teleport.prod.company.com-eks-prod
+`
+
+const globalKubeconfig = `apiVersion: v1
+kind: Config
+preferences:
+  colors: true
+clusters:
+- name: minikube
+  cluster:
+    server: https://192.168.49.2:8443
+- name: teleport.prod.company.com
+  cluster:
+    server: https://old.example.com
+users:
+- name: minikube
+  user:
+    client-certificate: /home/alice/.minikube/client.crt
+contexts:
+- name: minikube
+  context:
+    cluster: minikube
+    user: minikube
+current-context: minikube
+`
+
+// writeKubeconfigs writes the isolated and global kubeconfig fixtures and returns their paths
+func writeKubeconfigs(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "kube", "prod", "eks-prod.yaml")
+	dst := filepath.Join(dir, ".kube", "config")
+	os.MkdirAll(filepath.Dir(src), 0700)
+	os.MkdirAll(filepath.Dir(dst), 0700)
+	os.WriteFile(src, []byte(isolatedKubeconfig), 0600)
+	os.WriteFile(dst, []byte(globalKubeconfig), 0600)
+	return src, dst
+}
+
+// entryNames returns the names in a section of a kubeconfig file
+func entryNames(t *testing.T, path, section string) []string {
+	t.Helper()
+	config, err := readKubeconfig(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	var names []string
+	for _, entry := range config.entries(section) {
+		names = append(names, entryName(entry))
+	}
+	return names
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	src, dst := writeKubeconfigs(t)
+
+	if err := MergeKubeconfig(src, dst); err != nil {
+		t.Fatalf("Expected merge to succeed, got %v", err)
+	}
+
+	if names := strings.Join(entryNames(t, dst, "contexts"), ","); names != "minikube,teleport.prod.company.com-eks-prod" {
+		t.Errorf("Expected the context to be added, got %s", names)
+	}
+	if names := strings.Join(entryNames(t, dst, "clusters"), ","); names != "minikube,teleport.prod.company.com" {
+		t.Errorf("Expected the cluster to be replaced in place, got %s", names)
+	}
+
+	data, _ := os.ReadFile(dst)
+	var merged map[string]interface{}
+	yaml.Unmarshal(data, &merged)
+	if merged["current-context"] != "teleport.prod.company.com-eks-prod" {
+		t.Errorf("Expected the merged context to become current, got %v", merged["current-context"])
+	}
+	if strings.Contains(string(data), "old.example.com") {
+		t.Error("Expected the stale cluster entry to be replaced")
+	}
+	if !strings.Contains(string(data), "colors: true") {
+		t.Error("Expected unknown fields to be kept")
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestMergeKubeconfig_CreatesGlobalFile(t *testing.T) {
+	src, _ := writeKubeconfigs(t)
+	dst := filepath.Join(t.TempDir(), "new", "config")
+
+	if err := MergeKubeconfig(src, dst); err != nil {
+		t.Fatalf("Expected merge to succeed, got %v", err)
+	}
+	if names := entryNames(t, dst, "contexts"); len(names) != 1 {
+		t.Errorf("Expected one context, got %v", names)
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private kubeconfig, got %v", info.Mode().Perm())
+	}
+}
+
+func TestRemoveKubeconfigEntries(t *testing.T) {
+	src, dst := writeKubeconfigs(t)
+	if err := MergeKubeconfig(src, dst); err != nil {
+		t.Fatalf("Expected merge to succeed, got %v", err)
+	}
+
+	if err := RemoveKubeconfigEntries(src, dst); err != nil {
+		t.Fatalf("Expected removal to succeed, got %v", err)
+	}
+
+	for section, expected := range map[string]string{"contexts": "minikube", "clusters": "minikube", "users": "minikube"} {
+		if names := strings.Join(entryNames(t, dst, section), ","); names != expected {
+			t.Errorf("Expected %s %s, got %s", section, expected, names)
+		}
+	}
+	config, _ := readKubeconfig(dst)
+	if config["current-context"] != "" {
+		t.Errorf("Expected the removed context to be unset as current, got %v", config["current-context"])
+	}
+
+	// Nothing left to remove leaves the file alone
+	info, _ := os.Stat(dst)
+	if err := RemoveKubeconfigEntries(src, dst); err != nil {
+		t.Fatalf("Expected removal to succeed, got %v", err)
+	}
+	if after, _ := os.Stat(dst); !after.ModTime().Equal(info.ModTime()) {
+		t.Error("Expected an unchanged kubeconfig not to be rewritten")
+	}
+}
+
+func TestRemoveKubeconfigEntries_KeepsSharedEntries(t *testing.T) {
+	src, dst := writeKubeconfigs(t)
+	MergeKubeconfig(src, dst)
+
+	// Another context of the same Teleport cluster, e.g. from plain 'tsh kube login'
+	config, _ := readKubeconfig(dst)
+	config["contexts"] = append(config.entries("contexts"), map[string]interface{}{
+		"name":    "teleport.prod.company.com-eks-payments",
+		"context": map[string]interface{}{"cluster": "teleport.prod.company.com", "user": "teleport.prod.company.com-eks-payments"},
+	})
+	config.write(dst)
+
+	if err := RemoveKubeconfigEntries(src, dst); err != nil {
+		t.Fatalf("Expected removal to succeed, got %v", err)
+	}
+	if names := strings.Join(entryNames(t, dst, "clusters"), ","); names != "minikube,teleport.prod.company.com" {
+		t.Errorf("Expected the shared cluster to be kept, got %s", names)
+	}
+}
+
+func TestDefaultKubeconfigPath(t *testing.T) {
+	t.Setenv("KUBECONFIG", strings.Join([]string{"", "/tmp/a", "/tmp/b"}, string(filepath.ListSeparator)))
+	if path, err := DefaultKubeconfigPath(); err != nil || path != "/tmp/a" {
+		t.Errorf("Expected the first KUBECONFIG file, got %s (%v)", path, err)
+	}
+
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("HOME", "/home/alice")
+	if path, err := DefaultKubeconfigPath(); err != nil || path != filepath.Join("/home/alice", ".kube", "config") {
+		t.Errorf("Expected ~/.kube/config, got %s (%v)", path, err)
+	}
+}
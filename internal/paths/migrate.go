@@ -0,0 +1,138 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Migration records a file or directory moved from ~/.tkube to its new location
+type Migration struct {
+	From string
+	To   string
+}
+
+// MigrateLegacy moves the files of an existing ~/.tkube install to the locations chosen by Resolve,
+// including the named profiles below ~/.tkube/profiles and the selected profile.
+// Targets that already exist are never overwritten, and nothing is moved for TKUBE_HOME instances.
+func (p *Paths) MigrateLegacy() ([]Migration, error) {
+	if os.Getenv(EnvHome) != "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(p.LegacyDir); err != nil {
+		return nil, nil
+	}
+
+	// The default profile's locations, whichever profile is active
+	base := layout(filepath.Dir(p.ProfileFile), p.cacheDir, p.stateDir)
+
+	var moves []Migration
+	if p.ConfigFile == "" {
+		moves = append(moves, configMoves(p.LegacyDir, base.ConfigDir)...)
+	}
+	moves = append(moves, stateMoves(p.LegacyDir, base)...)
+	moves = append(moves,
+		Migration{From: filepath.Join(p.LegacyDir, "tsh"), To: base.TSHDir},
+		Migration{From: filepath.Join(p.LegacyDir, "profile"), To: base.ProfileFile},
+	)
+
+	// Named profiles keep their configuration and state side by side in ~/.tkube/profiles/<name>
+	legacyProfilesDir := filepath.Join(p.LegacyDir, "profiles")
+	entries, _ := os.ReadDir(legacyProfilesDir)
+	var profileDirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateProfileName(entry.Name()) != nil {
+			continue
+		}
+		named := layout(base.ConfigDir, p.cacheDir, p.stateDir)
+		named.applyProfile(entry.Name())
+
+		dir := filepath.Join(legacyProfilesDir, entry.Name())
+		profileDirs = append(profileDirs, dir)
+		moves = append(moves, configMoves(dir, named.ConfigDir)...)
+		moves = append(moves, stateMoves(dir, named)...)
+	}
+
+	var done []Migration
+	var errs []error
+	for _, move := range moves {
+		if move.From == move.To {
+			continue
+		}
+		if _, err := os.Stat(move.From); err != nil {
+			continue
+		}
+		if _, err := os.Stat(move.To); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(move.To), 0755); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create %s: %w", filepath.Dir(move.To), err))
+			continue
+		}
+		if err := os.Rename(move.From, move.To); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move %s to %s: %w", move.From, move.To, err))
+			continue
+		}
+		done = append(done, move)
+	}
+
+	// Drop the legacy directories once only stale lock files are left in them
+	if len(done) > 0 {
+		for _, dir := range profileDirs {
+			removeIfOnlyLocks(dir)
+		}
+		removeIfOnlyLocks(legacyProfilesDir)
+		removeIfOnlyLocks(p.LegacyDir)
+	}
+
+	return done, errors.Join(errs...)
+}
+
+// configMoves returns the moves of the configuration files in dir, including schema migration backups
+func configMoves(dir, configDir string) []Migration {
+	if dir == configDir {
+		return nil
+	}
+
+	var moves []Migration
+	matches, _ := filepath.Glob(filepath.Join(dir, "config.*"))
+	for _, match := range matches {
+		if strings.HasSuffix(match, ".lock") {
+			continue
+		}
+		moves = append(moves, Migration{From: match, To: filepath.Join(configDir, filepath.Base(match))})
+	}
+	return moves
+}
+
+// stateMoves returns the moves of the sessions, backups, team cache and kubeconfigs kept in dir
+func stateMoves(dir string, to *Paths) []Migration {
+	return []Migration{
+		{From: filepath.Join(dir, "sessions"), To: to.SessionsDir},
+		{From: filepath.Join(dir, "backups"), To: to.BackupsDir},
+		{From: filepath.Join(dir, "team"), To: to.TeamDir},
+		{From: filepath.Join(dir, "kube"), To: to.KubeDir},
+	}
+}
+
+// removeIfOnlyLocks removes dir if it contains nothing but lock files
+func removeIfOnlyLocks(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".lock") {
+			return
+		}
+	}
+
+	for _, entry := range entries {
+		os.Remove(filepath.Join(dir, entry.Name()))
+	}
+	os.Remove(dir)
+}
@@ -0,0 +1,209 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Environment variables that relocate tkube's files
+const (
+	// EnvHome places every tkube file below a single directory, e.g. for isolated test instances
+	EnvHome = "TKUBE_HOME"
+	// EnvConfig points at the user configuration file
+	EnvConfig = "TKUBE_CONFIG"
+	// EnvProfile selects a named profile, overriding the one chosen with 'tkube profile use'
+	EnvProfile = "TKUBE_PROFILE"
+)
+
+// DefaultProfile names the profile that uses the top-level configuration, sessions and backups
+const DefaultProfile = "default"
+
+// profileNamePattern matches names usable as profile directory names
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// legacyDirName is the directory in the home directory used by tkube before XDG support
+const legacyDirName = ".tkube"
+
+// appDirName is the tkube directory created inside the XDG base directories
+const appDirName = "tkube"
+
+// Paths holds the resolved locations of tkube's files
+type Paths struct {
+	// ConfigDir holds the user configuration file
+	ConfigDir string
+	// ConfigFile is the user configuration file set via TKUBE_CONFIG; empty means look it up in ConfigDir
+	ConfigFile string
+	// TSHDir holds the installed tsh versions (re-downloadable, so it lives in the cache directory)
+	TSHDir string
+	// SessionsDir holds the per-environment Teleport session directories
+	SessionsDir string
+	// BackupsDir holds previous versions of the user configuration
+	BackupsDir string
+	// TeamDir caches the shared team configuration (re-fetchable, so it lives in the cache directory)
+	TeamDir string
+	// LegacyDir is ~/.tkube, where existing installs keep their files
+	LegacyDir string
+	// Profile is the active named profile; empty for the default profile
+	Profile string
+	// ProfilesDir holds the configuration directories of the named profiles
+	ProfilesDir string
+	// ProfileFile stores the profile selected with 'tkube profile use'
+	ProfileFile string
+	// AgentSocket is the Unix socket the session agent serves its state on
+	AgentSocket string
+	// KubeDir holds the per-environment kubeconfig files written by 'tsh kube login'
+	KubeDir string
+
+	// cacheDir and stateDir are the base directories the profile's directories are placed in
+	cacheDir string
+	stateDir string
+}
+
+// Resolve determines tkube's file locations from the environment.
+//
+// TKUBE_HOME takes precedence and puts everything below one directory.
+// Otherwise ~/.tkube is used, except that a set XDG_CONFIG_HOME, XDG_CACHE_HOME
+// or XDG_STATE_HOME moves the configuration, the tsh installs or the sessions
+// and backups into a tkube directory below it. TKUBE_CONFIG overrides the
+// configuration file in either case.
+//
+// A named profile from TKUBE_PROFILE or 'tkube profile use' gets its own
+// configuration, sessions, backups and team cache below the profiles directories.
+func Resolve() (*Paths, error) {
+	return ResolveProfile("")
+}
+
+// ResolveProfile determines tkube's file locations like Resolve, using the named profile
+// instead of the selected one unless name is empty
+func ResolveProfile(name string) (*Paths, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil && os.Getenv(EnvHome) == "" {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	legacyDir := filepath.Join(homeDir, legacyDirName)
+
+	var p *Paths
+	if home := os.Getenv(EnvHome); home != "" {
+		p = layout(home, home, home)
+	} else {
+		configDir, cacheDir, stateDir := legacyDir, legacyDir, legacyDir
+		if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+			configDir = filepath.Join(dir, appDirName)
+		}
+		if dir := os.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+			cacheDir = filepath.Join(dir, appDirName)
+		}
+		if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+			stateDir = filepath.Join(dir, appDirName)
+		}
+		p = layout(configDir, cacheDir, stateDir)
+	}
+
+	p.LegacyDir = legacyDir
+	p.ConfigFile = os.Getenv(EnvConfig)
+
+	profile := name
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = p.SelectedProfile()
+	}
+	if err := p.applyProfile(profile); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// layout builds the file locations below the config, cache and state directories
+func layout(configDir, cacheDir, stateDir string) *Paths {
+	return &Paths{
+		ConfigDir:   configDir,
+		TSHDir:      filepath.Join(cacheDir, "tsh"),
+		SessionsDir: filepath.Join(stateDir, "sessions"),
+		BackupsDir:  filepath.Join(stateDir, "backups"),
+		TeamDir:     filepath.Join(cacheDir, "team"),
+		ProfilesDir: filepath.Join(configDir, "profiles"),
+		ProfileFile: filepath.Join(configDir, "profile"),
+		AgentSocket: filepath.Join(stateDir, "agent.sock"),
+		KubeDir:     filepath.Join(stateDir, "kube"),
+		cacheDir:    cacheDir,
+		stateDir:    stateDir,
+	}
+}
+
+// ValidateProfileName checks that name can be used as a profile name
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': use letters, digits, '-' and '_' and start with a letter or digit", name)
+	}
+	return nil
+}
+
+// SelectedProfile returns the profile stored by 'tkube profile use', or an empty string for the default profile
+func (p *Paths) SelectedProfile() string {
+	data, err := os.ReadFile(p.ProfileFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// applyProfile moves the configuration, sessions, backups, team cache, agent socket and kubeconfigs into the directories of a named profile.
+// The tsh installs are shared by all profiles.
+func (p *Paths) applyProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		return nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	p.Profile = name
+	p.ConfigDir = p.ProfileConfigDir(name)
+	p.SessionsDir = filepath.Join(p.stateDir, "profiles", name, "sessions")
+	p.BackupsDir = filepath.Join(p.stateDir, "profiles", name, "backups")
+	p.TeamDir = filepath.Join(p.cacheDir, "profiles", name, "team")
+	p.AgentSocket = filepath.Join(p.stateDir, "profiles", name, "agent.sock")
+	p.KubeDir = filepath.Join(p.stateDir, "profiles", name, "kube")
+	return nil
+}
+
+// ProfileConfigDir returns the directory holding the configuration of a named profile
+func (p *Paths) ProfileConfigDir(name string) string {
+	return filepath.Join(p.ProfilesDir, name)
+}
+
+// SessionDir returns the isolated Teleport session directory of an environment
+func (p *Paths) SessionDir(env string) string {
+	return filepath.Join(p.SessionsDir, env)
+}
+
+// KubeconfigDir returns the directory holding the kubeconfig files of an environment
+func (p *Paths) KubeconfigDir(env string) string {
+	return filepath.Join(p.KubeDir, env)
+}
+
+// KubeconfigPath returns the isolated kubeconfig of a Kubernetes cluster in an environment.
+// Without a cluster it returns the environment's own kubeconfig, which receives what tsh
+// writes outside of 'tsh kube login'.
+func (p *Paths) KubeconfigPath(env, cluster string) string {
+	if cluster == "" {
+		return filepath.Join(p.KubeconfigDir(env), "config")
+	}
+	// Cluster names come from Teleport and must not escape the environment's directory
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(cluster)
+	return filepath.Join(p.KubeconfigDir(env), name+".yaml")
+}
+
+// TSHPath returns the path of an installed tsh version
+func (p *Paths) TSHPath(version string) string {
+	return filepath.Join(p.TSHDir, version, "tsh")
+}
@@ -0,0 +1,253 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// clearEnv unsets every variable that influences path resolution
+func clearEnv(t *testing.T, homeDir string) {
+	t.Helper()
+	t.Setenv("HOME", homeDir)
+	for _, name := range []string{EnvHome, EnvConfig, EnvProfile, "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestResolve_Legacy(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	p, err := Resolve()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	legacyDir := filepath.Join(homeDir, ".tkube")
+	if p.ConfigDir != legacyDir {
+		t.Errorf("Expected config dir %s, got %s", legacyDir, p.ConfigDir)
+	}
+	if p.TSHDir != filepath.Join(legacyDir, "tsh") {
+		t.Errorf("Expected tsh dir below %s, got %s", legacyDir, p.TSHDir)
+	}
+	if p.SessionDir("prod") != filepath.Join(legacyDir, "sessions", "prod") {
+		t.Errorf("Unexpected session dir %s", p.SessionDir("prod"))
+	}
+	if p.KubeconfigPath("prod", "eks-prod") != filepath.Join(legacyDir, "kube", "prod", "eks-prod.yaml") {
+		t.Errorf("Unexpected kubeconfig %s", p.KubeconfigPath("prod", "eks-prod"))
+	}
+}
+
+func TestResolve_TKubeHome(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	tkubeHome := filepath.Join(homeDir, "isolated")
+	t.Setenv(EnvHome, tkubeHome)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, "xdg-config"))
+	t.Setenv(EnvConfig, filepath.Join(homeDir, "ci", "config.yaml"))
+
+	p, _ := Resolve()
+
+	if p.ConfigDir != tkubeHome || p.TSHDir != filepath.Join(tkubeHome, "tsh") || p.BackupsDir != filepath.Join(tkubeHome, "backups") {
+		t.Errorf("Expected everything below TKUBE_HOME, got %+v", p)
+	}
+	if p.ConfigFile != filepath.Join(homeDir, "ci", "config.yaml") {
+		t.Errorf("Expected TKUBE_CONFIG to be used, got %s", p.ConfigFile)
+	}
+}
+
+func TestResolve_XDG(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(homeDir, "cache"))
+	t.Setenv("XDG_STATE_HOME", "relative/state") // ignored, XDG requires absolute paths
+
+	p, _ := Resolve()
+
+	if p.ConfigDir != filepath.Join(homeDir, "config", "tkube") {
+		t.Errorf("Unexpected config dir %s", p.ConfigDir)
+	}
+	if p.TSHDir != filepath.Join(homeDir, "cache", "tkube", "tsh") {
+		t.Errorf("Unexpected tsh dir %s", p.TSHDir)
+	}
+	if p.SessionsDir != filepath.Join(homeDir, ".tkube", "sessions") {
+		t.Errorf("Expected sessions to stay in ~/.tkube, got %s", p.SessionsDir)
+	}
+}
+
+func TestResolve_Profile(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	t.Setenv("XDG_STATE_HOME", filepath.Join(homeDir, "state"))
+	t.Setenv(EnvProfile, "work")
+
+	p, err := Resolve()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	legacyDir := filepath.Join(homeDir, ".tkube")
+	if p.Profile != "work" {
+		t.Errorf("Expected profile work, got %q", p.Profile)
+	}
+	if p.ConfigDir != filepath.Join(legacyDir, "profiles", "work") {
+		t.Errorf("Unexpected config dir %s", p.ConfigDir)
+	}
+	if p.SessionDir("prod") != filepath.Join(homeDir, "state", "tkube", "profiles", "work", "sessions", "prod") {
+		t.Errorf("Unexpected session dir %s", p.SessionDir("prod"))
+	}
+	if p.TSHDir != filepath.Join(legacyDir, "tsh") {
+		t.Errorf("Expected tsh installs to be shared, got %s", p.TSHDir)
+	}
+	if p.AgentSocket != filepath.Join(homeDir, "state", "tkube", "profiles", "work", "agent.sock") {
+		t.Errorf("Expected an agent socket per profile, got %s", p.AgentSocket)
+	}
+	if p.KubeDir != filepath.Join(homeDir, "state", "tkube", "profiles", "work", "kube") {
+		t.Errorf("Expected kubeconfigs per profile, got %s", p.KubeDir)
+	}
+	if p.ProfileFile != filepath.Join(legacyDir, "profile") {
+		t.Errorf("Expected the profile file in the base config dir, got %s", p.ProfileFile)
+	}
+}
+
+func TestResolve_SelectedProfile(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	legacyDir := filepath.Join(homeDir, ".tkube")
+	os.MkdirAll(legacyDir, 0755)
+	os.WriteFile(filepath.Join(legacyDir, "profile"), []byte("customer\n"), 0644)
+
+	p, _ := Resolve()
+	if p.Profile != "customer" {
+		t.Errorf("Expected the selected profile, got %q", p.Profile)
+	}
+
+	// TKUBE_PROFILE wins over the selection, and "default" means no named profile
+	t.Setenv(EnvProfile, DefaultProfile)
+	p, _ = Resolve()
+	if p.Profile != "" || p.ConfigDir != legacyDir {
+		t.Errorf("Expected the default profile, got %q in %s", p.Profile, p.ConfigDir)
+	}
+
+	t.Setenv(EnvProfile, "../escape")
+	if _, err := Resolve(); err == nil {
+		t.Error("Expected an invalid profile name to be rejected")
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	legacyDir := filepath.Join(homeDir, ".tkube")
+	os.MkdirAll(filepath.Join(legacyDir, "tsh", "17.7.1"), 0755)
+	os.MkdirAll(filepath.Join(legacyDir, "sessions", "prod"), 0700)
+	os.WriteFile(filepath.Join(legacyDir, "config.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(legacyDir, "config.json.lock"), nil, 0600)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(homeDir, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(homeDir, "state"))
+
+	p, _ := Resolve()
+	moves, err := p.MigrateLegacy()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(moves) != 3 {
+		t.Errorf("Expected 3 moves, got %+v", moves)
+	}
+
+	for _, path := range []string{
+		filepath.Join(p.ConfigDir, "config.json"),
+		filepath.Join(p.TSHDir, "17.7.1"),
+		p.SessionDir("prod"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to exist after migration", path)
+		}
+	}
+	if _, err := os.Stat(legacyDir); !os.IsNotExist(err) {
+		t.Error("Expected the emptied legacy directory to be removed")
+	}
+
+	// Running again is a no-op
+	if moves, _ := p.MigrateLegacy(); len(moves) != 0 {
+		t.Errorf("Expected no moves on second run, got %+v", moves)
+	}
+}
+
+func TestMigrateLegacy_Profiles(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	legacyDir := filepath.Join(homeDir, ".tkube")
+	workDir := filepath.Join(legacyDir, "profiles", "work")
+	os.MkdirAll(filepath.Join(workDir, "sessions", "prod"), 0700)
+	os.WriteFile(filepath.Join(workDir, "config.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(legacyDir, "profile"), []byte("work\n"), 0644)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(homeDir, "state"))
+
+	// The selection is still in ~/.tkube, so the default profile is active until it is moved
+	p, _ := Resolve()
+	if _, err := p.MigrateLegacy(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	p, _ = Resolve()
+	if p.Profile != "work" {
+		t.Errorf("Expected the selected profile to be migrated, got %q", p.Profile)
+	}
+	for _, path := range []string{
+		filepath.Join(p.ConfigDir, "config.json"),
+		p.SessionDir("prod"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to exist after migration", path)
+		}
+	}
+	if p.ConfigDir != filepath.Join(homeDir, "config", "tkube", "profiles", "work") {
+		t.Errorf("Unexpected profile config dir %s", p.ConfigDir)
+	}
+	if _, err := os.Stat(legacyDir); !os.IsNotExist(err) {
+		t.Error("Expected the emptied legacy directory to be removed")
+	}
+}
+
+func TestMigrateLegacy_TKubeHome(t *testing.T) {
+	homeDir := t.TempDir()
+	clearEnv(t, homeDir)
+
+	os.MkdirAll(filepath.Join(homeDir, ".tkube", "tsh"), 0755)
+	t.Setenv(EnvHome, filepath.Join(homeDir, "isolated"))
+
+	p, _ := Resolve()
+	if moves, _ := p.MigrateLegacy(); len(moves) != 0 {
+		t.Errorf("Expected isolated instances to leave ~/.tkube alone, got %+v", moves)
+	}
+}
+
+func TestKubeconfigPath(t *testing.T) {
+	p := &Paths{KubeDir: "/state/kube"}
+
+	tests := map[string]string{
+		"":               "/state/kube/prod/config",
+		"eks-prod":       "/state/kube/prod/eks-prod.yaml",
+		"config":         "/state/kube/prod/config.yaml",
+		"team/eks-prod":  "/state/kube/prod/team_eks-prod.yaml",
+		"../../etc/kube": "/state/kube/prod/.._.._etc_kube.yaml",
+	}
+	for cluster, expected := range tests {
+		if path := p.KubeconfigPath("prod", cluster); path != filepath.FromSlash(expected) {
+			t.Errorf("KubeconfigPath(prod, %q) = %s, expected %s", cluster, path, expected)
+		}
+	}
+}
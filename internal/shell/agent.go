@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"tkube/internal/agent"
+	"tkube/internal/teleport"
+)
+
+// agentEnvironment returns the state of an environment known to a running session agent.
+// The agent is asked once per completion, so a missing agent costs a single failed connect.
+func (p *Provider) agentEnvironment(env string) (agent.EnvironmentState, bool) {
+	if !p.agentQueried {
+		p.agentQueried = true
+		if p.agentSocket != "" {
+			p.agentState, _ = agent.Query(p.agentSocket)
+		}
+	}
+	if p.agentState == nil {
+		return agent.EnvironmentState{}, false
+	}
+
+	envState, ok := p.agentState.Environments[env]
+	return envState, ok && envState.Session != nil
+}
+
+// sessionInfo returns the session of an environment from the agent, or from tsh if no agent knows it
+func (p *Provider) sessionInfo(env, proxy string) *teleport.SessionInfo {
+	if envState, ok := p.agentEnvironment(env); ok && envState.Proxy == proxy {
+		return envState.Session
+	}
+	return p.teleportClient.GetSessionInfo(env, proxy)
+}
+
+// clusters returns the clusters of an environment cached by the agent, or asks tsh
+func (p *Provider) clusters(env string) ([]string, error) {
+	if envState, ok := p.agentEnvironment(env); ok && envState.Session.IsValid() && envState.HasClusters() {
+		return envState.Clusters, nil
+	}
+	return p.teleportClient.GetClustersForCompletion(env)
+}
@@ -2,8 +2,12 @@ package shell
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
+	"tkube/internal/agent"
 	"tkube/internal/config"
+	"tkube/internal/paths"
 	"tkube/internal/teleport"
 )
 
@@ -11,6 +15,11 @@ import (
 type Provider struct {
 	configManager  *config.Manager
 	teleportClient *teleport.Client
+
+	// agentSocket is where a running session agent serves sessions and cluster lists
+	agentSocket  string
+	agentState   *agent.State
+	agentQueried bool
 }
 
 // CompletionItem represents a completion suggestion with contextual help
@@ -22,10 +31,14 @@ type CompletionItem struct {
 
 // NewProvider creates a new shell completion provider
 func NewProvider(configManager *config.Manager, teleportClient *teleport.Client) *Provider {
-	return &Provider{
+	provider := &Provider{
 		configManager:  configManager,
 		teleportClient: teleportClient,
 	}
+	if p, err := paths.Resolve(); err == nil {
+		provider.agentSocket = p.AgentSocket
+	}
+	return provider
 }
 
 // GetEnvironments returns a list of environment names for completion
@@ -63,7 +76,7 @@ func (p *Provider) GetEnvironmentsWithContext() []CompletionItem {
 	var items []CompletionItem
 	for env, envConfig := range config.Environments {
 		// Get authentication status for contextual description
-		sessionInfo := p.teleportClient.GetSessionInfo(env, envConfig.Proxy)
+		sessionInfo := p.sessionInfo(env, envConfig.Proxy)
 		
 		var description string
 		var category string
@@ -72,9 +85,10 @@ func (p *Provider) GetEnvironmentsWithContext() []CompletionItem {
 			if sessionInfo.IsExpired {
 				description = fmt.Sprintf("⏰ %s (session expired)", envConfig.Proxy)
 				category = "expired"
-			} else if sessionInfo.TimeRemaining != "" {
-				timeStr := p.formatTimeRemaining(sessionInfo.TimeRemaining)
-				if strings.Contains(timeStr, "h") && !strings.HasPrefix(timeStr, "1h") && !strings.HasPrefix(timeStr, "2h") {
+			} else if !sessionInfo.ValidUntil.IsZero() {
+				remaining := sessionInfo.TimeRemaining()
+				timeStr := p.formatTimeRemaining(remaining.Round(time.Minute).String())
+				if remaining >= 3*time.Hour {
 					description = fmt.Sprintf("✅ %s (%s left)", envConfig.Proxy, timeStr)
 					category = "authenticated"
 				} else {
@@ -102,7 +116,7 @@ func (p *Provider) GetEnvironmentsWithContext() []CompletionItem {
 
 // GetClusters returns a list of cluster names for a given environment
 func (p *Provider) GetClusters(env string) []string {
-	clusters, err := p.teleportClient.GetClustersForCompletion(env)
+	clusters, err := p.clusters(env)
 	if err != nil {
 		return nil
 	}
@@ -138,7 +152,7 @@ func (p *Provider) GetClustersWithContext(env string) []CompletionItem {
 	}
 
 	// Check authentication status
-	sessionInfo := p.teleportClient.GetSessionInfo(env, envConfig.Proxy)
+	sessionInfo := p.sessionInfo(env, envConfig.Proxy)
 	if !sessionInfo.IsAuthenticated || sessionInfo.IsExpired {
 		return []CompletionItem{
 			{
@@ -150,7 +164,7 @@ func (p *Provider) GetClustersWithContext(env string) []CompletionItem {
 	}
 
 	// Get clusters
-	clusters, err := p.teleportClient.GetClustersForCompletion(env)
+	clusters, err := p.clusters(env)
 	if err != nil {
 		return []CompletionItem{
 			{
@@ -192,9 +206,10 @@ func (p *Provider) GetClustersWithContext(env string) []CompletionItem {
 		description := fmt.Sprintf("🚀 Connect to %s/%s", env, cluster)
 		
 		// Add contextual information based on session time remaining
-		if sessionInfo.TimeRemaining != "" {
-			timeStr := p.formatTimeRemaining(sessionInfo.TimeRemaining)
-			if !strings.Contains(timeStr, "h") || strings.HasPrefix(timeStr, "1h") || strings.HasPrefix(timeStr, "2h") {
+		if !sessionInfo.ValidUntil.IsZero() {
+			remaining := sessionInfo.TimeRemaining()
+			if remaining < 3*time.Hour {
+				timeStr := p.formatTimeRemaining(remaining.Round(time.Minute).String())
 				description += fmt.Sprintf(" (session expires in %s)", timeStr)
 			}
 		}
@@ -206,12 +221,33 @@ func (p *Provider) GetClustersWithContext(env string) []CompletionItem {
 		})
 	}
 
+	// Aliases complete like clusters and show the real cluster name
+	for _, alias := range sortedAliases(envConfig.Aliases) {
+		items = append(items, CompletionItem{
+			Value:       alias,
+			Description: fmt.Sprintf("🔗 %s", envConfig.Aliases[alias]),
+			Category:    "alias",
+		})
+	}
+
 	return items
 }
 
+// sortedAliases returns the alias names of an environment in sorted order
+func sortedAliases(aliases map[string]string) []string {
+	names := make([]string, 0, len(aliases))
+	for alias, cluster := range aliases {
+		if cluster != "" {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetClustersWithPrefix returns a list of cluster names that match the given prefix
 func (p *Provider) GetClustersWithPrefix(env, prefix string) []string {
-	clusters, err := p.teleportClient.GetClustersForCompletion(env)
+	clusters, err := p.clusters(env)
 	if err != nil {
 		return nil
 	}
@@ -225,6 +261,11 @@ func (p *Provider) GetClustersWithPrefix(env, prefix string) []string {
 		return clusters
 	}
 
+	// Offer the environment's cluster aliases alongside the real names
+	if envConfig, err := p.configManager.GetEnvironment(env); err == nil {
+		clusters = append(clusters, sortedAliases(envConfig.Aliases)...)
+	}
+
 	if prefix == "" {
 		return clusters
 	}
@@ -263,7 +304,7 @@ func (p *Provider) GetCommandsWithContext() []CompletionItem {
 	if err == nil && len(config.Environments) > 0 {
 		authCount := 0
 		for env, envConfig := range config.Environments {
-			if p.teleportClient.CheckAuthenticationStatus(env, envConfig.Proxy) {
+			if p.sessionInfo(env, envConfig.Proxy).IsValid() {
 				authCount++
 			}
 		}
@@ -346,6 +387,15 @@ func (p *Provider) GetConfigSubcommands() []string {
 		"edit",
 		"remove",
 		"validate",
+		"migrate",
+		"history",
+		"restore",
+		"import-tsh",
+		"sync",
+		"get",
+		"set",
+		"unset",
+		"schema",
 	}
 }
 
@@ -427,6 +477,197 @@ func (p *Provider) GetConfigSubcommandsWithContext() []CompletionItem {
 		Category:    "check",
 	})
 
+	// Migrate command with dynamic description
+	migrateDesc := "🔄 Upgrade configuration to the current schema version"
+	if plan, err := p.configManager.PlanMigration(); err == nil && plan.NeedsMigration() {
+		migrateDesc = fmt.Sprintf("🔄 Upgrade configuration (schema v%d → v%d)", plan.From, plan.To)
+	}
+
+	items = append(items, CompletionItem{
+		Value:       "migrate",
+		Description: migrateDesc,
+		Category:    "modify",
+	})
+
+	// History and restore commands with dynamic descriptions
+	backups, backupErr := p.configManager.ListBackups()
+	historyDesc := "🕘 List previous configuration versions"
+	restoreDesc := "⏪ Restore a previous configuration version"
+	if backupErr == nil && len(backups) > 0 {
+		historyDesc = fmt.Sprintf("🕘 List previous configuration versions (%d stored)", len(backups))
+		restoreDesc = fmt.Sprintf("⏪ Restore a previous configuration version (latest: %s)", backups[0].ID)
+	} else if backupErr == nil {
+		restoreDesc = "⏪ Restore a previous configuration version (no backups yet)"
+	}
+
+	items = append(items, CompletionItem{
+		Value:       "history",
+		Description: historyDesc,
+		Category:    "view",
+	})
+
+	items = append(items, CompletionItem{
+		Value:       "restore",
+		Description: restoreDesc,
+		Category:    "modify",
+	})
+
+	// Import command, counting the tsh profiles available for import
+	importDesc := "📥 Create environments from existing tsh profiles"
+	if dir, err := teleport.DefaultTSHProfileDir(); err == nil {
+		if profiles, err := teleport.LoadTSHProfiles(dir); err == nil && len(profiles) > 0 {
+			importDesc = fmt.Sprintf("📥 Create environments from tsh profiles (%d found)", len(profiles))
+		}
+	}
+
+	items = append(items, CompletionItem{
+		Value:       "import-tsh",
+		Description: importDesc,
+		Category:    "modify",
+	})
+
+	// Sync command, naming the subscribed team configuration
+	syncDesc := "🔄 Refresh the shared team configuration"
+	if source, err := p.configManager.TeamSource(); err == nil && source != nil {
+		syncDesc = fmt.Sprintf("🔄 Refresh the team configuration from %s", source.Location())
+	} else if err == nil {
+		syncDesc = "🔄 Refresh the shared team configuration (none configured)"
+	}
+
+	items = append(items, CompletionItem{
+		Value:       "sync",
+		Description: syncDesc,
+		Category:    "modify",
+	})
+
+	items = append(items, CompletionItem{
+		Value:       "get",
+		Description: "🔍 Show the value of a configuration key",
+		Category:    "view",
+	})
+
+	items = append(items, CompletionItem{
+		Value:       "set",
+		Description: "✏️  Set a configuration key in your config",
+		Category:    "modify",
+	})
+
+	items = append(items, CompletionItem{
+		Value:       "unset",
+		Description: "🧹 Remove a configuration key from your config",
+		Category:    "modify",
+	})
+
+	items = append(items, CompletionItem{
+		Value:       "schema",
+		Description: "📐 Print the JSON Schema of the configuration file",
+		Category:    "view",
+	})
+
+	return items
+}
+
+// GetConfigBackupsWithContext returns configuration backup IDs with their timestamps
+func (p *Provider) GetConfigBackupsWithContext() []CompletionItem {
+	backups, err := p.configManager.ListBackups()
+	if err != nil {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, backup := range backups {
+		items = append(items, CompletionItem{
+			Value:       backup.ID,
+			Description: fmt.Sprintf("🕘 %s (%d bytes)", backup.Time.Format("2006-01-02 15:04:05"), backup.Size),
+			Category:    "backup",
+		})
+	}
+	return items
+}
+
+// GetTagSelectorsWithContext returns the key=value tags of all environments, with the environments carrying them
+func (p *Provider) GetTagSelectorsWithContext() []CompletionItem {
+	config, err := p.configManager.Load()
+	if err != nil {
+		return nil
+	}
+
+	matches := make(map[string][]string)
+	for env, envConfig := range config.Environments {
+		for key, value := range envConfig.Tags {
+			tag := key + "=" + value
+			matches[tag] = append(matches[tag], env)
+		}
+	}
+
+	var items []CompletionItem
+	for _, tag := range sortedKeys(matches) {
+		envs := matches[tag]
+		sort.Strings(envs)
+		items = append(items, CompletionItem{
+			Value:       tag,
+			Description: fmt.Sprintf("🏷️  %s", strings.Join(envs, ", ")),
+			Category:    "tag",
+		})
+	}
+	return items
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetConfigKeysWithContext returns the configuration keys below prefix with their type and current value
+func (p *Provider) GetConfigKeysWithContext(prefix string) []CompletionItem {
+	var items []CompletionItem
+	for _, key := range p.configManager.CompleteKeys(prefix) {
+		if !key.Leaf {
+			items = append(items, CompletionItem{
+				Value:       key.Path + ".",
+				Description: fmt.Sprintf("📂 %s", key.Type),
+				Category:    "object",
+			})
+			continue
+		}
+
+		description := fmt.Sprintf("🔑 %s", key.Type)
+		if value, layer, err := p.configManager.GetValue(key.Path); err == nil {
+			description = fmt.Sprintf("🔑 %s = %v (%s)", key.Type, value, layer)
+		}
+		items = append(items, CompletionItem{
+			Value:       key.Path,
+			Description: description,
+			Category:    "key",
+		})
+	}
+	return items
+}
+
+// GetProfilesWithContext returns the configuration profiles with their environment counts
+func (p *Provider) GetProfilesWithContext() []CompletionItem {
+	profiles, err := p.configManager.ListProfiles()
+	if err != nil {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, profile := range profiles {
+		description := fmt.Sprintf("👤 %d environments", profile.Environments)
+		if profile.Active {
+			description += " (active)"
+		}
+		items = append(items, CompletionItem{
+			Value:       profile.Name,
+			Description: description,
+			Category:    "profile",
+		})
+	}
 	return items
 }
 
@@ -483,7 +724,7 @@ func (p *Provider) GetSystemStatus() map[string]interface{} {
 		authCount := 0
 		expiredCount := 0
 		for env, envConfig := range config.Environments {
-			sessionInfo := p.teleportClient.GetSessionInfo(env, envConfig.Proxy)
+			sessionInfo := p.sessionInfo(env, envConfig.Proxy)
 			if sessionInfo.IsAuthenticated {
 				if sessionInfo.IsExpired {
 					expiredCount++
@@ -487,4 +487,17 @@ func TestProvider_EdgeCases(t *testing.T) {
 	// Test GetClusters with empty string
 	clusters = provider.GetClusters("")
 	t.Logf("Empty environment result: %v", clusters)
-}
\ No newline at end of file
+}
+
+func TestSortedAliases(t *testing.T) {
+	aliases := map[string]string{
+		"web":      "eks-prod-eu-west-1-web-green",
+		"payments": "eks-prod-eu-west-1-payments-blue",
+		"unset":    "",
+	}
+
+	got := sortedAliases(aliases)
+	if len(got) != 2 || got[0] != "payments" || got[1] != "web" {
+		t.Errorf("Expected sorted aliases without empty targets, got %v", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 	"time"
+	"tkube/internal/paths"
 )
 
 // TSHInstaller handles downloading and installing tsh clients
@@ -21,13 +22,17 @@ type TSHInstaller struct {
 
 // NewTSHInstaller creates a new tsh installer
 func NewTSHInstaller() (*TSHInstaller, error) {
-	homeDir, err := os.UserHomeDir()
+	p, err := paths.Resolve()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	baseDir := filepath.Join(homeDir, ".tkube", "tsh")
-	return &TSHInstaller{baseDir: baseDir}, nil
+	return &TSHInstaller{baseDir: p.TSHDir}, nil
+}
+
+// GetBaseDir returns the directory holding the installed tsh versions
+func (installer *TSHInstaller) GetBaseDir() string {
+	return installer.baseDir
 }
 
 // PackageInfo represents information about a Teleport package
@@ -232,7 +237,7 @@ func (installer *TSHInstaller) extractFromPkg(pkgPath, destDir string) error {
 	defer os.RemoveAll(tempDir)
 
 	// Use pkgutil to expand the .pkg file
-	fmt.Println("🔧 Extracting .pkg file...")
+	fmt.Fprintln(os.Stderr, "🔧 Extracting .pkg file...")
 	if err := installer.runShellCommand("pkgutil", "--expand-full", pkgPath, filepath.Join(tempDir, "extracted")); err != nil {
 		return fmt.Errorf("failed to extract pkg: %w", err)
 	}
@@ -0,0 +1,121 @@
+package teleport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"tkube/internal/config"
+)
+
+// ErrKubeAccessDenied is returned by KubeLoginWithEnv when the user may not access the cluster
+var ErrKubeAccessDenied = errors.New("access to the Kubernetes cluster was denied")
+
+// resourceRequestVersion is the first tsh release supporting 'tsh request search'
+const resourceRequestVersion = "12.0.0"
+
+// kubeDeniedMessages are lower-case fragments of tsh errors meaning the user lacks access to a cluster.
+// Teleport hides clusters the user's roles do not allow, so "not found" usually means the same.
+var kubeDeniedMessages = []string{
+	"access denied",
+	"permission denied",
+	"not authorized",
+	"forbidden",
+}
+
+// isKubeAccessDenied reports whether tsh kube login output says the user lacks access to a cluster
+func isKubeAccessDenied(output, cluster string) bool {
+	output = strings.ToLower(output)
+	for _, message := range kubeDeniedMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return strings.Contains(output, fmt.Sprintf("kubernetes cluster %q not found", strings.ToLower(cluster)))
+}
+
+// KubeAccess is a Kubernetes cluster the user may request access to
+type KubeAccess struct {
+	Cluster string
+	// ResourceID identifies the cluster in a resource access request, e.g. /teleport.example.com/kube_cluster/eks-prod
+	ResourceID string
+}
+
+// kubeResource is a Kubernetes cluster in the JSON output of 'tsh request search'
+type kubeResource struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+// parseKubeResources returns the names of the Kubernetes clusters in the output of 'tsh request search --format=json'
+func parseKubeResources(data []byte) ([]string, error) {
+	var resources []kubeResource
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return nil, fmt.Errorf("failed to parse requestable resources: %w", err)
+	}
+
+	var names []string
+	for _, resource := range resources {
+		if resource.Kind == "" || resource.Kind == "kube_cluster" {
+			names = append(names, resource.Metadata.Name)
+		}
+	}
+	return names, nil
+}
+
+// FindKubeAccess searches a Kubernetes cluster among the resources the user's roles allow requesting.
+// It returns nil if the cluster cannot be requested.
+func (c *Client) FindKubeAccess(env, proxy, cluster string) (*KubeAccess, error) {
+	envConfig, err := c.configManager.GetEnvironment(env)
+	if err != nil {
+		return nil, err
+	}
+	if version := envConfig.TSHVersion; version != "" && config.CompareVersions(version, resourceRequestVersion) < 0 {
+		return nil, fmt.Errorf("searching requestable clusters needs tsh %s or newer, but tsh %s is configured", resourceRequestVersion, version)
+	}
+
+	output, err := c.tshOutput(env, "--proxy="+proxy, "request", "search", "--kind=kube_cluster", "--search="+cluster, "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search requestable clusters: %w", err)
+	}
+
+	names, err := parseKubeResources(output)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name == cluster {
+			teleportCluster := c.GetSessionInfo(env, proxy).Cluster
+			if teleportCluster == "" {
+				return nil, fmt.Errorf("cannot determine the Teleport cluster of environment %s", env)
+			}
+			return &KubeAccess{Cluster: cluster, ResourceID: "/" + teleportCluster + "/kube_cluster/" + cluster}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RequestKubeAccess files a resource access request for a Kubernetes cluster.
+// Teleport picks the requestable roles granting the cluster, which the returned request lists.
+func (c *Client) RequestKubeAccess(env, proxy string, access *KubeAccess, reason string) (*AccessRequest, error) {
+	args := []string{"--proxy=" + proxy, "request", "create", "--resource=" + access.ResourceID, "--nowait"}
+	if reason != "" {
+		args = append(args, "--reason="+reason)
+	}
+
+	output, err := c.tshOutput(env, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	id, err := parseRequestID(string(output))
+	if err != nil {
+		return nil, err
+	}
+	if request, err := c.GetAccessRequest(env, proxy, id); err == nil {
+		return request, nil
+	}
+	return &AccessRequest{ID: id, State: RequestPending, Reason: reason}, nil
+}
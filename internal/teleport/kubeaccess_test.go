@@ -0,0 +1,49 @@
+package teleport
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsKubeAccessDenied(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{`ERROR: access denied to perform action "read" on "kube_cluster"`, true},
+		{"ERROR: kubernetes cluster \"eks-prod\" not found", true},
+		{"ERROR: Kubernetes cluster \"EKS-PROD\" not found", true},
+		{"ERROR: User alice is not authorized to access eks-prod", true},
+		{"ERROR: kubernetes cluster \"eks-test\" not found", false},
+		{"ERROR: dial tcp: lookup teleport.prod.company.com: no such host", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if denied := isKubeAccessDenied(test.output, "eks-prod"); denied != test.expected {
+			t.Errorf("isKubeAccessDenied(%q) = %v, expected %v", test.output, denied, test.expected)
+		}
+	}
+}
+
+func TestParseKubeResources(t *testing.T) {
+	output := []byte(`[
+  {"kind": "kube_cluster", "version": "v3", "metadata": {"name": "eks-prod", "labels": {"env": "prod"}}},
+  {"kind": "node", "version": "v2", "metadata": {"name": "bastion"}},
+  {"kind": "kube_cluster", "version": "v3", "metadata": {"name": "eks-prod-payments"}}
+]`)
+
+	names, err := parseKubeResources(output)
+	if err != nil {
+		t.Fatalf("Expected resources to parse, got %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"eks-prod", "eks-prod-payments"}) {
+		t.Errorf("Expected only Kubernetes clusters, got %v", names)
+	}
+
+	if names, err := parseKubeResources([]byte("[]")); err != nil || len(names) != 0 {
+		t.Errorf("Expected no resources, got %v (%v)", names, err)
+	}
+	if _, err := parseKubeResources([]byte("Kind  Name")); err == nil {
+		t.Error("Expected an error for table output")
+	}
+}
@@ -0,0 +1,137 @@
+package teleport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tkube/internal/kubectl"
+)
+
+// SetEnviron returns environ with the KEY=value entries of vars, dropping any existing entries
+// for the same keys so every variable is set exactly once
+func SetEnviron(environ []string, vars ...string) []string {
+	keys := make(map[string]bool, len(vars))
+	for _, entry := range vars {
+		key, _, _ := strings.Cut(entry, "=")
+		keys[key] = true
+	}
+
+	result := make([]string, 0, len(environ)+len(vars))
+	for _, entry := range environ {
+		if key, _, _ := strings.Cut(entry, "="); !keys[key] {
+			result = append(result, entry)
+		}
+	}
+	return append(result, vars...)
+}
+
+// tshEnviron returns the environment tsh runs with for an environment: its isolated session
+// directory and kubeconfig, so tsh never writes to the global ~/.kube/config
+func (c *Client) tshEnviron(env string) []string {
+	return SetEnviron(os.Environ(),
+		"TELEPORT_HOME="+c.getSessionDir(env),
+		"KUBECONFIG="+c.paths.KubeconfigPath(env, ""),
+	)
+}
+
+// KubeconfigPath returns the isolated kubeconfig 'tkube <env> <cluster>' writes the cluster's context to
+func (c *Client) KubeconfigPath(env, cluster string) string {
+	return c.paths.KubeconfigPath(env, cluster)
+}
+
+// ensureKubeconfigDir creates the kubeconfig directory of an environment if it doesn't exist
+func (c *Client) ensureKubeconfigDir(env string) error {
+	return os.MkdirAll(c.paths.KubeconfigDir(env), 0700)
+}
+
+// mergedFileName lists, in an environment's kubeconfig directory, the global kubeconfigs its contexts were merged into
+const mergedFileName = "merged"
+
+// RecordMerge notes that the contexts of an environment were merged into globalPath, so
+// RemoveKubeconfigs cleans them up even if merging has been turned off in the meantime
+func (c *Client) RecordMerge(env, globalPath string) error {
+	for _, path := range c.mergedPaths(env) {
+		if path == globalPath {
+			return nil
+		}
+	}
+	if err := c.ensureKubeconfigDir(env); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(c.paths.KubeconfigDir(env), mergedFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintln(file, globalPath)
+	return err
+}
+
+// mergedPaths returns the global kubeconfigs recorded by RecordMerge
+func (c *Client) mergedPaths(env string) []string {
+	data, err := os.ReadFile(filepath.Join(c.paths.KubeconfigDir(env), mergedFileName))
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// RemoveKubeconfigs deletes the kubeconfig files of an environment, along with the contexts
+// merged from them into a global kubeconfig: every one recorded by RecordMerge, and globalPath
+// unless it is empty.
+func (c *Client) RemoveKubeconfigs(env, globalPath string) error {
+	// Never let an odd environment name point outside the kubeconfig directory
+	if env == "" || env == "." || env == ".." || filepath.Base(env) != env {
+		return fmt.Errorf("invalid environment name '%s'", env)
+	}
+
+	globalPaths := c.mergedPaths(env)
+	if globalPath != "" {
+		globalPaths = append(globalPaths, globalPath)
+	}
+
+	dir := c.paths.KubeconfigDir(env)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	files = append(files, c.paths.KubeconfigPath(env, ""))
+	for _, global := range globalPaths {
+		for _, file := range files {
+			if err := kubectl.RemoveKubeconfigEntries(file, global); err != nil {
+				return fmt.Errorf("failed to clean up %s: %w", global, err)
+			}
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove kubeconfig directory: %w", err)
+	}
+	return nil
+}
+
+// KubeEnviron returns the environment for running tools against a cluster of an environment:
+// its isolated session directory and kubeconfig, and its pinned tsh first on PATH, so kubectl's
+// exec credential plugin and any tsh call use the same session
+func (c *Client) KubeEnviron(env, cluster string) ([]string, error) {
+	tshPath := c.getTSHPath(env)
+	if tshPath == "" {
+		return nil, fmt.Errorf("no tsh path available for environment %s", env)
+	}
+
+	path := filepath.Dir(tshPath)
+	if current := os.Getenv("PATH"); current != "" {
+		path += string(os.PathListSeparator) + current
+	}
+
+	return SetEnviron(c.tshEnviron(env),
+		"KUBECONFIG="+c.KubeconfigPath(env, cluster),
+		"PATH="+path,
+	), nil
+}
@@ -0,0 +1,198 @@
+package teleport
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TSHProfile is a login profile written by plain tsh or Teleport Connect to ~/.tsh/<proxy host>.yaml
+type TSHProfile struct {
+	// Name is the profile file name, which tsh derives from the proxy host
+	Name        string
+	Proxy       string
+	User        string
+	Cluster     string
+	KubeCluster string
+	// ValidUntil is the expiry of the profile's TLS certificate; zero if there is no certificate
+	ValidUntil time.Time
+}
+
+// tshProfileFile mirrors the fields tkube reads from a tsh profile file
+type tshProfileFile struct {
+	WebProxyAddr string `yaml:"web_proxy_addr"`
+	User         string `yaml:"user"`
+	Cluster      string `yaml:"cluster"`
+	KubeCluster  string `yaml:"kube_cluster"`
+}
+
+// IsValid reports whether the profile's certificate has not expired yet
+func (p *TSHProfile) IsValid() bool {
+	return !p.ValidUntil.IsZero() && time.Now().Before(p.ValidUntil)
+}
+
+// DefaultTSHProfileDir returns the directory plain tsh keeps its profiles in: TELEPORT_HOME or ~/.tsh
+func DefaultTSHProfileDir() (string, error) {
+	if dir := os.Getenv("TELEPORT_HOME"); dir != "" {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".tsh"), nil
+}
+
+// LoadTSHProfiles reads all tsh profiles in dir, sorted by name
+func LoadTSHProfiles(dir string) ([]TSHProfile, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []TSHProfile
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tsh profile: %w", err)
+		}
+
+		var raw tshProfileFile
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse tsh profile %s: %w", file, err)
+		}
+		if raw.WebProxyAddr == "" {
+			continue
+		}
+
+		profile := TSHProfile{
+			Name:        strings.TrimSuffix(filepath.Base(file), ".yaml"),
+			Proxy:       raw.WebProxyAddr,
+			User:        raw.User,
+			Cluster:     raw.Cluster,
+			KubeCluster: raw.KubeCluster,
+		}
+		profile.ValidUntil, _ = certificateExpiry(filepath.Join(profileKeyDir(dir, profile), profile.User+"-x509.pem"))
+
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// ImportSession copies a still-valid tsh profile and its keys into the isolated session
+// directory of an environment, so the environment can be used without logging in again
+func (c *Client) ImportSession(env, dir string, profile TSHProfile) error {
+	if !profile.IsValid() {
+		return fmt.Errorf("session of profile %s has expired", profile.Name)
+	}
+
+	// The environment may not be in the configuration yet, so guard the name here too
+	if env == "" || env == "." || env == ".." || filepath.Base(env) != env {
+		return fmt.Errorf("invalid environment name '%s'", env)
+	}
+	if err := c.ensureSessionDir(env); err != nil {
+		return fmt.Errorf("failed to create session directory for environment %s: %w", env, err)
+	}
+	sessionDir := c.getSessionDir(env)
+
+	if err := copyFile(filepath.Join(dir, profile.Name+".yaml"), filepath.Join(sessionDir, profile.Name+".yaml")); err != nil {
+		return fmt.Errorf("failed to copy tsh profile: %w", err)
+	}
+	if err := copyDir(profileKeyDir(dir, profile), profileKeyDir(sessionDir, profile)); err != nil {
+		return fmt.Errorf("failed to copy tsh keys: %w", err)
+	}
+
+	// Trusted host keys are optional, tsh re-fetches them on the next login
+	if err := copyFile(filepath.Join(dir, "known_hosts"), filepath.Join(sessionDir, "known_hosts")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to copy known hosts: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(sessionDir, "current-profile"), []byte(profile.Name), 0600); err != nil {
+		return fmt.Errorf("failed to set current profile: %w", err)
+	}
+	return nil
+}
+
+// profileKeyDir returns the directory holding the keys and certificates of a profile
+func profileKeyDir(dir string, profile TSHProfile) string {
+	return filepath.Join(dir, "keys", profile.Name)
+}
+
+// certificateExpiry returns the NotAfter time of the first certificate in a PEM file
+func certificateExpiry(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no certificate found in %s", path)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+	}
+	return cert.NotAfter, nil
+}
+
+// copyDir recursively copies a directory, keeping file modes
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies a regular file, keeping its mode
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
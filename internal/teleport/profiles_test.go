@@ -0,0 +1,76 @@
+package teleport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"tkube/internal/teleport/teleporttest"
+)
+
+func TestLoadTSHProfiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := teleporttest.WriteTSHProfile(dir, "teleport.prod.company.com", "web_proxy_addr: teleport.prod.company.com:443\nuser: alice\ncluster: prod\nkube_cluster: eks-prod\n", "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+	if err := teleporttest.WriteTSHProfile(dir, "teleport.test.company.com", "web_proxy_addr: teleport.test.company.com:443\nuser: alice\ncluster: test\n", "alice", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "empty.yaml"), []byte("user: nobody\n"), 0600)
+
+	profiles, err := LoadTSHProfiles(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("Expected 2 profiles, got %d", len(profiles))
+	}
+
+	prod := profiles[0]
+	if prod.Name != "teleport.prod.company.com" || prod.Proxy != "teleport.prod.company.com:443" {
+		t.Errorf("Unexpected profile %+v", prod)
+	}
+	if prod.User != "alice" || prod.Cluster != "prod" || prod.KubeCluster != "eks-prod" {
+		t.Errorf("Unexpected profile fields %+v", prod)
+	}
+	if !prod.IsValid() {
+		t.Error("Expected prod session to be valid")
+	}
+	if profiles[1].IsValid() {
+		t.Error("Expected test session to be expired")
+	}
+}
+
+func TestClient_ImportSession(t *testing.T) {
+	t.Setenv("TKUBE_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := teleporttest.WriteTSHProfile(dir, "teleport.prod.company.com", "web_proxy_addr: teleport.prod.company.com:443\nuser: alice\n", "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+
+	client, err := NewClient(nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	profiles, _ := LoadTSHProfiles(dir)
+	if err := client.ImportSession("prod", dir, profiles[0]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sessionDir := client.getSessionDir("prod")
+	for _, file := range []string{"teleport.prod.company.com.yaml", "current-profile", "keys/teleport.prod.company.com/alice-x509.pem"} {
+		if _, err := os.Stat(filepath.Join(sessionDir, file)); err != nil {
+			t.Errorf("Expected %s to be copied: %v", file, err)
+		}
+	}
+
+	if err := client.ImportSession("../escape", dir, profiles[0]); err == nil {
+		t.Error("Expected error for an environment name outside the sessions directory")
+	}
+
+	profiles[0].ValidUntil = time.Now().Add(-time.Minute)
+	if err := client.ImportSession("expired", dir, profiles[0]); err == nil {
+		t.Error("Expected error for an expired session")
+	}
+}
@@ -0,0 +1,276 @@
+package teleport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access request states as reported by tsh
+const (
+	RequestPending  = "PENDING"
+	RequestApproved = "APPROVED"
+	RequestDenied   = "DENIED"
+	RequestPromoted = "PROMOTED"
+)
+
+// requestStates maps the numeric states of older tsh JSON output to their names
+var requestStates = map[int]string{
+	0: "NONE",
+	1: RequestPending,
+	2: RequestApproved,
+	3: RequestDenied,
+	4: RequestPromoted,
+}
+
+// requestIDPattern finds the ID in the output of 'tsh request create'
+var requestIDPattern = regexp.MustCompile(`(?m)^\s*Request ID:\s+(\S+)`)
+
+// AccessRequest is a Teleport access request for additional roles
+type AccessRequest struct {
+	ID            string    `json:"id"`
+	User          string    `json:"user"`
+	Roles         []string  `json:"roles"`
+	State         string    `json:"state"`
+	Reason        string    `json:"reason,omitempty"`
+	ResolveReason string    `json:"resolve_reason,omitempty"`
+	Created       time.Time `json:"created"`
+	// Expires is when the access granted by the request ends
+	Expires time.Time `json:"expires"`
+}
+
+// IsPending reports whether the request still waits for reviews
+func (r *AccessRequest) IsPending() bool {
+	return r.State == RequestPending
+}
+
+// IsApproved reports whether the request was approved and can be assumed
+func (r *AccessRequest) IsApproved() bool {
+	return r.State == RequestApproved
+}
+
+// requestState is an access request state tsh prints either as a name or a number
+type requestState string
+
+// UnmarshalJSON accepts both "APPROVED" and 2
+func (s *requestState) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = requestState(strings.ToUpper(name))
+		return nil
+	}
+
+	var number int
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("invalid access request state %s", data)
+	}
+	if name, ok := requestStates[number]; ok {
+		*s = requestState(name)
+		return nil
+	}
+	*s = requestState(strconv.Itoa(number))
+	return nil
+}
+
+// requestResource is an access request in the JSON output of 'tsh request ls' and 'tsh request show'
+type requestResource struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		User          string       `json:"user"`
+		Roles         []string     `json:"roles"`
+		State         requestState `json:"state"`
+		Created       time.Time    `json:"created"`
+		Expires       time.Time    `json:"expires"`
+		RequestReason string       `json:"request_reason"`
+		ResolveReason string       `json:"resolve_reason"`
+	} `json:"spec"`
+}
+
+// toAccessRequest converts the tsh representation of a request
+func (r requestResource) toAccessRequest() AccessRequest {
+	return AccessRequest{
+		ID:            r.Metadata.Name,
+		User:          r.Spec.User,
+		Roles:         r.Spec.Roles,
+		State:         string(r.Spec.State),
+		Reason:        r.Spec.RequestReason,
+		ResolveReason: r.Spec.ResolveReason,
+		Created:       r.Spec.Created,
+		Expires:       r.Spec.Expires,
+	}
+}
+
+// parseAccessRequests parses the output of 'tsh request ls --format=json'
+func parseAccessRequests(data []byte) ([]AccessRequest, error) {
+	var resources []requestResource
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return nil, fmt.Errorf("failed to parse access requests: %w", err)
+	}
+
+	requests := make([]AccessRequest, 0, len(resources))
+	for _, resource := range resources {
+		requests = append(requests, resource.toAccessRequest())
+	}
+	return requests, nil
+}
+
+// parseAccessRequest parses the output of 'tsh request show --format=json', which is a single
+// request or, in some releases, a list holding it
+func parseAccessRequest(data []byte) (*AccessRequest, error) {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		requests, err := parseAccessRequests(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(requests) == 0 {
+			return nil, fmt.Errorf("access request not found")
+		}
+		return &requests[0], nil
+	}
+
+	var resource requestResource
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, fmt.Errorf("failed to parse access request: %w", err)
+	}
+	request := resource.toAccessRequest()
+	return &request, nil
+}
+
+// parseRequestID returns the request ID printed by 'tsh request create'
+func parseRequestID(output string) (string, error) {
+	match := requestIDPattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("no request ID in tsh output: %s", strings.TrimSpace(output))
+	}
+	return match[1], nil
+}
+
+// tshCommand prepares a tsh command for an environment, using its pinned tsh version and
+// isolated session directory
+func (c *Client) tshCommand(env string, args ...string) (*exec.Cmd, error) {
+	if err := c.EnsureTSHVersion(env); err != nil {
+		return nil, fmt.Errorf("failed to ensure tsh version for environment %s: %w", env, err)
+	}
+
+	tshPath := c.getTSHPath(env)
+	if tshPath == "" {
+		return nil, fmt.Errorf("no tsh path available for environment %s", env)
+	}
+
+	// Ensure session directory exists
+	if err := c.ensureSessionDir(env); err != nil {
+		return nil, fmt.Errorf("failed to create session directory for environment %s: %w", env, err)
+	}
+
+	cmd := exec.Command(tshPath, args...)
+	cmd.Env = c.tshEnviron(env)
+	return cmd, nil
+}
+
+// tshOutput runs tsh for an environment and returns its output, with tsh's error message on failure
+func (c *Client) tshOutput(env string, args ...string) ([]byte, error) {
+	cmd, err := c.tshCommand(env, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateAccessRequest files an access request for roles without waiting for it to be reviewed
+func (c *Client) CreateAccessRequest(env, proxy string, roles []string, reason string) (*AccessRequest, error) {
+	args := []string{"--proxy=" + proxy, "request", "create", "--roles=" + strings.Join(roles, ","), "--nowait"}
+	if reason != "" {
+		args = append(args, "--reason="+reason)
+	}
+
+	output, err := c.tshOutput(env, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	id, err := parseRequestID(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	// The listing has the details tsh does not print in a parseable form
+	if request, err := c.GetAccessRequest(env, proxy, id); err == nil {
+		return request, nil
+	}
+	return &AccessRequest{ID: id, Roles: roles, State: RequestPending, Reason: reason}, nil
+}
+
+// ListAccessRequests returns the access requests visible to the user of an environment
+func (c *Client) ListAccessRequests(env, proxy string) ([]AccessRequest, error) {
+	output, err := c.tshOutput(env, "--proxy="+proxy, "request", "ls", "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access requests: %w", err)
+	}
+	return parseAccessRequests(output)
+}
+
+// GetAccessRequest returns a single access request
+func (c *Client) GetAccessRequest(env, proxy, id string) (*AccessRequest, error) {
+	output, err := c.tshOutput(env, "--proxy="+proxy, "request", "show", id, "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request %s: %w", id, err)
+	}
+	return parseAccessRequest(output)
+}
+
+// WaitForAccessRequest polls an access request until it is reviewed or ctx is done
+func (c *Client) WaitForAccessRequest(ctx context.Context, env, proxy, id string, interval time.Duration) (*AccessRequest, error) {
+	return waitForRequest(ctx, interval, func() (*AccessRequest, error) {
+		return c.GetAccessRequest(env, proxy, id)
+	})
+}
+
+// waitForRequest calls get every interval until the request is no longer pending
+func waitForRequest(ctx context.Context, interval time.Duration, get func() (*AccessRequest, error)) (*AccessRequest, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		request, err := get()
+		if err != nil {
+			return nil, err
+		}
+		if !request.IsPending() {
+			return request, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return request, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// LoginWithRequest re-issues the session certificates of an environment with the roles of an approved access request
+func (c *Client) LoginWithRequest(env, proxy, id string) error {
+	cmd, err := c.tshCommand(env, "login", "--proxy="+proxy, "--user="+c.getEffectiveUser(env), "--request-id="+id)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,157 @@
+package teleport
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const requestListJSON = `[
+  {
+    "kind": "access_request",
+    "version": "v3",
+    "metadata": {"name": "5a2b1c9e-0000-4000-8000-000000000001"},
+    "spec": {
+      "user": "alice",
+      "roles": ["dba"],
+      "state": 1,
+      "created": "2025-08-15T09:00:00Z",
+      "expires": "2025-08-15T21:00:00Z",
+      "request_reason": "INC-1234"
+    }
+  },
+  {
+    "kind": "access_request",
+    "version": "v3",
+    "metadata": {"name": "5a2b1c9e-0000-4000-8000-000000000002"},
+    "spec": {
+      "user": "bob",
+      "roles": ["prod-admin", "dba"],
+      "state": "DENIED",
+      "created": "2025-08-14T09:00:00Z",
+      "resolve_reason": "use the dba role"
+    }
+  }
+]`
+
+func TestParseAccessRequests(t *testing.T) {
+	requests, err := parseAccessRequests([]byte(requestListJSON))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+
+	expected := AccessRequest{
+		ID:      "5a2b1c9e-0000-4000-8000-000000000001",
+		User:    "alice",
+		Roles:   []string{"dba"},
+		State:   RequestPending,
+		Reason:  "INC-1234",
+		Created: time.Date(2025, 8, 15, 9, 0, 0, 0, time.UTC),
+		Expires: time.Date(2025, 8, 15, 21, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(requests[0], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, requests[0])
+	}
+	if !requests[0].IsPending() {
+		t.Error("Expected numeric state 1 to be pending")
+	}
+
+	if requests[1].State != RequestDenied || requests[1].ResolveReason != "use the dba role" {
+		t.Errorf("Expected a denied request with review reason, got %+v", requests[1])
+	}
+}
+
+func TestParseAccessRequest(t *testing.T) {
+	single := `{"metadata": {"name": "abc"}, "spec": {"user": "alice", "roles": ["dba"], "state": 2}}`
+	request, err := parseAccessRequest([]byte(single))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if request.ID != "abc" || !request.IsApproved() {
+		t.Errorf("Expected approved request abc, got %+v", request)
+	}
+
+	request, err = parseAccessRequest([]byte(requestListJSON))
+	if err != nil {
+		t.Fatalf("Expected no error for a list, got %v", err)
+	}
+	if request.ID != "5a2b1c9e-0000-4000-8000-000000000001" {
+		t.Errorf("Expected the first request of a list, got %+v", request)
+	}
+
+	if _, err := parseAccessRequest([]byte("[]")); err == nil {
+		t.Error("Expected error for an empty list")
+	}
+	if _, err := parseAccessRequest([]byte(`{"spec": {"state": true}}`)); err == nil {
+		t.Error("Expected error for an invalid state")
+	}
+}
+
+func TestParseRequestID(t *testing.T) {
+	output := `Creating request...
+Request ID:     5a2b1c9e-0000-4000-8000-000000000001
+Username:       alice
+Roles:          dba
+Reason:         INC-1234
+Reviewers:      [none] (suggested)
+Status:         PENDING
+
+hint: use 'tsh login --request-id=<request-id>' to login with an approved request
+`
+	id, err := parseRequestID(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if id != "5a2b1c9e-0000-4000-8000-000000000001" {
+		t.Errorf("Unexpected request ID %q", id)
+	}
+
+	if _, err := parseRequestID("ERROR: access denied"); err == nil {
+		t.Error("Expected error without a request ID")
+	}
+}
+
+func TestWaitForRequest(t *testing.T) {
+	states := []string{RequestPending, RequestPending, RequestApproved}
+	calls := 0
+	request, err := waitForRequest(context.Background(), time.Millisecond, func() (*AccessRequest, error) {
+		state := states[calls]
+		calls++
+		return &AccessRequest{ID: "abc", State: state}, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !request.IsApproved() || calls != 3 {
+		t.Errorf("Expected approval after 3 checks, got %+v after %d", request, calls)
+	}
+}
+
+func TestWaitForRequest_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	request, err := waitForRequest(ctx, time.Millisecond, func() (*AccessRequest, error) {
+		return &AccessRequest{ID: "abc", State: RequestPending}, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if request == nil || !request.IsPending() {
+		t.Errorf("Expected the pending request to be returned, got %+v", request)
+	}
+}
+
+func TestWaitForRequest_Error(t *testing.T) {
+	_, err := waitForRequest(context.Background(), time.Millisecond, func() (*AccessRequest, error) {
+		return nil, errors.New("tsh failed")
+	})
+	if err == nil {
+		t.Error("Expected the error of the check to be returned")
+	}
+}
@@ -0,0 +1,259 @@
+package teleport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+	"tkube/internal/config"
+)
+
+// statusJSONVersion is the first tsh release supporting 'tsh status --format=json'
+const statusJSONVersion = "10.0.0"
+
+// statusTimeLayout is how older tsh releases print the "Valid until" time
+const statusTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// validForPattern matches the remaining session time tsh prints after the expiry, e.g. "[valid for 11h29m0s]"
+var validForPattern = regexp.MustCompile(`\[valid for ([0-9hms.]+)\]`)
+
+// SessionInfo represents session information for an environment
+type SessionInfo struct {
+	IsAuthenticated bool `json:"authenticated"`
+	IsExpired       bool `json:"expired"`
+	// ValidUntil is the expiry of the session's certificates; zero if unknown
+	ValidUntil time.Time `json:"valid_until"`
+	// ProfileURL is the proxy the session belongs to, e.g. https://teleport.company.com:443
+	ProfileURL string `json:"profile_url,omitempty"`
+	// Cluster is the name of the Teleport cluster
+	Cluster  string   `json:"cluster,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Logins   []string `json:"logins,omitempty"`
+	// KubeEnabled is set if the cluster has Kubernetes access enabled
+	KubeEnabled bool `json:"kubernetes_enabled,omitempty"`
+	// KubeCluster is the Kubernetes cluster selected with 'tsh kube login'
+	KubeCluster string   `json:"kubernetes_cluster,omitempty"`
+	KubeUsers   []string `json:"kubernetes_users,omitempty"`
+	KubeGroups  []string `json:"kubernetes_groups,omitempty"`
+	// ActiveRequests are the IDs of the access requests assumed by the session
+	ActiveRequests []string `json:"active_requests,omitempty"`
+}
+
+// TimeRemaining returns how long the session is still valid; zero if it has expired or the expiry is unknown
+func (s *SessionInfo) TimeRemaining() time.Duration {
+	if s.ValidUntil.IsZero() {
+		return 0
+	}
+	if remaining := time.Until(s.ValidUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// IsValid reports whether the session is authenticated and not expired
+func (s *SessionInfo) IsValid() bool {
+	return s.IsAuthenticated && !s.IsExpired
+}
+
+// NeedsRenewal reports whether a valid session has less than threshold left; a zero threshold never renews
+func (s *SessionInfo) NeedsRenewal(threshold time.Duration) bool {
+	if threshold <= 0 || !s.IsValid() || s.ValidUntil.IsZero() {
+		return false
+	}
+	return s.TimeRemaining() < threshold
+}
+
+// statusProfile is a profile in the JSON output of 'tsh status'
+type statusProfile struct {
+	ProfileURL        string   `json:"profile_url"`
+	Username          string   `json:"username"`
+	Cluster           string   `json:"cluster"`
+	Roles             []string `json:"roles"`
+	Logins            []string `json:"logins"`
+	KubernetesEnabled bool     `json:"kubernetes_enabled"`
+	KubernetesCluster string   `json:"kubernetes_cluster"`
+	KubernetesUsers   []string `json:"kubernetes_users"`
+	KubernetesGroups  []string `json:"kubernetes_groups"`
+	ValidUntil        string   `json:"valid_until"`
+	ActiveRequests    []string `json:"active_requests"`
+}
+
+// statusOutput is the JSON output of 'tsh status --format=json'
+type statusOutput struct {
+	Active   *statusProfile  `json:"active"`
+	Profiles []statusProfile `json:"profiles"`
+}
+
+// parseStatusJSON parses the output of 'tsh status --format=json'
+func parseStatusJSON(data []byte, now time.Time) (*SessionInfo, error) {
+	var output statusOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse tsh status: %w", err)
+	}
+
+	info := &SessionInfo{}
+	if output.Active == nil || output.Active.Username == "" {
+		return info, nil
+	}
+
+	active := output.Active
+	info.IsAuthenticated = true
+	info.ProfileURL = active.ProfileURL
+	info.Cluster = active.Cluster
+	info.Username = active.Username
+	info.Roles = active.Roles
+	info.Logins = active.Logins
+	info.KubeEnabled = active.KubernetesEnabled
+	info.KubeCluster = active.KubernetesCluster
+	info.KubeUsers = active.KubernetesUsers
+	info.KubeGroups = active.KubernetesGroups
+	info.ActiveRequests = active.ActiveRequests
+
+	if active.ValidUntil != "" {
+		validUntil, err := time.Parse(time.RFC3339Nano, active.ValidUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse session expiry '%s': %w", active.ValidUntil, err)
+		}
+		info.ValidUntil = validUntil
+		info.IsExpired = !now.Before(validUntil)
+	}
+
+	return info, nil
+}
+
+// parseStatusText parses the human readable output of 'tsh status' printed by tsh releases without JSON support
+func parseStatusText(output string, now time.Time) *SessionInfo {
+	info := &SessionInfo{}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ">")), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Profile URL":
+			// Only the first (active) profile is of interest
+			if info.ProfileURL != "" {
+				return info
+			}
+			info.ProfileURL = value
+		case "Logged in as":
+			info.IsAuthenticated = true
+			info.Username = value
+		case "Cluster":
+			info.Cluster = value
+		case "Roles":
+			info.Roles = splitStatusList(value)
+		case "Logins":
+			info.Logins = splitStatusList(value)
+		case "Kubernetes":
+			info.KubeEnabled = value == "enabled"
+		case "Kubernetes cluster":
+			info.KubeCluster = strings.Trim(value, `"`)
+		case "Kubernetes users":
+			info.KubeUsers = splitStatusList(value)
+		case "Kubernetes groups":
+			info.KubeGroups = splitStatusList(value)
+		case "Valid until":
+			info.IsAuthenticated = true
+			info.ValidUntil, info.IsExpired = parseValidUntil(value, now)
+		}
+	}
+
+	return info
+}
+
+// parseValidUntil parses a "Valid until" value such as
+// "2025-08-15 22:05:12 +0200 CEST [valid for 11h29m0s]" or "2025-08-15 10:05:12 +0000 UTC [EXPIRED]"
+func parseValidUntil(value string, now time.Time) (time.Time, bool) {
+	expired := strings.Contains(value, "EXPIRED")
+
+	timestamp := value
+	if i := strings.Index(value, " ["); i >= 0 {
+		timestamp = strings.TrimSpace(value[:i])
+	}
+	if validUntil, err := time.Parse(statusTimeLayout, timestamp); err == nil {
+		return validUntil, expired || !now.Before(validUntil)
+	}
+
+	// Fall back to the relative time if the timestamp uses an unknown format
+	if match := validForPattern.FindStringSubmatch(value); match != nil {
+		if remaining, err := time.ParseDuration(match[1]); err == nil {
+			return now.Add(remaining), expired
+		}
+	}
+	return time.Time{}, expired
+}
+
+// splitStatusList splits a comma-separated list printed by 'tsh status'
+func splitStatusList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readStatus runs 'tsh status' with the given tsh binary and environment and parses its output.
+// JSON output is requested from tsh releases supporting it; older ones are parsed from text.
+func readStatus(tshPath, tshVersion string, env []string, args ...string) *SessionInfo {
+	if tshVersion == "" || config.CompareVersions(tshVersion, statusJSONVersion) >= 0 {
+		cmd := exec.Command(tshPath, append(append([]string{"status"}, args...), "--format=json")...)
+		cmd.Env = env
+		// tsh exits non-zero for an expired session but still prints it
+		if output, err := cmd.Output(); err == nil || (isExitError(err) && len(output) > 0) {
+			if info, err := parseStatusJSON(output, time.Now()); err == nil {
+				return info
+			}
+		}
+
+		// A release known to support JSON fails only if there is no session
+		if tshVersion != "" {
+			return &SessionInfo{}
+		}
+	}
+
+	cmd := exec.Command(tshPath, append([]string{"status"}, args...)...)
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if err != nil && !isExitError(err) {
+		return &SessionInfo{}
+	}
+	return parseStatusText(string(output), time.Now())
+}
+
+// isExitError reports whether err means a command ran but exited non-zero
+func isExitError(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
+
+// sessionStatus returns the session of an environment using its tsh version and isolated session directory.
+// tsh is not installed on demand, so an environment whose version is missing reports no session.
+func (c *Client) sessionStatus(env, proxy string) *SessionInfo {
+	tshPath := c.getTSHPath(env)
+	if tshPath == "" {
+		return &SessionInfo{}
+	}
+
+	// Check if the tsh version is actually installed
+	if !c.installer.IsVersionInstalled(c.getRequiredTSHVersion(env)) {
+		return &SessionInfo{}
+	}
+
+	// Ensure session directory exists
+	if err := c.ensureSessionDir(env); err != nil {
+		return &SessionInfo{}
+	}
+
+	user := c.getEffectiveUser(env)
+	return readStatus(tshPath, c.getRequiredTSHVersion(env), c.tshEnviron(env), "--proxy="+proxy, "--user="+user)
+}
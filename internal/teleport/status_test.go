@@ -0,0 +1,224 @@
+package teleport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var statusNow = time.Date(2025, 8, 15, 10, 0, 0, 0, time.UTC)
+
+func TestParseStatusJSON(t *testing.T) {
+	data := []byte(`{
+  "active": {
+    "profile_url": "https://teleport.prod.company.com:443",
+    "username": "alice",
+    "active_requests": ["8d3c1b2e"],
+    "cluster": "prod",
+    "roles": ["access", "editor"],
+    "traits": {"logins": ["alice"]},
+    "logins": ["alice", "root"],
+    "kubernetes_enabled": true,
+    "kubernetes_cluster": "eks-prod",
+    "kubernetes_users": ["alice"],
+    "kubernetes_groups": ["system:masters"],
+    "databases": [],
+    "valid_until": "2025-08-15T22:00:00Z",
+    "extensions": ["permit-pty"]
+  },
+  "profiles": []
+}`)
+
+	info, err := parseStatusJSON(data, statusNow)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := &SessionInfo{
+		IsAuthenticated: true,
+		ValidUntil:      time.Date(2025, 8, 15, 22, 0, 0, 0, time.UTC),
+		ProfileURL:      "https://teleport.prod.company.com:443",
+		Cluster:         "prod",
+		Username:        "alice",
+		Roles:           []string{"access", "editor"},
+		Logins:          []string{"alice", "root"},
+		KubeEnabled:     true,
+		KubeCluster:     "eks-prod",
+		KubeUsers:       []string{"alice"},
+		KubeGroups:      []string{"system:masters"},
+		ActiveRequests:  []string{"8d3c1b2e"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+	if !info.IsValid() {
+		t.Error("Expected session to be valid")
+	}
+}
+
+func TestParseStatusJSON_Expired(t *testing.T) {
+	data := []byte(`{"active": {"username": "alice", "cluster": "prod", "valid_until": "2025-08-15T09:00:00Z"}}`)
+
+	info, err := parseStatusJSON(data, statusNow)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !info.IsAuthenticated || !info.IsExpired {
+		t.Errorf("Expected an expired session, got %+v", info)
+	}
+	if info.IsValid() {
+		t.Error("Expected expired session to be invalid")
+	}
+}
+
+func TestParseStatusJSON_NotLoggedIn(t *testing.T) {
+	for _, data := range []string{`{"active": null, "profiles": []}`, `{}`} {
+		info, err := parseStatusJSON([]byte(data), statusNow)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", data, err)
+		}
+		if info.IsAuthenticated || !info.ValidUntil.IsZero() {
+			t.Errorf("Expected no session for %s, got %+v", data, info)
+		}
+	}
+}
+
+func TestParseStatusJSON_Invalid(t *testing.T) {
+	if _, err := parseStatusJSON([]byte("> Profile URL: https://x"), statusNow); err == nil {
+		t.Error("Expected error for text output")
+	}
+	if _, err := parseStatusJSON([]byte(`{"active": {"username": "alice", "valid_until": "tomorrow"}}`), statusNow); err == nil {
+		t.Error("Expected error for invalid expiry")
+	}
+}
+
+func TestReadStatus_ExpiredWithExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as tsh")
+	}
+
+	// tsh prints the expired session and exits 1
+	tshPath := filepath.Join(t.TempDir(), "tsh")
+	script := `#!/bin/sh
+echo '{"active": {"username": "alice", "cluster": "prod", "valid_until": "2020-01-01T00:00:00Z"}}'
+echo 'ERROR: your credentials have expired' >&2
+exit 1
+`
+	if err := os.WriteFile(tshPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	info := readStatus(tshPath, "17.7.1", os.Environ())
+	if !info.IsAuthenticated || !info.IsExpired {
+		t.Errorf("Expected an expired session, got %+v", info)
+	}
+	if info.Username != "alice" {
+		t.Errorf("Expected user alice, got %q", info.Username)
+	}
+}
+
+func TestParseStatusText(t *testing.T) {
+	output := `> Profile URL:        https://teleport.prod.company.com:443
+  Logged in as:       alice
+  Cluster:            prod
+  Roles:              access, editor
+  Logins:             alice, root
+  Kubernetes:         enabled
+  Kubernetes cluster: "eks-prod"
+  Kubernetes users:   alice
+  Kubernetes groups:  system:masters
+  Valid until:        2025-08-15 22:00:00 +0000 UTC [valid for 12h0m0s]
+  Extensions:         permit-pty
+
+  Profile URL:        https://teleport.test.company.com:443
+  Logged in as:       bob
+  Cluster:            test
+  Valid until:        2025-08-15 08:00:00 +0000 UTC [EXPIRED]
+`
+
+	info := parseStatusText(output, statusNow)
+
+	if !info.IsValid() {
+		t.Fatalf("Expected a valid session, got %+v", info)
+	}
+	if info.ProfileURL != "https://teleport.prod.company.com:443" || info.Username != "alice" || info.Cluster != "prod" {
+		t.Errorf("Expected fields of the active profile, got %+v", info)
+	}
+	if !reflect.DeepEqual(info.Roles, []string{"access", "editor"}) || !reflect.DeepEqual(info.Logins, []string{"alice", "root"}) {
+		t.Errorf("Unexpected roles or logins: %+v", info)
+	}
+	if !info.KubeEnabled || info.KubeCluster != "eks-prod" {
+		t.Errorf("Unexpected kubernetes fields: %+v", info)
+	}
+	if !reflect.DeepEqual(info.KubeUsers, []string{"alice"}) || !reflect.DeepEqual(info.KubeGroups, []string{"system:masters"}) {
+		t.Errorf("Unexpected kubernetes users or groups: %+v", info)
+	}
+	if !info.ValidUntil.Equal(time.Date(2025, 8, 15, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected expiry %v", info.ValidUntil)
+	}
+}
+
+func TestParseStatusText_NotLoggedIn(t *testing.T) {
+	info := parseStatusText("Not logged in.\n", statusNow)
+	if info.IsAuthenticated {
+		t.Errorf("Expected no session, got %+v", info)
+	}
+}
+
+func TestParseValidUntil(t *testing.T) {
+	tests := []struct {
+		value           string
+		expectedTime    time.Time
+		expectedExpired bool
+	}{
+		{"2025-08-15 22:00:00 +0000 UTC [valid for 12h0m0s]", time.Date(2025, 8, 15, 22, 0, 0, 0, time.UTC), false},
+		{"2025-08-15 08:00:00 +0000 UTC [EXPIRED]", time.Date(2025, 8, 15, 8, 0, 0, 0, time.UTC), true},
+		{"2025-08-15 09:00:00 +0000 UTC", time.Date(2025, 8, 15, 9, 0, 0, 0, time.UTC), true},
+		{"Fri Aug 15 12:00 [valid for 2h0m0s]", statusNow.Add(2 * time.Hour), false},
+		{"unknown", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		validUntil, expired := parseValidUntil(test.value, statusNow)
+		if !validUntil.Equal(test.expectedTime) || expired != test.expectedExpired {
+			t.Errorf("parseValidUntil(%q) = %v, %v; expected %v, %v", test.value, validUntil, expired, test.expectedTime, test.expectedExpired)
+		}
+	}
+}
+
+func TestSessionInfo_TimeRemaining(t *testing.T) {
+	if remaining := (&SessionInfo{}).TimeRemaining(); remaining != 0 {
+		t.Errorf("Expected zero for unknown expiry, got %v", remaining)
+	}
+	if remaining := (&SessionInfo{ValidUntil: time.Now().Add(-time.Minute)}).TimeRemaining(); remaining != 0 {
+		t.Errorf("Expected zero for expired session, got %v", remaining)
+	}
+	if remaining := (&SessionInfo{ValidUntil: time.Now().Add(time.Hour)}).TimeRemaining(); remaining <= 59*time.Minute {
+		t.Errorf("Expected about an hour, got %v", remaining)
+	}
+}
+
+func TestSessionInfo_NeedsRenewal(t *testing.T) {
+	tests := []struct {
+		name      string
+		info      SessionInfo
+		threshold time.Duration
+		expected  bool
+	}{
+		{"below threshold", SessionInfo{IsAuthenticated: true, ValidUntil: time.Now().Add(4 * time.Minute)}, 15 * time.Minute, true},
+		{"above threshold", SessionInfo{IsAuthenticated: true, ValidUntil: time.Now().Add(time.Hour)}, 15 * time.Minute, false},
+		{"disabled", SessionInfo{IsAuthenticated: true, ValidUntil: time.Now().Add(4 * time.Minute)}, 0, false},
+		{"unknown expiry", SessionInfo{IsAuthenticated: true}, 15 * time.Minute, false},
+		{"expired", SessionInfo{IsAuthenticated: true, IsExpired: true, ValidUntil: time.Now().Add(-time.Minute)}, 15 * time.Minute, false},
+		{"not logged in", SessionInfo{}, 15 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		if renew := tt.info.NeedsRenewal(tt.threshold); renew != tt.expected {
+			t.Errorf("%s: expected NeedsRenewal %v, got %v", tt.name, tt.expected, renew)
+		}
+	}
+}
@@ -1,19 +1,23 @@
 package teleport
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"tkube/internal/config"
+	"tkube/internal/paths"
 )
 
 // Client handles Teleport operations
 type Client struct {
 	configManager *config.Manager
 	installer     *TSHInstaller
+	paths         *paths.Paths
 }
 
 // NewClient creates a new Teleport client
@@ -23,172 +27,52 @@ func NewClient(configManager *config.Manager) (*Client, error) {
 		return nil, fmt.Errorf("failed to create tsh installer: %w", err)
 	}
 
+	p, err := paths.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
 		configManager: configManager,
 		installer:     installer,
+		paths:         p,
 	}, nil
 }
 
 // IsAuthenticated checks if the user is authenticated to a Teleport proxy
 func (c *Client) IsAuthenticated(proxy string) bool {
-	cmd := exec.Command("tsh", "status", "--proxy="+proxy)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-
-	outputStr := string(output)
-	return strings.Contains(outputStr, "logged in") || strings.Contains(outputStr, "Valid until")
+	return readStatus("tsh", "", os.Environ(), "--proxy="+proxy).IsValid()
 }
 
 // IsAuthenticatedWithEnv checks if the user is authenticated to a Teleport proxy using environment-specific tsh
 func (c *Client) IsAuthenticatedWithEnv(env, proxy string) bool {
+	return c.SessionInfoWithEnv(env, proxy).IsValid()
+}
+
+// SessionInfoWithEnv returns the session of an environment, installing its tsh version if needed
+func (c *Client) SessionInfoWithEnv(env, proxy string) *SessionInfo {
 	// Ensure tsh version is installed
 	if err := c.EnsureTSHVersion(env); err != nil {
 		fmt.Printf("⚠️  Failed to ensure tsh version for environment %s: %v\n", env, err)
-		return false
+		return &SessionInfo{}
 	}
 
-	tshPath := c.getTSHPath(env)
-	if tshPath == "" {
+	if c.getTSHPath(env) == "" {
 		fmt.Printf("⚠️  No tsh path available for environment %s\n", env)
-		return false
-	}
-
-	// Ensure session directory exists
-	if err := c.ensureSessionDir(env); err != nil {
-		fmt.Printf("⚠️  Failed to create session directory for environment %s: %v\n", env, err)
-		return false
-	}
-
-	user := c.getEffectiveUser(env)
-	cmd := exec.Command(tshPath, "status", "--proxy="+proxy, "--user="+user)
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
+		return &SessionInfo{}
 	}
 
-	outputStr := string(output)
-	return strings.Contains(outputStr, "logged in") || strings.Contains(outputStr, "Valid until")
+	return c.sessionStatus(env, proxy)
 }
 
 // CheckAuthenticationStatus checks if the user is authenticated without auto-installing tsh
 func (c *Client) CheckAuthenticationStatus(env, proxy string) bool {
-	tshPath := c.getTSHPath(env)
-	if tshPath == "" {
-		return false
-	}
-
-	// Check if the tsh version is actually installed
-	if !c.installer.IsVersionInstalled(c.getRequiredTSHVersion(env)) {
-		return false
-	}
-
-	// Ensure session directory exists
-	if err := c.ensureSessionDir(env); err != nil {
-		return false
-	}
-
-	user := c.getEffectiveUser(env)
-	cmd := exec.Command(tshPath, "status", "--proxy="+proxy, "--user="+user)
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-
-	outputStr := string(output)
-	return strings.Contains(outputStr, "logged in") || strings.Contains(outputStr, "Valid until")
-}
-
-// SessionInfo represents session information for an environment
-type SessionInfo struct {
-	IsAuthenticated bool
-	ValidUntil      string
-	TimeRemaining   string
-	IsExpired       bool
+	return c.sessionStatus(env, proxy).IsValid()
 }
 
-// GetSessionInfo returns detailed session information for an environment
+// GetSessionInfo returns detailed session information for an environment without auto-installing tsh
 func (c *Client) GetSessionInfo(env, proxy string) *SessionInfo {
-	info := &SessionInfo{
-		IsAuthenticated: false,
-		ValidUntil:      "",
-		TimeRemaining:   "",
-		IsExpired:       false,
-	}
-
-	tshPath := c.getTSHPath(env)
-	if tshPath == "" {
-		return info
-	}
-
-	// Check if the tsh version is actually installed
-	if !c.installer.IsVersionInstalled(c.getRequiredTSHVersion(env)) {
-		return info
-	}
-
-	// Ensure session directory exists
-	if err := c.ensureSessionDir(env); err != nil {
-		return info
-	}
-
-	user := c.getEffectiveUser(env)
-	cmd := exec.Command(tshPath, "status", "--proxy="+proxy, "--user="+user)
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return info
-	}
-
-	outputStr := string(output)
-
-	// Check if authenticated
-	if !strings.Contains(outputStr, "logged in") && !strings.Contains(outputStr, "Valid until") {
-		return info
-	}
-
-	info.IsAuthenticated = true
-
-	// Parse "Valid until" line
-	lines := strings.Split(outputStr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "Valid until:") {
-			// Extract the time part
-			parts := strings.Split(line, "Valid until:")
-			if len(parts) > 1 {
-				timeInfo := strings.TrimSpace(parts[1])
-
-				// Check if expired
-				if strings.Contains(timeInfo, "EXPIRED") {
-					info.IsExpired = true
-					info.TimeRemaining = "EXPIRED"
-					info.ValidUntil = timeInfo
-				} else {
-					// Extract time remaining from brackets like [valid for 11h29m0s]
-					if strings.Contains(timeInfo, "[valid for ") && strings.Contains(timeInfo, "]") {
-						start := strings.Index(timeInfo, "[valid for ") + len("[valid for ")
-						end := strings.Index(timeInfo, "]")
-						if start < end {
-							info.TimeRemaining = timeInfo[start:end]
-						}
-					}
-
-					// Extract the actual expiry time (before the bracket)
-					if bracketIndex := strings.Index(timeInfo, " ["); bracketIndex > 0 {
-						info.ValidUntil = strings.TrimSpace(timeInfo[:bracketIndex])
-					} else {
-						info.ValidUntil = timeInfo
-					}
-				}
-			}
-			break
-		}
-	}
-
-	return info
+	return c.sessionStatus(env, proxy)
 }
 
 // getRequiredTSHVersion returns the required tsh version for an environment
@@ -206,6 +90,20 @@ func (c *Client) getRequiredTSHVersion(env string) string {
 	return envConfig.TSHVersion
 }
 
+// getLoginArgs returns the tsh login flags of an environment's login options,
+// checked against the environment's tsh version
+func (c *Client) getLoginArgs(env string) ([]string, error) {
+	envConfig, err := c.configManager.GetEnvironment(env)
+	if err != nil || envConfig.Login.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := envConfig.Login.Validate(envConfig.TSHVersion); err != nil {
+		return nil, fmt.Errorf("invalid login options for environment %s: %w", env, err)
+	}
+	return envConfig.Login.Args(), nil
+}
+
 // Login authenticates to a Teleport proxy
 func (c *Client) Login(proxy string) error {
 	// For the generic login, use system user
@@ -217,8 +115,8 @@ func (c *Client) Login(proxy string) error {
 	return cmd.Run()
 }
 
-// LoginWithEnv authenticates to a Teleport proxy using environment-specific tsh
-func (c *Client) LoginWithEnv(env, proxy string) error {
+// LoginWithEnv authenticates to a Teleport proxy using environment-specific tsh, writing tsh's output to out
+func (c *Client) LoginWithEnv(env, proxy string, out io.Writer) error {
 	// Ensure tsh version is installed
 	if err := c.EnsureTSHVersion(env); err != nil {
 		return fmt.Errorf("failed to ensure tsh version for environment %s: %w", env, err)
@@ -237,10 +135,17 @@ func (c *Client) LoginWithEnv(env, proxy string) error {
 	// Get the effective user for this environment
 	user := c.getEffectiveUser(env)
 
-	cmd := exec.Command(tshPath, "login", "--proxy="+proxy, "--user="+user)
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
+	args := []string{"login", "--proxy=" + proxy, "--user=" + user}
+	loginArgs, err := c.getLoginArgs(env)
+	if err != nil {
+		return err
+	}
+	args = append(args, loginArgs...)
+
+	cmd := exec.Command(tshPath, args...)
+	cmd.Env = c.tshEnviron(env)
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = out
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
@@ -254,8 +159,9 @@ func (c *Client) KubeLogin(proxy, cluster string) error {
 	return cmd.Run()
 }
 
-// KubeLoginWithEnv authenticates to a Kubernetes cluster via Teleport using environment-specific tsh
-func (c *Client) KubeLoginWithEnv(env, proxy, cluster string) error {
+// KubeLoginWithEnv authenticates to a Kubernetes cluster via Teleport using environment-specific tsh,
+// writing tsh's output to out
+func (c *Client) KubeLoginWithEnv(env, proxy, cluster string, out io.Writer) error {
 	// Ensure tsh version is installed
 	if err := c.EnsureTSHVersion(env); err != nil {
 		return fmt.Errorf("failed to ensure tsh version for environment %s: %w", env, err)
@@ -271,12 +177,21 @@ func (c *Client) KubeLoginWithEnv(env, proxy, cluster string) error {
 		return fmt.Errorf("failed to create session directory for environment %s: %w", env, err)
 	}
 
+	// Each cluster gets its own kubeconfig, so terminals using other clusters keep their context
 	cmd := exec.Command(tshPath, "--proxy="+proxy, "kube", "login", cluster)
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
+	cmd.Env = SetEnviron(c.tshEnviron(env), "KUBECONFIG="+c.KubeconfigPath(env, cluster))
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	cmd.Stdout = out
+	// Keep a copy of the error output to tell missing access apart from other failures
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		if isKubeAccessDenied(stderr.String(), cluster) {
+			return fmt.Errorf("%w: %s", ErrKubeAccessDenied, strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+	return nil
 }
 
 // GetClusters returns a list of available Kubernetes clusters for an environment
@@ -302,7 +217,7 @@ func (c *Client) GetClusters(env string) ([]string, error) {
 	}
 
 	cmd := exec.Command(tshPath, "--proxy="+envConfig.Proxy, "kube", "ls", "--format=json")
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
+	cmd.Env = c.tshEnviron(env)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get clusters: %w", err)
@@ -363,7 +278,7 @@ func (c *Client) GetClustersForCompletion(env string) ([]string, error) {
 	if !c.CheckAuthenticationStatus(env, envConfig.Proxy) {
 		// Attempt background authentication for tab completion
 		fmt.Fprintf(os.Stderr, "🔐 Authenticating to %s for tab completion...\n", envConfig.Proxy)
-		if err := c.LoginWithEnv(env, envConfig.Proxy); err != nil {
+		if err := c.LoginWithEnv(env, envConfig.Proxy, os.Stderr); err != nil {
 			return []string{"❌ Authentication failed - run: tkube " + env + " <cluster> to authenticate"}, nil
 		}
 		fmt.Fprintf(os.Stderr, "✅ Authenticated successfully!\n")
@@ -376,7 +291,7 @@ func (c *Client) GetClustersForCompletion(env string) ([]string, error) {
 
 	// Get clusters using the specific tsh version for this environment
 	cmd := exec.Command(tshPath, "--proxy="+envConfig.Proxy, "kube", "ls", "--format=json")
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
+	cmd.Env = c.tshEnviron(env)
 	output, err := cmd.Output()
 	if err != nil {
 		return []string{"⚠️  Failed to get clusters - check connection to " + envConfig.Proxy}, nil
@@ -429,20 +344,37 @@ func (c *Client) getTSHPath(env string) string {
 
 // getSessionDir returns the isolated session directory for a specific environment
 func (c *Client) getSessionDir(env string) string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-	return filepath.Join(homeDir, ".tkube", "sessions", env)
+	return c.paths.SessionDir(env)
 }
 
-// ensureSessionDir creates the session directory if it doesn't exist
+// ensureSessionDir creates the session and kubeconfig directories if they don't exist
 func (c *Client) ensureSessionDir(env string) error {
 	sessionDir := c.getSessionDir(env)
 	if sessionDir == "" {
 		return fmt.Errorf("failed to get session directory for environment %s", env)
 	}
-	return os.MkdirAll(sessionDir, 0700)
+	if err := os.MkdirAll(sessionDir, 0700); err != nil {
+		return err
+	}
+	return c.ensureKubeconfigDir(env)
+}
+
+// RemoveSession deletes the isolated session directory of an environment
+func (c *Client) RemoveSession(env string) error {
+	// Never let an odd environment name point outside the sessions directory
+	if env == "" || env == "." || env == ".." || filepath.Base(env) != env {
+		return fmt.Errorf("invalid environment name '%s'", env)
+	}
+
+	sessionDir := c.getSessionDir(env)
+	if sessionDir == "" {
+		return fmt.Errorf("failed to get session directory for environment %s", env)
+	}
+
+	if err := os.RemoveAll(sessionDir); err != nil {
+		return fmt.Errorf("failed to remove session directory: %w", err)
+	}
+	return nil
 }
 
 // getEffectiveUser returns the effective user for an environment
@@ -486,12 +418,7 @@ func (c *Client) getSystemUser() string {
 
 // IsTSHVersionInstalled checks if a specific tsh version is installed
 func (c *Client) IsTSHVersionInstalled(version string) bool {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return false
-	}
-
-	tshPath := filepath.Join(homeDir, ".tkube", "tsh", version, "tsh")
+	tshPath := c.installer.GetTSHPath(version)
 	if _, err := os.Stat(tshPath); err != nil {
 		return false
 	}
@@ -546,7 +473,7 @@ func (c *Client) LogoutWithEnv(env, proxy string) error {
 	// For isolated sessions, we can simply logout from all sessions in this environment's session directory
 	// This is simpler and more reliable than targeting specific proxy/user combinations
 	cmd := exec.Command(tshPath, "logout")
-	cmd.Env = append(os.Environ(), "TELEPORT_HOME="+c.getSessionDir(env))
+	cmd.Env = c.tshEnviron(env)
 	output, err := cmd.CombinedOutput()
 	
 	// If there's an error, check if it's because user is already logged out
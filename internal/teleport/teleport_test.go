@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"tkube/internal/config"
 )
@@ -45,12 +46,12 @@ func TestClient_GetSessionInfo(t *testing.T) {
 		t.Error("Expected IsAuthenticated to be false for non-existent tsh")
 	}
 
-	if sessionInfo.ValidUntil != "" {
+	if !sessionInfo.ValidUntil.IsZero() {
 		t.Error("Expected ValidUntil to be empty")
 	}
 
-	if sessionInfo.TimeRemaining != "" {
-		t.Error("Expected TimeRemaining to be empty")
+	if sessionInfo.TimeRemaining() != 0 {
+		t.Error("Expected TimeRemaining to be zero")
 	}
 
 	if sessionInfo.IsExpired {
@@ -487,7 +488,7 @@ func TestClient_Login_Methods(t *testing.T) {
 	}
 
 	// Test LoginWithEnv (will fail but shouldn't panic)
-	err = client.LoginWithEnv("nonexistent", "invalid.proxy.com:443")
+	err = client.LoginWithEnv("nonexistent", "invalid.proxy.com:443", os.Stdout)
 	if err == nil {
 		t.Error("Expected error for non-existent environment")
 	}
@@ -499,7 +500,7 @@ func TestClient_Login_Methods(t *testing.T) {
 	}
 
 	// Test KubeLoginWithEnv (will fail but shouldn't panic)
-	err = client.KubeLoginWithEnv("nonexistent", "invalid.proxy.com:443", "test-cluster")
+	err = client.KubeLoginWithEnv("nonexistent", "invalid.proxy.com:443", "test-cluster", os.Stdout)
 	if err == nil {
 		t.Error("Expected error for non-existent environment")
 	}
@@ -570,3 +571,36 @@ func TestClient_LogoutWithEnv_NoTSHPath(t *testing.T) {
 		t.Logf("LogoutWithEnv failed as expected (no tsh path): %v", err)
 	}
 }
+
+func TestClient_GetLoginArgs(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TKUBE_HOME", tempDir)
+	t.Setenv("TKUBE_CONFIG", filepath.Join(tempDir, "config.json"))
+	os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{
+  "schema_version": 1,
+  "environments": {
+    "prod": {"proxy": "prod.proxy.com:443", "tsh_version": "17.7.1", "login": {"auth_connector": "okta", "ttl": "4h"}},
+    "legacy": {"proxy": "legacy.proxy.com:443", "tsh_version": "9.3.4", "login": {"mfa_mode": "otp"}},
+    "plain": {"proxy": "plain.proxy.com:443"}
+  }
+}`), 0600)
+
+	configManager, _ := config.NewManager()
+	client, _ := NewClient(configManager)
+
+	args, err := client.getLoginArgs("prod")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(args, " ") != "--auth=okta --ttl=240" {
+		t.Errorf("Unexpected login flags %v", args)
+	}
+
+	if _, err := client.getLoginArgs("legacy"); err == nil {
+		t.Error("Expected error for an option the pinned tsh version does not support")
+	}
+
+	if args, err := client.getLoginArgs("plain"); err != nil || args != nil {
+		t.Errorf("Expected no flags, got %v (%v)", args, err)
+	}
+}
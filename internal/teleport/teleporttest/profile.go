@@ -0,0 +1,45 @@
+// Package teleporttest provides fixtures for tests that read tsh's files
+package teleporttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteTSHProfile writes a tsh profile with a self-signed certificate valid until
+// validUntil into dir, laid out the way tsh stores it
+func WriteTSHProfile(dir, name, profile, user string, validUntil time.Time) error {
+	if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(profile), 0600); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: user},
+		NotBefore:    validUntil.Add(-12 * time.Hour),
+		NotAfter:     validUntil,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyDir := filepath.Join(dir, "keys", name)
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	return os.WriteFile(filepath.Join(keyDir, user+"-x509.pem"), certPEM, 0600)
+}
@@ -189,3 +189,15 @@ Or for automatic installation:
 After installation, the version will be automatically configured for this environment.`,
 		requiredVersion, requiredVersion, requiredVersion)
 }
+
+// ProbeProxy checks that a Teleport proxy answers on /webapi/ping and returns the version it reports
+func (vd *VersionDetector) ProbeProxy(proxy string) (string, error) {
+	endpoint := fmt.Sprintf("https://%s/webapi/ping", proxy)
+
+	version, err := vd.queryEndpoint(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("proxy %s is not reachable: %w", proxy, err)
+	}
+
+	return version, nil
+}